@@ -12,7 +12,6 @@ export default function RegisterPage() {
     confirmPassword: '',
     email: '',
     full_name: '',
-    role: 'member', // Default role
   });
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(false);
@@ -44,6 +43,12 @@ export default function RegisterPage() {
       
       const response = await authAPI.register(userData);
       
+      // Accounts awaiting approval get no token yet
+      if (response.status === 202) {
+        setError(response.data.message);
+        return;
+      }
+      
       // Store token and user data
       localStorage.setItem('token', response.data.token);
       localStorage.setItem('user', JSON.stringify(response.data.user));
@@ -51,7 +56,13 @@ export default function RegisterPage() {
       // Redirect to dashboard
       router.push('/dashboard');
     } catch (err) {
-      setError(err.response?.data || 'An error occurred during registration');
+      // Password policy failures list each rule the password broke
+      const data = err.response?.data;
+      if (data?.violations) {
+        setError(data.violations.map(v => v.message).join('. '));
+      } else {
+        setError(data || 'An error occurred during registration');
+      }
     } finally {
       setLoading(false);
     }
@@ -126,7 +137,7 @@ export default function RegisterPage() {
               value={formData.password}
               onChange={handleChange}
               required
-              minLength={8}
+              minLength={10}
             />
           </div>
           
@@ -142,7 +153,7 @@ export default function RegisterPage() {
               value={formData.confirmPassword}
               onChange={handleChange}
               required
-              minLength={8}
+              minLength={10}
             />
           </div>
           
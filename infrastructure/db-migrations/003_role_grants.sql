@@ -0,0 +1,16 @@
+-- Accounts created by public registration may need approval before they can log in
+ALTER TABLE users
+    ADD COLUMN status ENUM('active', 'pending') NOT NULL DEFAULT 'active' AFTER role;
+
+-- Audit trail of roles granted by admins and overseers
+CREATE TABLE IF NOT EXISTS role_grants (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    role ENUM('admin', 'overseer', 'group_leader', 'teacher', 'member') NOT NULL,
+    previous_role ENUM('admin', 'overseer', 'group_leader', 'teacher', 'member'),
+    granted_by INT,
+    granted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_role_grants_user (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (granted_by) REFERENCES users(id) ON DELETE SET NULL
+);
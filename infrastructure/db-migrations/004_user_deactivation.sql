@@ -0,0 +1,6 @@
+-- Deactivated accounts keep their data but can no longer log in
+ALTER TABLE users
+    MODIFY COLUMN status ENUM('active', 'pending', 'deactivated') NOT NULL DEFAULT 'active';
+
+-- Speeds up the admin user list, which pages by name
+CREATE INDEX idx_users_full_name ON users (full_name);
@@ -0,0 +1,16 @@
+-- Set once the user proves they receive mail at their address
+ALTER TABLE users
+    ADD COLUMN email_verified_at TIMESTAMP NULL DEFAULT NULL AFTER email;
+
+-- Single-use tokens sent by email for password resets and email verification
+CREATE TABLE IF NOT EXISTS account_tokens (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL,
+    purpose ENUM('password_reset', 'email_verification') NOT NULL,
+    email VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_account_tokens_user (user_id, purpose),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,20 @@
+-- Failed login counts per account and per client IP, shared by all user-service replicas
+CREATE TABLE IF NOT EXISTS login_attempts (
+    attempt_key VARCHAR(150) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    window_start TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP NULL DEFAULT NULL
+);
+
+-- Security-relevant events such as lockouts and unlocks
+CREATE TABLE IF NOT EXISTS audit_events (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    user_id INT,
+    actor_id INT,
+    ip_address VARCHAR(45),
+    details VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_audit_events_user (user_id),
+    INDEX idx_audit_events_type (event_type, created_at)
+);
@@ -0,0 +1,21 @@
+-- TOTP two-factor authentication. The secret is stored during enrollment and
+-- only takes effect once totp_enabled_at is set; totp_last_step stops a code
+-- from being used twice.
+ALTER TABLE users
+    ADD COLUMN totp_secret VARCHAR(64) NULL DEFAULT NULL,
+    ADD COLUMN totp_enabled_at TIMESTAMP NULL DEFAULT NULL,
+    ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+-- One-time codes for logging in without the authenticator
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP NULL DEFAULT NULL,
+    UNIQUE KEY uq_recovery_codes (user_id, code_hash),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Password-verified logins waiting for their second factor
+ALTER TABLE account_tokens
+    MODIFY COLUMN purpose ENUM('password_reset', 'email_verification', 'login_challenge') NOT NULL;
@@ -0,0 +1,15 @@
+-- Hashes of each user's recent passwords so they cannot be reused. The
+-- current password is recorded here too; older rows beyond the configured
+-- history size are pruned whenever the password changes.
+CREATE TABLE IF NOT EXISTS password_history (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_password_history_user (user_id, id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Seed the history with every user's current password
+INSERT INTO password_history (user_id, password_hash)
+SELECT id, password_hash FROM users;
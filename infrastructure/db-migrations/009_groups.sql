@@ -0,0 +1,26 @@
+-- Ministry groups form a tree: an overseer's group contains group leaders'
+-- groups, which contain teachers' groups. Each group has one leader, who may
+-- see everyone in the group and the groups below it.
+CREATE TABLE IF NOT EXISTS ministry_groups (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    parent_id INT NULL DEFAULT NULL,
+    leader_id INT NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_ministry_groups_parent (parent_id),
+    INDEX idx_ministry_groups_leader (leader_id),
+    FOREIGN KEY (parent_id) REFERENCES ministry_groups(id),
+    FOREIGN KEY (leader_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+    group_id INT NOT NULL,
+    user_id INT NOT NULL,
+    joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, user_id),
+    INDEX idx_group_members_user (user_id),
+    FOREIGN KEY (group_id) REFERENCES ministry_groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+-- Revocations of deleted users' sessions. The sessions themselves go with the
+-- user, so they are kept here until they would have expired, letting
+-- GET /sessions/revoked keep listing them.
+CREATE TABLE IF NOT EXISTS revoked_sessions (
+    session_id CHAR(32) PRIMARY KEY,
+    revoked_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    INDEX idx_revoked_sessions_revoked (revoked_at)
+);
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Claims represents the JWT claims issued by user-service
+type Claims struct {
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"`
+	Issuer    string `json:"iss"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Issuer identifies tokens generated by user-service
+const Issuer = "user-service"
+
+// header is the JOSE header of a token
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ"`
+}
+
+var (
+	keyringMu      sync.RWMutex
+	defaultKeyring = NewKeyring(nil)
+)
+
+// UseKeyring sets the keyring SignToken and VerifyToken use
+func UseKeyring(k *Keyring) {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	defaultKeyring = k
+}
+
+// DefaultKeyring returns the keyring set by UseKeyring
+func DefaultKeyring() *Keyring {
+	keyringMu.RLock()
+	defer keyringMu.RUnlock()
+	return defaultKeyring
+}
+
+// SignToken encodes and signs the given claims with the default keyring
+func SignToken(claims Claims) (string, error) {
+	return DefaultKeyring().Sign(claims)
+}
+
+// VerifyToken validates a JWT token issued by user-service using the default
+// keyring and rejects tokens whose session has been revoked
+func VerifyToken(tokenString string) (*Claims, error) {
+	claims, err := DefaultKeyring().Verify(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	
+	if claims.SessionID != "" && currentRevocationList().IsRevoked(claims.SessionID) {
+		return nil, errors.New("session has been revoked")
+	}
+	
+	return claims, nil
+}
+
+// Sign encodes and signs the given claims with the keyring's signing key
+func (k *Keyring) Sign(claims Claims) (string, error) {
+	if !k.CanSign() {
+		return "", errors.New("no signing key configured")
+	}
+	
+	// Encode the header and payload
+	headerJSON, err := json.Marshal(header{Alg: k.signing.Algorithm, Kid: k.signing.ID, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	
+	// Create signature
+	signature, err := k.signing.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	
+	// Combine to create token
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify validates a JWT token against the keyring
+func (k *Keyring) Verify(tokenString string) (*Claims, error) {
+	// Split the token
+	parts := strings.Split(tokenString, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("invalid token format")
+	}
+	
+	// Parse the header
+	decodedHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("failed to decode header")
+	}
+	var tokenHeader header
+	if err := json.Unmarshal(decodedHeader, &tokenHeader); err != nil {
+		return nil, errors.New("failed to parse header")
+	}
+	
+	// Verify signature against the keys matching the header's kid and alg
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("failed to decode signature")
+	}
+	signingInput := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range k.lookup(tokenHeader.Kid, tokenHeader.Alg) {
+		if key.verify(signingInput, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("invalid token signature")
+	}
+	
+	// Decode payload
+	decodedPayload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("failed to decode payload")
+	}
+	
+	// Parse claims
+	var claims Claims
+	if err := json.Unmarshal(decodedPayload, &claims); err != nil {
+		return nil, errors.New("failed to parse claims")
+	}
+	
+	// Check if token is expired
+	if claims.ExpiresAt < time.Now().Unix() {
+		return nil, errors.New("token is expired")
+	}
+	
+	// Check that the token was issued by user-service
+	if claims.Issuer != Issuer {
+		return nil, errors.New("invalid token issuer")
+	}
+	
+	return &claims, nil
+}
@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signWith builds a token with an arbitrary header and secret
+func signWith(t *testing.T, secret []byte, header string, claims Claims) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("failed to marshal claims: %v", err)
+	}
+	header64 := base64.RawURLEncoding.EncodeToString([]byte(header))
+	payload64 := base64.RawURLEncoding.EncodeToString(payload)
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(header64 + "." + payload64))
+	return header64 + "." + payload64 + "." + base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// useTestKeyring installs an HS256 keyring for the duration of the test
+func useTestKeyring(t *testing.T) {
+	t.Helper()
+	key, err := NewHMACKey("test", testSecret)
+	if err != nil {
+		t.Fatalf("NewHMACKey: %v", err)
+	}
+	previous := DefaultKeyring()
+	UseKeyring(NewKeyring(key))
+	t.Cleanup(func() { UseKeyring(previous) })
+}
+
+func TestVerifyToken(t *testing.T) {
+	useTestKeyring(t)
+	const hs256 = `{"alg":"HS256","typ":"JWT"}`
+	valid := Claims{
+		UserID:    42,
+		Username:  "teacher1",
+		Role:      "teacher",
+		Issuer:    Issuer,
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	}
+	expired := valid
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	wrongIssuer := valid
+	wrongIssuer.Issuer = "someone-else"
+
+	validToken, err := SignToken(valid)
+	if err != nil {
+		t.Fatalf("SignToken: %v", err)
+	}
+	escalated := valid
+	escalated.Role = "admin"
+	escalatedPayload, _ := json.Marshal(escalated)
+	parts := strings.Split(validToken, ".")
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString(escalatedPayload) + "." + parts[2]
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", validToken, false},
+		{"valid without kid", signWith(t, testSecret, hs256, valid), false},
+		{"expired", signWith(t, testSecret, hs256, expired), true},
+		{"wrongly signed", signWith(t, []byte("not-the-secret-not-the-secret-!!"), hs256, valid), true},
+		{"unknown kid", signWith(t, testSecret, `{"alg":"HS256","kid":"other","typ":"JWT"}`, valid), true},
+		{"tampered payload", tampered, true},
+		{"wrong issuer", signWith(t, testSecret, hs256, wrongIssuer), true},
+		{"alg none", signWith(t, testSecret, `{"alg":"none","typ":"JWT"}`, valid), true},
+		{"malformed: empty", "", true},
+		{"malformed: two segments", parts[0] + "." + parts[1], true},
+		{"malformed: bad base64", parts[0] + ".!!!." + parts[2], true},
+		{"malformed: not json", "bm90.anNvbg.c2ln", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := VerifyToken(tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got claims %+v", claims)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *claims != valid {
+				t.Errorf("claims = %+v, want %+v", claims, valid)
+			}
+		})
+	}
+}
+
+func TestAsymmetricKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		signer  crypto.Signer
+		wantAlg string
+	}{
+		{"RS256", rsaKey, AlgRS256},
+		{"EdDSA", edKey, AlgEdDSA},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := NewPrivateKey("", tt.signer)
+			if err != nil {
+				t.Fatalf("NewPrivateKey: %v", err)
+			}
+			if key.Algorithm != tt.wantAlg || key.ID == "" {
+				t.Fatalf("key = %s/%q, want %s with a derived kid", key.Algorithm, key.ID, tt.wantAlg)
+			}
+			issuer := NewKeyring(key)
+			claims := Claims{UserID: 1, Role: "admin", Issuer: Issuer, ExpiresAt: time.Now().Add(time.Hour).Unix()}
+			token, err := issuer.Sign(claims)
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+
+			// A verifier only sees the published JWKS
+			jwks := issuer.JWKS()
+			if len(jwks.Keys) != 1 || jwks.Keys[0].KeyID != key.ID {
+				t.Fatalf("JWKS = %+v, want the signing key", jwks)
+			}
+			public, err := ParseJWK(jwks.Keys[0])
+			if err != nil {
+				t.Fatalf("ParseJWK: %v", err)
+			}
+			if public.CanSign() {
+				t.Fatal("key parsed from JWKS must be verify-only")
+			}
+			if _, err := NewKeyring(nil, public).Verify(token); err != nil {
+				t.Errorf("Verify with published key: %v", err)
+			}
+
+			// The public key must not be usable as an HMAC secret
+			confused := signWith(t, []byte(jwks.Keys[0].N+jwks.Keys[0].X), `{"alg":"HS256","kid":"`+key.ID+`","typ":"JWT"}`, claims)
+			if _, err := NewKeyring(nil, public).Verify(confused); err == nil {
+				t.Error("expected algorithm confusion to be rejected")
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, _ := NewHMACKey("2025-01", testSecret)
+	newKey, _ := NewHMACKey("2025-02", []byte("fedcba9876543210fedcba9876543210"))
+	claims := Claims{UserID: 1, Issuer: Issuer, ExpiresAt: time.Now().Add(time.Hour).Unix()}
+
+	oldToken, err := NewKeyring(oldKey).Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	// During the overlap window old tokens still validate
+	oldKey.NotAfter = time.Now().Add(time.Hour)
+	rotated := NewKeyring(newKey, oldKey)
+	if _, err := rotated.Verify(oldToken); err != nil {
+		t.Errorf("old token rejected during overlap: %v", err)
+	}
+	newToken, err := rotated.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	header, _ := base64.RawURLEncoding.DecodeString(strings.Split(newToken, ".")[0])
+	if !strings.Contains(string(header), `"kid":"2025-02"`) {
+		t.Errorf("new token header = %s, want kid 2025-02", header)
+	}
+
+	// Once the window closes they are rejected
+	oldKey.NotAfter = time.Now().Add(-time.Second)
+	if _, err := rotated.Verify(oldToken); err == nil {
+		t.Error("old token accepted after overlap window")
+	}
+	if _, err := rotated.Verify(newToken); err != nil {
+		t.Errorf("new token rejected: %v", err)
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "jwt-secret")
+	if err := os.WriteFile(secretFile, append(testSecret, '\n'), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		cfg      KeyConfig
+		wantErr  bool
+		wantSign bool
+	}{
+		{"secret file", KeyConfig{SecretFile: secretFile}, false, true},
+		{"secret", KeyConfig{Secret: string(testSecret)}, false, true},
+		{"short secret", KeyConfig{Secret: "your-secret-key"}, true, false},
+		{"missing file", KeyConfig{SecretFile: filepath.Join(dir, "missing")}, true, false},
+		{"verify only", KeyConfig{JWKSURL: "http://user-service/.well-known/jwks.json"}, false, false},
+		{"nothing", KeyConfig{}, true, false},
+		{"bad expiry", KeyConfig{Secret: string(testSecret), PreviousKeysUntil: "tomorrow"}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := LoadKeyring(tt.cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if keyring.CanSign() != tt.wantSign {
+				t.Errorf("CanSign = %v, want %v", keyring.CanSign(), tt.wantSign)
+			}
+		})
+	}
+}
+
+type revokedSet map[string]bool
+
+func (r revokedSet) IsRevoked(sessionID string) bool { return r[sessionID] }
+
+func TestVerifyTokenRevokedSession(t *testing.T) {
+	useTestKeyring(t)
+	UseRevocationList(revokedSet{"lost-phone": true})
+	t.Cleanup(func() { UseRevocationList(noRevocations{}) })
+
+	claims := Claims{UserID: 1, Issuer: Issuer, ExpiresAt: time.Now().Add(time.Hour).Unix()}
+	for sid, wantErr := range map[string]bool{"lost-phone": true, "laptop": false, "": false} {
+		claims.SessionID = sid
+		token, err := SignToken(claims)
+		if err != nil {
+			t.Fatalf("SignToken: %v", err)
+		}
+		if _, err := VerifyToken(token); (err != nil) != wantErr {
+			t.Errorf("session %q: err = %v, want error %v", sid, err, wantErr)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Downline is the response of user-service's downline endpoint: the groups a
+// user leads, directly or below groups they lead, and the users in them
+type Downline struct {
+	GroupIDs []int `json:"group_ids"`
+	UserIDs  []int `json:"user_ids"`
+}
+
+// HasUser reports whether userID is in the downline
+func (d *Downline) HasUser(userID int) bool {
+	for _, id := range d.UserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// DownlineClient asks user-service which users are under the caller, so other
+// services can scope what a leader sees
+type DownlineClient struct {
+	url    string
+	client *http.Client
+}
+
+// NewDownlineClient creates a client for the user-service at baseURL
+func NewDownlineClient(baseURL string) *DownlineClient {
+	return &DownlineClient{
+		url:    baseURL + "/users/me/downline",
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Fetch returns the downline of the user the authorization header belongs
+// to. The header is forwarded unchanged, so user-service applies its own checks.
+func (c *DownlineClient) Fetch(authorization string) (*Downline, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var downline Downline
+	if err := json.NewDecoder(resp.Body).Decode(&downline); err != nil {
+		return nil, err
+	}
+	return &downline, nil
+}
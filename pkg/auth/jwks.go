@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// jwksRefreshInterval limits how often an unknown kid triggers a JWKS refetch
+const jwksRefreshInterval = time.Minute
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public halves of the keyring's asymmetric keys.
+// HS256 secrets are never published.
+func (k *Keyring) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range k.Keys() {
+		switch pub := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Algorithm: AlgRS256,
+				Use:       "sig",
+				N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Algorithm: AlgEdDSA,
+				Use:       "sig",
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return set
+}
+
+// ParseJWK converts a JWK into a verify-only Key
+func ParseJWK(jwk JWK) (*Key, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, errors.New("invalid RSA modulus")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return NewPublicKey(jwk.KeyID, &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		})
+	case "OKP":
+		if jwk.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key")
+		}
+		return NewPublicKey(jwk.KeyID, ed25519.PublicKey(x))
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+}
+
+// remoteJWKS caches verification keys published by user-service
+type remoteJWKS struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      []*Key
+	fetchedAt time.Time
+}
+
+func newRemoteJWKS(url string) *remoteJWKS {
+	return &remoteJWKS{url: url, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// lookup finds matching keys, refetching the set when the kid is unknown
+func (r *remoteJWKS) lookup(kid, alg string) []*Key {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matches := r.match(kid, alg)
+	if len(matches) == 0 && time.Since(r.fetchedAt) > jwksRefreshInterval {
+		if err := r.fetch(); err != nil {
+			log.Printf("Failed to fetch JWKS from %s: %v", r.url, err)
+		}
+		matches = r.match(kid, alg)
+	}
+	return matches
+}
+
+func (r *remoteJWKS) match(kid, alg string) []*Key {
+	var matches []*Key
+	for _, key := range r.keys {
+		if key.Algorithm == alg && (kid == "" || key.ID == kid) {
+			matches = append(matches, key)
+		}
+	}
+	return matches
+}
+
+func (r *remoteJWKS) fetch() error {
+	r.fetchedAt = time.Now()
+
+	resp, err := r.client.Get(r.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return err
+	}
+
+	var keys []*Key
+	for _, jwk := range set.Keys {
+		key, err := ParseJWK(jwk)
+		if err != nil {
+			log.Printf("Skipping JWK %q: %v", jwk.KeyID, err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	r.keys = keys
+	return nil
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// KeyConfig describes where token keys come from
+type KeyConfig struct {
+	Secret            string   // HS256 shared secret
+	SecretFile        string   // file holding the HS256 shared secret, e.g. a mounted Kubernetes secret
+	PrivateKeyFile    string   // PEM RSA or Ed25519 private key; takes precedence over the secret
+	KeyID             string   // kid for the signing key; derived from the key when empty
+	PreviousKeyFiles  []string // keys from before a rotation, still accepted for verification
+	PreviousKeysUntil string   // RFC 3339 time at which previous keys stop validating; empty means never
+	PublicKeyFiles    []string // PEM public keys accepted for verification only
+	JWKSURL           string   // JWKS endpoint to fetch verification keys from
+}
+
+// Keyring holds the key tokens are signed with and every key they may be verified with
+type Keyring struct {
+	signing *Key
+	keys    []*Key
+
+	jwks *remoteJWKS
+}
+
+// NewKeyring creates a Keyring that signs with signing (which may be nil for
+// verify-only services) and verifies with signing plus any extra keys
+func NewKeyring(signing *Key, verifying ...*Key) *Keyring {
+	k := &Keyring{signing: signing}
+	if signing != nil {
+		k.keys = append(k.keys, signing)
+	}
+	k.keys = append(k.keys, verifying...)
+	return k
+}
+
+// LoadKeyring builds a Keyring from configuration
+func LoadKeyring(cfg KeyConfig) (*Keyring, error) {
+	// Load the signing key
+	var signing *Key
+	var err error
+	switch {
+	case cfg.PrivateKeyFile != "":
+		signing, err = LoadKeyFile(cfg.KeyID, cfg.PrivateKeyFile)
+		if err == nil && !signing.CanSign() {
+			err = errors.New("not a private key")
+		}
+	case cfg.SecretFile != "":
+		signing, err = LoadKeyFile(cfg.KeyID, cfg.SecretFile)
+	case cfg.Secret != "":
+		signing, err = NewHMACKey(cfg.KeyID, []byte(cfg.Secret))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signing key: %w", err)
+	}
+
+	// Load keys from before the last rotation
+	var notAfter time.Time
+	if cfg.PreviousKeysUntil != "" {
+		notAfter, err = time.Parse(time.RFC3339, cfg.PreviousKeysUntil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid previous key expiry: %w", err)
+		}
+	}
+	var verifying []*Key
+	for _, path := range cfg.PreviousKeyFiles {
+		key, err := LoadKeyFile("", path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load previous key %s: %w", path, err)
+		}
+		key.NotAfter = notAfter
+		verifying = append(verifying, key)
+	}
+
+	// Load verify-only public keys
+	for _, path := range cfg.PublicKeyFiles {
+		key, err := LoadKeyFile("", path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load public key %s: %w", path, err)
+		}
+		verifying = append(verifying, key)
+	}
+
+	keyring := NewKeyring(signing, verifying...)
+	if cfg.JWKSURL != "" {
+		keyring.jwks = newRemoteJWKS(cfg.JWKSURL)
+	}
+	if len(keyring.keys) == 0 && keyring.jwks == nil {
+		return nil, errors.New("no token keys configured")
+	}
+	return keyring, nil
+}
+
+// CanSign reports whether the keyring can issue tokens
+func (k *Keyring) CanSign() bool {
+	return k.signing != nil && k.signing.CanSign()
+}
+
+// lookup returns the keys that may have signed a token with the given header
+func (k *Keyring) lookup(kid, alg string) []*Key {
+	now := time.Now()
+	var candidates []*Key
+
+	for _, key := range k.keys {
+		if key.Algorithm != alg || key.retired(now) {
+			continue
+		}
+		// Tokens issued before kids were introduced carry none
+		if kid == "" || key.ID == kid {
+			candidates = append(candidates, key)
+		}
+	}
+
+	if len(candidates) == 0 && k.jwks != nil {
+		candidates = k.jwks.lookup(kid, alg)
+	}
+	return candidates
+}
+
+// Keys returns the keys currently accepted for verification, excluding retired ones
+func (k *Keyring) Keys() []*Key {
+	now := time.Now()
+	var keys []*Key
+	for _, key := range k.keys {
+		if !key.retired(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Supported signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// minSecretLength is the shortest HS256 secret we accept (256 bits)
+const minSecretLength = 32
+
+// Key is a token signing or verification key identified by its kid
+type Key struct {
+	ID        string
+	Algorithm string
+	// NotAfter retires the key: tokens signed with it are rejected after this time.
+	// The zero value means the key does not expire.
+	NotAfter time.Time
+
+	secret     []byte
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+}
+
+// NewHMACKey creates an HS256 key from a shared secret
+func NewHMACKey(id string, secret []byte) (*Key, error) {
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("HS256 secret must be at least %d bytes", minSecretLength)
+	}
+	if id == "" {
+		sum := sha256.Sum256(append([]byte("kid:"), secret...))
+		id = base64.RawURLEncoding.EncodeToString(sum[:8])
+	}
+	return &Key{ID: id, Algorithm: AlgHS256, secret: secret}, nil
+}
+
+// NewPrivateKey creates an RS256 or EdDSA signing key from an RSA or Ed25519 private key
+func NewPrivateKey(id string, signer crypto.Signer) (*Key, error) {
+	key, err := NewPublicKey(id, signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.privateKey = signer
+	return key, nil
+}
+
+// NewPublicKey creates a verify-only RS256 or EdDSA key
+func NewPublicKey(id string, pub crypto.PublicKey) (*Key, error) {
+	key := &Key{ID: id, publicKey: pub}
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		key.Algorithm = AlgRS256
+	case ed25519.PublicKey:
+		key.Algorithm = AlgEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", pub)
+	}
+	if key.ID == "" {
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(der)
+		key.ID = base64.RawURLEncoding.EncodeToString(sum[:8])
+	}
+	return key, nil
+}
+
+// ParsePEMKey parses a PEM encoded RSA or Ed25519 private or public key
+func ParsePEMKey(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", parsed)
+		}
+		return NewPrivateKey(id, signer)
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewPrivateKey(id, parsed)
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewPublicKey(id, parsed)
+	case "RSA PUBLIC KEY":
+		parsed, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return NewPublicKey(id, parsed)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// LoadKeyFile reads a PEM key, or a raw HS256 secret if the file is not PEM encoded
+func LoadKeyFile(id, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(string(data), "-----BEGIN") {
+		return ParsePEMKey(id, data)
+	}
+	return NewHMACKey(id, []byte(strings.TrimSpace(string(data))))
+}
+
+// CanSign reports whether the key holds private material
+func (k *Key) CanSign() bool {
+	return k.secret != nil || k.privateKey != nil
+}
+
+// retired reports whether the key's overlap window has ended
+func (k *Key) retired(now time.Time) bool {
+	return !k.NotAfter.IsZero() && now.After(k.NotAfter)
+}
+
+// sign produces the signature for the given signing input
+func (k *Key) sign(input []byte) ([]byte, error) {
+	switch k.Algorithm {
+	case AlgHS256:
+		h := hmac.New(sha256.New, k.secret)
+		h.Write(input)
+		return h.Sum(nil), nil
+	case AlgRS256:
+		digest := sha256.Sum256(input)
+		return k.privateKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+	case AlgEdDSA:
+		return k.privateKey.Sign(rand.Reader, input, crypto.Hash(0))
+	}
+	return nil, fmt.Errorf("unsupported algorithm %q", k.Algorithm)
+}
+
+// verify checks a signature over the given signing input
+func (k *Key) verify(input, signature []byte) bool {
+	switch k.Algorithm {
+	case AlgHS256:
+		if k.secret == nil {
+			return false
+		}
+		h := hmac.New(sha256.New, k.secret)
+		h.Write(input)
+		return hmac.Equal(signature, h.Sum(nil))
+	case AlgRS256:
+		pub, ok := k.publicKey.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		digest := sha256.Sum256(input)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	case AlgEdDSA:
+		pub, ok := k.publicKey.(ed25519.PublicKey)
+		if !ok {
+			return false
+		}
+		return ed25519.Verify(pub, input, signature)
+	}
+	return false
+}
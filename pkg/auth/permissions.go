@@ -0,0 +1,100 @@
+package auth
+
+// Roles a user can hold, from most to least privileged
+const (
+	RoleAdmin       = "admin"
+	RoleOverseer    = "overseer"
+	RoleGroupLeader = "group_leader"
+	RoleTeacher     = "teacher"
+	RoleMember      = "member"
+)
+
+// Permissions name an action on a resource as "resource:action". Reading
+// statuses, lessons and rooms is public and needs no permission.
+const (
+	ContactsRead   = "contacts:read"
+	ContactsWrite  = "contacts:write"
+	ContactsDelete = "contacts:delete"
+	HistoryWrite   = "history:write" // correct and void contacts' status history
+	TasksWrite     = "tasks:write"   // add, change and complete follow-up tasks on visible contacts
+
+	StatusesWrite = "statuses:write"
+	FieldsWrite   = "fields:write" // define custom contact fields
+	TagsWrite     = "tags:write"   // rename, merge and delete tags; anyone who can write contacts can create and apply them
+	LessonsWrite  = "lessons:write"
+
+	StudiesRead   = "studies:read"
+	StudiesWrite  = "studies:write"
+	StudiesDelete = "studies:delete"
+
+	RoomsWrite = "rooms:write"
+
+	ReservationsRead   = "reservations:read"
+	ReservationsWrite  = "reservations:write"  // book rooms and change one's own reservations
+	ReservationsManage = "reservations:manage" // change anyone's reservations
+
+	UsersRead  = "users:read"
+	UsersWrite = "users:write"
+	RolesGrant = "roles:grant"
+
+	GroupsWrite = "groups:write" // create groups and manage their leaders and members
+)
+
+// allPermissions lists every known permission; admins hold all of them
+var allPermissions = []string{
+	ContactsRead, ContactsWrite, ContactsDelete, HistoryWrite, TasksWrite,
+	StatusesWrite, FieldsWrite, TagsWrite, LessonsWrite,
+	StudiesRead, StudiesWrite, StudiesDelete,
+	RoomsWrite,
+	ReservationsRead, ReservationsWrite, ReservationsManage,
+	UsersRead, UsersWrite, RolesGrant,
+	GroupsWrite,
+}
+
+// rolePermissions is the permission matrix: what each role may do
+var rolePermissions = map[string][]string{
+	RoleAdmin: allPermissions,
+	RoleOverseer: {
+		ContactsRead, ContactsWrite, ContactsDelete, HistoryWrite, TasksWrite,
+		TagsWrite, LessonsWrite,
+		StudiesRead, StudiesWrite, StudiesDelete,
+		ReservationsRead, ReservationsWrite, ReservationsManage,
+		UsersRead, RolesGrant,
+		GroupsWrite,
+	},
+	RoleGroupLeader: {
+		ContactsRead, ContactsWrite, TasksWrite,
+		StudiesRead, StudiesWrite,
+		ReservationsRead, ReservationsWrite,
+	},
+	RoleTeacher: {
+		ContactsRead, TasksWrite,
+		StudiesRead, StudiesWrite,
+		ReservationsRead, ReservationsWrite,
+	},
+	RoleMember: {
+		ContactsRead, // only contacts they own or are assigned
+		TasksWrite,
+		ReservationsRead, ReservationsWrite,
+	},
+}
+
+// Can reports whether a role holds a permission
+func Can(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// KnownPermission reports whether permission appears in the matrix
+func KnownPermission(permission string) bool {
+	return Can(RoleAdmin, permission)
+}
+
+// Permissions returns the permissions a role holds
+func Permissions(role string) []string {
+	return append([]string(nil), rolePermissions[role]...)
+}
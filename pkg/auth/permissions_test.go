@@ -0,0 +1,54 @@
+package auth
+
+import "testing"
+
+func TestCan(t *testing.T) {
+	tests := []struct {
+		role       string
+		permission string
+		want       bool
+	}{
+		{RoleAdmin, StatusesWrite, true},
+		{RoleAdmin, UsersWrite, true},
+		{RoleOverseer, LessonsWrite, true},
+		{RoleOverseer, StatusesWrite, false},
+		{RoleAdmin, FieldsWrite, true},
+		{RoleOverseer, FieldsWrite, false},
+		{RoleOverseer, TagsWrite, true},
+		{RoleOverseer, HistoryWrite, true},
+		{RoleGroupLeader, HistoryWrite, false},
+		{RoleGroupLeader, TagsWrite, false},
+		{RoleOverseer, UsersWrite, false},
+		{RoleOverseer, GroupsWrite, true},
+		{RoleGroupLeader, GroupsWrite, false},
+		{RoleGroupLeader, ContactsWrite, true},
+		{RoleGroupLeader, ContactsDelete, false},
+		{RoleTeacher, StudiesWrite, true},
+		{RoleTeacher, ContactsWrite, false},
+		{RoleTeacher, LessonsWrite, false},
+		{RoleMember, ReservationsWrite, true},
+		{RoleMember, ReservationsManage, false},
+		{RoleMember, ContactsRead, true},
+		{RoleMember, ContactsWrite, false},
+		{RoleMember, StudiesRead, false},
+		{RoleMember, TasksWrite, true},
+		{"", ReservationsRead, false},
+		{"guest", ReservationsRead, false},
+	}
+
+	for _, tt := range tests {
+		if got := Can(tt.role, tt.permission); got != tt.want {
+			t.Errorf("Can(%q, %q) = %v, want %v", tt.role, tt.permission, got, tt.want)
+		}
+	}
+}
+
+func TestEveryPermissionIsKnown(t *testing.T) {
+	for role, permissions := range rolePermissions {
+		for _, p := range permissions {
+			if !KnownPermission(p) {
+				t.Errorf("role %s holds unknown permission %s", role, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RevocationList reports whether a session has been revoked
+type RevocationList interface {
+	IsRevoked(sessionID string) bool
+}
+
+// noRevocations is used until a service installs a RevocationList
+type noRevocations struct{}
+
+func (noRevocations) IsRevoked(string) bool { return false }
+
+var (
+	revocationMu   sync.RWMutex
+	revocationList RevocationList = noRevocations{}
+)
+
+// UseRevocationList sets the list VerifyToken checks session IDs against
+func UseRevocationList(r RevocationList) {
+	revocationMu.Lock()
+	defer revocationMu.Unlock()
+	revocationList = r
+}
+
+func currentRevocationList() RevocationList {
+	revocationMu.RLock()
+	defer revocationMu.RUnlock()
+	return revocationList
+}
+
+// RevokedSessions is the response of user-service's revoked sessions endpoint
+type RevokedSessions struct {
+	SessionIDs []string `json:"session_ids"`
+}
+
+// RemoteRevocationList polls user-service for recently revoked sessions.
+// Access tokens are short-lived, so only sessions revoked within one access
+// token lifetime need to be known.
+type RemoteRevocationList struct {
+	url    string
+	client *http.Client
+
+	mu      sync.RWMutex
+	revoked map[string]bool
+}
+
+// NewRemoteRevocationList starts polling url every interval
+func NewRemoteRevocationList(url string, interval time.Duration) *RemoteRevocationList {
+	l := &RemoteRevocationList{
+		url:     url,
+		client:  &http.Client{Timeout: 5 * time.Second},
+		revoked: map[string]bool{},
+	}
+	go func() {
+		for {
+			if err := l.refresh(); err != nil {
+				log.Printf("Failed to refresh revoked sessions from %s: %v", l.url, err)
+			}
+			time.Sleep(interval)
+		}
+	}()
+	return l
+}
+
+// IsRevoked reports whether the session was in the last fetched list
+func (l *RemoteRevocationList) IsRevoked(sessionID string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.revoked[sessionID]
+}
+
+func (l *RemoteRevocationList) refresh() error {
+	resp, err := l.client.Get(l.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var body RevokedSessions
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+
+	revoked := make(map[string]bool, len(body.SessionIDs))
+	for _, id := range body.SessionIDs {
+		revoked[id] = true
+	}
+
+	l.mu.Lock()
+	l.revoked = revoked
+	l.mu.Unlock()
+	return nil
+}
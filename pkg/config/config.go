@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cardoza1991/church-management-system/pkg/auth"
+)
+
+// DB holds the database settings shared by every service
+type DB struct {
+	DBHost     string
+	DBPort     string
+	DBUser     string
+	DBPassword string
+	DBName     string
+}
+
+// LoadDB returns database settings populated from environment variables
+func LoadDB() DB {
+	return DB{
+		DBHost:     GetEnv("DB_HOST", "localhost"),
+		DBPort:     GetEnv("DB_PORT", "3306"),
+		DBUser:     GetEnv("DB_USER", "root"),
+		DBPassword: GetEnv("DB_PASSWORD", ""),
+		DBName:     GetEnv("DB_NAME", "church_mgmt"),
+	}
+}
+
+// DSN returns a formatted database connection string
+func (c DB) DSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", 
+		c.DBUser, c.DBPassword, c.DBHost, c.DBPort, c.DBName)
+}
+
+// LoadKeys returns token key settings populated from environment variables.
+// Services that only verify tokens pass user-service's JWKS URL as the default.
+func LoadKeys(defaultJWKSURL string) auth.KeyConfig {
+	return auth.KeyConfig{
+		Secret:            GetEnv("JWT_SECRET", ""),
+		SecretFile:        GetEnv("JWT_SECRET_FILE", ""),
+		PrivateKeyFile:    GetEnv("JWT_PRIVATE_KEY_FILE", ""),
+		KeyID:             GetEnv("JWT_KEY_ID", ""),
+		PreviousKeyFiles:  GetEnvList("JWT_PREVIOUS_KEY_FILES"),
+		PreviousKeysUntil: GetEnv("JWT_PREVIOUS_KEYS_UNTIL", ""),
+		PublicKeyFiles:    GetEnvList("JWT_PUBLIC_KEY_FILES"),
+		JWKSURL:           GetEnv("JWKS_URL", defaultJWKSURL),
+	}
+}
+
+// GetEnv returns the value of an environment variable or a default value
+func GetEnv(key, defaultValue string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return defaultValue
+}
+
+// GetEnvList returns a comma separated environment variable as a list
+func GetEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(GetEnv(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// GetEnvDuration returns an environment variable parsed as a duration, or the
+// default value if it is unset or invalid
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// GetEnvInt returns an environment variable parsed as an integer, or the
+// default value if it is unset or invalid
+func GetEnvInt(key string, defaultValue int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
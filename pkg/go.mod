@@ -0,0 +1,7 @@
+module github.com/cardoza1991/church-management-system/pkg
+
+go 1.22.2
+
+require github.com/go-sql-driver/mysql v1.9.0
+
+require filippo.io/edwards25519 v1.1.0 // indirect
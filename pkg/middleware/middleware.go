@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/cardoza1991/church-management-system/pkg/auth"
+)
+
+// Claims is re-exported so handlers only need to import this package
+type Claims = auth.Claims
+
+// contextKey is unexported to avoid collisions with other packages' context values
+type contextKey string
+
+const claimsKey contextKey = "user"
+
+// WithClaims returns a copy of ctx carrying the authenticated user's claims
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// ClaimsFromContext returns the claims set by AuthMiddleware
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// AuthMiddleware checks for a valid JWT token
+func AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Get token from Authorization header
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			http.Error(w, "Authorization header required", http.StatusUnauthorized)
+			return
+		}
+		
+		// Extract the token
+		tokenParts := strings.Split(authHeader, " ")
+		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+			http.Error(w, "Invalid token format", http.StatusUnauthorized)
+			return
+		}
+		
+		// Verify the token
+		claims, err := auth.VerifyToken(tokenParts[1])
+		if err != nil {
+			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+		
+		// Add claims to request context and call the next handler
+		next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+	})
+}
+
+// AdminRequired ensures the user has admin role
+func AdminRequired(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Get user from context (set by AuthMiddleware)
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		
+		// Check if user has admin role
+		if claims.Role != "admin" {
+			http.Error(w, "Admin access required", http.StatusForbidden)
+			return
+		}
+		
+		// Call the next handler
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Require ensures the user's role grants permission, e.g. Require("contacts:write").
+// It panics on an unknown permission so typos fail at startup.
+func Require(permission string) func(http.Handler) http.Handler {
+	if !auth.KnownPermission(permission) {
+		panic("middleware: unknown permission " + permission)
+	}
+	
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Get user from context (set by AuthMiddleware)
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			
+			// Check the role's permissions
+			if !auth.Can(claims.Role, permission) {
+				http.Error(w, "Insufficient permissions", http.StatusForbidden)
+				return
+			}
+			
+			// Call the next handler
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RespondJSON is a helper function to respond with JSON
+func RespondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
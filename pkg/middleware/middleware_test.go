@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cardoza1991/church-management-system/pkg/auth"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	key, err := auth.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewHMACKey: %v", err)
+	}
+	auth.UseKeyring(auth.NewKeyring(key))
+	defer auth.UseKeyring(auth.NewKeyring(nil))
+
+	claims := Claims{
+		UserID:    7,
+		Username:  "member1",
+		Role:      "member",
+		Issuer:    auth.Issuer,
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	}
+	expired := claims
+	expired.ExpiresAt = time.Now().Add(-time.Hour).Unix()
+
+	validToken, err := auth.SignToken(claims)
+	if err != nil {
+		t.Fatalf("SignToken: %v", err)
+	}
+	expiredToken, err := auth.SignToken(expired)
+	if err != nil {
+		t.Fatalf("SignToken: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+	}{
+		{"valid", "Bearer " + validToken, http.StatusOK},
+		{"missing header", "", http.StatusUnauthorized},
+		{"not bearer", "Basic dXNlcjpwYXNz", http.StatusUnauthorized},
+		{"expired", "Bearer " + expiredToken, http.StatusUnauthorized},
+		{"tampered", "Bearer " + validToken + "x", http.StatusUnauthorized},
+		{"malformed", "Bearer not-a-token", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *Claims
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = ClaimsFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			AuthMiddleware(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && (got == nil || *got != claims) {
+				t.Errorf("context claims = %+v, want %+v", got, claims)
+			}
+		})
+	}
+}
+
+func TestAdminRequired(t *testing.T) {
+	tests := []struct {
+		name       string
+		claims     *Claims
+		wantStatus int
+	}{
+		{"admin", &Claims{Role: "admin"}, http.StatusOK},
+		{"member", &Claims{Role: "member"}, http.StatusForbidden},
+		{"no claims", nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.claims != nil {
+				req = req.WithContext(WithClaims(req.Context(), tt.claims))
+			}
+			rec := httptest.NewRecorder()
+			AdminRequired(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRequire(t *testing.T) {
+	tests := []struct {
+		name       string
+		permission string
+		claims     *Claims
+		wantStatus int
+	}{
+		{"admin writes contacts", "contacts:write", &Claims{Role: "admin"}, http.StatusOK},
+		{"leader writes contacts", "contacts:write", &Claims{Role: "group_leader"}, http.StatusOK},
+		{"teacher writes contacts", "contacts:write", &Claims{Role: "teacher"}, http.StatusForbidden},
+		{"member books rooms", "reservations:write", &Claims{Role: "member"}, http.StatusOK},
+		{"unknown role", "reservations:write", &Claims{Role: "guest"}, http.StatusForbidden},
+		{"no claims", "contacts:read", nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.claims != nil {
+				req = req.WithContext(WithClaims(req.Context(), tt.claims))
+			}
+			rec := httptest.NewRecorder()
+			Require(tt.permission)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRequireUnknownPermission(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Require did not panic on an unknown permission")
+		}
+	}()
+	Require("contacts:wirte")
+}
@@ -0,0 +1,184 @@
+// Package pagination implements offset and keyset (cursor) pagination for
+// list endpoints.
+//
+// Keyset pages are ordered by a sort key and then a unique ID, and each page
+// starts just after (or, going backwards, just before) the row named by an
+// opaque cursor. Unlike offsets, cursors do not skip or repeat rows when rows
+// are added or removed between requests.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Cursor marks the row at the edge of a page
+type Cursor struct {
+	Sort   string `json:"s,omitempty"` // sort the cursor was made for
+	Key    string `json:"k"`           // sort key of the edge row
+	ID     int    `json:"i"`           // ID of the edge row
+	Before bool   `json:"b,omitempty"` // the page before the edge row rather than after it
+}
+
+// ErrInvalidCursor is returned for cursors that cannot be decoded or belong
+// to a different sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// String encodes the cursor as an opaque URL-safe token
+func (c Cursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseCursor decodes a token made by Cursor.String
+func ParseCursor(token string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// FormatTime formats a sort key time the way MySQL compares it
+func FormatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.999999")
+}
+
+// Request is the page a client asked for. With a Cursor, Offset is ignored.
+type Request struct {
+	Limit  int // zero means no limit
+	Offset int
+	Cursor *Cursor
+}
+
+// ParseRequest reads the limit, offset and cursor query parameters. Invalid
+// limits and offsets fall back to the defaults; limits above maxLimit, if
+// maxLimit is set, are ignored too. Only a bad cursor is an error.
+func ParseRequest(r *http.Request, defaultLimit, maxLimit int) (Request, error) {
+	q := r.URL.Query()
+	req := Request{Limit: defaultLimit}
+
+	if limit, err := strconv.Atoi(q.Get("limit")); err == nil && limit > 0 && (maxLimit == 0 || limit <= maxLimit) {
+		req.Limit = limit
+	}
+	if offset, err := strconv.Atoi(q.Get("offset")); err == nil && offset >= 0 {
+		req.Offset = offset
+	}
+
+	if token := q.Get("cursor"); token != "" {
+		c, err := ParseCursor(token)
+		if err != nil {
+			return req, err
+		}
+		req.Cursor = c
+		req.Offset = 0
+	}
+	return req, nil
+}
+
+// CheckSort rejects a cursor made for a different sort
+func (req Request) CheckSort(sort string) error {
+	if req.Cursor != nil && req.Cursor.Sort != sort {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// Keyset orders a listing by a sort key and then a unique ID
+type Keyset struct {
+	Key        string // SQL expression of the sort key
+	ID         string // SQL expression of the unique ID
+	Descending bool
+}
+
+// Where returns an SQL condition selecting the rows past the request's cursor
+func (k Keyset) Where(req Request) (string, []interface{}) {
+	c := req.Cursor
+	if c == nil {
+		return "1 = 1", nil
+	}
+	op := ">"
+	if c.Before != k.Descending {
+		op = "<"
+	}
+	return "(" + k.Key + " " + op + " ? OR (" + k.Key + " = ? AND " + k.ID + " " + op + " ?))",
+		[]interface{}{c.Key, c.Key, c.ID}
+}
+
+// OrderBy returns the ORDER BY clause, reversed when paging backwards
+func (k Keyset) OrderBy(req Request) string {
+	descending := k.Descending
+	if req.Cursor != nil && req.Cursor.Before {
+		descending = !descending
+	}
+	direction := " ASC"
+	if descending {
+		direction = " DESC"
+	}
+	return k.Key + direction + ", " + k.ID + direction
+}
+
+// QueryLimit returns the LIMIT and OFFSET to query with. One extra row is
+// fetched to tell whether another page follows.
+func (req Request) QueryLimit() (int, int) {
+	if req.Limit == 0 {
+		return math.MaxInt32, req.Offset
+	}
+	return req.Limit + 1, req.Offset
+}
+
+// Cursors holds the cursors of the pages either side of a page. They are
+// empty when there is no such page.
+type Cursors struct {
+	Next string
+	Prev string
+}
+
+// Page trims rows fetched with QueryLimit to the requested page, in display
+// order, and returns cursors for the neighbouring pages. edge returns the
+// cursor for a row; Page sets its direction.
+func Page[T any](rows []T, req Request, edge func(T) Cursor) ([]T, Cursors) {
+	more := req.Limit > 0 && len(rows) > req.Limit
+	if more {
+		rows = rows[:req.Limit]
+	}
+
+	backward := req.Cursor != nil && req.Cursor.Before
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, Cursors{}
+	}
+
+	// Going forwards, earlier rows exist if we started past the first row;
+	// going backwards, later rows always exist
+	hasNext, hasPrev := more, req.Cursor != nil || req.Offset > 0
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+
+	var cursors Cursors
+	if hasNext {
+		c := edge(rows[len(rows)-1])
+		c.Before = false
+		cursors.Next = c.String()
+	}
+	if hasPrev {
+		c := edge(rows[0])
+		c.Before = true
+		cursors.Prev = c.String()
+	}
+	return rows, cursors
+}
@@ -0,0 +1,107 @@
+package pagination
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := Cursor{Sort: "name:asc", Key: "Smith, Ann", ID: 42, Before: true}
+	got, err := ParseCursor(c.String())
+	if err != nil {
+		t.Fatalf("ParseCursor: %v", err)
+	}
+	if *got != c {
+		t.Errorf("round trip = %+v, want %+v", *got, c)
+	}
+
+	if _, err := ParseCursor("not a cursor!"); err != ErrInvalidCursor {
+		t.Errorf("ParseCursor(garbage) error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/contacts?limit=500&offset=40", nil)
+	req, err := ParseRequest(r, 20, 100)
+	if err != nil || req.Limit != 20 || req.Offset != 40 || req.Cursor != nil {
+		t.Errorf("ParseRequest = %+v, %v; want limit 20 offset 40", req, err)
+	}
+
+	token := Cursor{Key: "a", ID: 1}.String()
+	r = httptest.NewRequest("GET", "/contacts?offset=40&cursor="+token, nil)
+	req, err = ParseRequest(r, 20, 100)
+	if err != nil || req.Offset != 0 || req.Cursor == nil {
+		t.Errorf("ParseRequest with cursor = %+v, %v; want cursor and no offset", req, err)
+	}
+	if err := req.CheckSort("name:asc"); err != ErrInvalidCursor {
+		t.Errorf("CheckSort for another sort = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestKeyset(t *testing.T) {
+	k := Keyset{Key: "c.name", ID: "c.id"}
+	after := Request{Cursor: &Cursor{Key: "m", ID: 7}}
+	before := Request{Cursor: &Cursor{Key: "m", ID: 7, Before: true}}
+
+	where, args := k.Where(after)
+	if where != "(c.name > ? OR (c.name = ? AND c.id > ?))" || !reflect.DeepEqual(args, []interface{}{"m", "m", 7}) {
+		t.Errorf("Where(after) = %q %v", where, args)
+	}
+	if where, _ := k.Where(before); where != "(c.name < ? OR (c.name = ? AND c.id < ?))" {
+		t.Errorf("Where(before) = %q", where)
+	}
+	if order := k.OrderBy(before); order != "c.name DESC, c.id DESC" {
+		t.Errorf("OrderBy(before) = %q", order)
+	}
+
+	k.Descending = true
+	if where, _ := k.Where(after); where != "(c.name < ? OR (c.name = ? AND c.id < ?))" {
+		t.Errorf("descending Where(after) = %q", where)
+	}
+	if where, _ := k.Where(Request{}); where != "1 = 1" {
+		t.Errorf("Where without cursor = %q", where)
+	}
+}
+
+func TestPage(t *testing.T) {
+	edge := func(id int) Cursor { return Cursor{ID: id} }
+	decode := func(token string) int {
+		if token == "" {
+			return 0
+		}
+		c, err := ParseCursor(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.ID
+	}
+
+	tests := []struct {
+		name       string
+		rows       []int
+		req        Request
+		want       []int
+		next, prev int
+	}{
+		{"first page", []int{1, 2, 3}, Request{Limit: 2}, []int{1, 2}, 2, 0},
+		{"last page", []int{3, 4}, Request{Limit: 2, Cursor: &Cursor{ID: 2}}, []int{3, 4}, 0, 3},
+		{"offset page", []int{5, 6, 7}, Request{Limit: 2, Offset: 4}, []int{5, 6}, 6, 5},
+		{"backward", []int{4, 3, 2}, Request{Limit: 2, Cursor: &Cursor{ID: 5, Before: true}}, []int{3, 4}, 4, 3},
+		{"backward to start", []int{2, 1}, Request{Limit: 2, Cursor: &Cursor{ID: 3, Before: true}}, []int{1, 2}, 2, 0},
+		{"no limit", []int{1, 2, 3}, Request{}, []int{1, 2, 3}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		rows, cursors := Page(tt.rows, tt.req, edge)
+		if !reflect.DeepEqual(rows, tt.want) {
+			t.Errorf("%s: rows = %v, want %v", tt.name, rows, tt.want)
+		}
+		if got := decode(cursors.Next); got != tt.next {
+			t.Errorf("%s: next cursor at %d, want %d", tt.name, got, tt.next)
+		}
+		if got := decode(cursors.Prev); got != tt.prev {
+			t.Errorf("%s: prev cursor at %d, want %d", tt.name, got, tt.prev)
+		}
+	}
+}
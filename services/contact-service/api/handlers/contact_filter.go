@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
+)
+
+// parseContactFilter reads ListContacts' search, filter and sort parameters.
+// Dates are YYYY-MM-DD, where a "to" date includes that whole day, or RFC 3339.
+// fields are the custom fields that may be filtered on.
+func parseContactFilter(r *http.Request, fields []*models.CustomField) (models.ContactFilter, error) {
+	q := r.URL.Query()
+	filter := models.ContactFilter{
+		Search: strings.TrimSpace(q.Get("search")),
+		Sort:   q.Get("sort"),
+	}
+	
+	var err error
+	if filter.IDs, err = parseIDList(q["ids"], "ids"); err != nil {
+		return filter, err
+	}
+	if filter.TagIDs, err = parseIDList(q["tag_id"], "tag_id"); err != nil {
+		return filter, err
+	}
+	if filter.StatusID, err = parseIDParam(q.Get("current_status_id"), "current_status_id"); err != nil {
+		return filter, err
+	}
+	if filter.AssignedWorkerID, err = parseIDParam(q.Get("assigned_worker_id"), "assigned_worker_id"); err != nil {
+		return filter, err
+	}
+	if filter.GroupID, err = parseIDParam(q.Get("group_id"), "group_id"); err != nil {
+		return filter, err
+	}
+	
+	dates := []struct {
+		name  string
+		dest  *time.Time
+		until bool
+	}{
+		{"date_added_from", &filter.AddedFrom, false},
+		{"date_added_to", &filter.AddedTo, true},
+		{"last_updated_from", &filter.UpdatedFrom, false},
+		{"last_updated_to", &filter.UpdatedTo, true},
+	}
+	for _, d := range dates {
+		if *d.dest, err = parseDateParam(q.Get(d.name), d.until); err != nil {
+			return filter, fmt.Errorf("invalid %s: %v", d.name, err)
+		}
+	}
+	
+	if filter.Fields, err = parseFieldFilters(q, fields); err != nil {
+		return filter, err
+	}
+	
+	if filter.Sort != "" {
+		if _, ok := models.ContactSortFields[filter.Sort]; !ok {
+			return filter, fmt.Errorf("invalid sort field %q", filter.Sort)
+		}
+	}
+	switch strings.ToLower(q.Get("order")) {
+	case "", "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		return filter, fmt.Errorf("invalid order %q: use asc or desc", q.Get("order"))
+	}
+	
+	return filter, nil
+}
+
+// parseFieldFilters reads custom field filters: field.<key>=value matches a
+// value exactly, and field.<key>.from and field.<key>.to bound number and
+// date fields. Values are given as they would be in a request body.
+func parseFieldFilters(q url.Values, fields []*models.CustomField) ([]models.FieldFilter, error) {
+	byKey := make(map[string]*models.CustomField, len(fields))
+	for _, field := range fields {
+		byKey[field.Key] = field
+	}
+	
+	filters := make(map[string]*models.FieldFilter)
+	for name, values := range q {
+		if !strings.HasPrefix(name, "field.") {
+			continue
+		}
+		key, bound := strings.TrimPrefix(name, "field."), ""
+		if i := strings.LastIndex(key, "."); i >= 0 {
+			key, bound = key[:i], key[i+1:]
+		}
+		
+		field, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown custom field %q", key)
+		}
+		value, err := field.Normalize(values[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", name, err.Error())
+		}
+		
+		filter := filters[key]
+		if filter == nil {
+			filter = &models.FieldFilter{Field: field}
+			filters[key] = filter
+		}
+		switch {
+		case bound == "":
+			filter.Value = value
+		case field.Type != models.FieldNumber && field.Type != models.FieldDate:
+			return nil, fmt.Errorf("invalid %s: only number and date fields have ranges", name)
+		case bound == "from":
+			filter.From = value
+		case bound == "to":
+			filter.To = value
+		default:
+			return nil, fmt.Errorf("invalid %s: use field.%s, field.%s.from or field.%s.to", name, key, key, key)
+		}
+	}
+	
+	// Keep the field order so the same filters make the same query
+	var result []models.FieldFilter
+	for _, field := range fields {
+		if filter := filters[field.Key]; filter != nil {
+			result = append(result, *filter)
+		}
+	}
+	return result, nil
+}
+
+// parseIDParam parses an optional positive ID
+func parseIDParam(value, name string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid %s", name)
+	}
+	return id, nil
+}
+
+// parseIDList parses IDs given as repeated parameters, comma-separated
+// lists or both
+func parseIDList(values []string, name string) ([]int, error) {
+	var ids []int
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			id, err := parseIDParam(strings.TrimSpace(part), name)
+			if err != nil {
+				return nil, err
+			}
+			if id != 0 {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// parseDateParam parses an optional date. A date without a time given as the
+// end of a range moves to the following midnight so the day is included.
+func parseDateParam(value string, until bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		if until {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
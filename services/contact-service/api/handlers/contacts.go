@@ -2,12 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/cardoza1991/church-management-system/services/contact-service/api/middleware"
+	"github.com/cardoza1991/church-management-system/pkg/auth"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/pkg/pagination"
 	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
 )
 
@@ -15,42 +18,59 @@ import (
 type ContactHandler struct {
 	ContactRepo *models.ContactRepository
 	StatusRepo  *models.StatusRepository
+	FieldRepo   *models.CustomFieldRepository
+	TagRepo     *models.TagRepository
+	TransitionRepo *models.TransitionRepository
+	TaskRepo    *models.TaskRepository
+	InteractionRepo *models.InteractionRepository
+	Downline    *auth.DownlineClient // asks user-service who is under the caller
 }
 
-// ListContacts returns a list of contacts
+// ListContacts returns a page of the contacts the caller may see, optionally
+// searched, filtered and sorted
 func (h *ContactHandler) ListContacts(w http.ResponseWriter, r *http.Request) {
-	// Get pagination parameters
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-	
-	limit := 20 // Default limit
-	if limitStr != "" {
-		parsedLimit, err := strconv.Atoi(limitStr)
-		if err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-		}
+	// Work out which contacts the caller may see
+	scope, err := h.scope(r)
+	if err != nil {
+		http.Error(w, "Failed to determine contact scope: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 	
-	offset := 0 // Default offset
-	if offsetStr != "" {
-		parsedOffset, err := strconv.Atoi(offsetStr)
-		if err == nil && parsedOffset >= 0 {
-			offset = parsedOffset
-		}
+	// Get search, filter and sort parameters
+	filter, ok := h.contactFilter(w, r)
+	if !ok {
+		return
+	}
+	
+	// Get pagination parameters: a cursor from a previous page, or limit and offset
+	page, err := pagination.ParseRequest(r, 20, 100)
+	if err == nil {
+		err = page.CheckSort(filter.SortName())
+	}
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
 	}
 	
 	// Fetch contacts from repository
-	contacts, err := h.ContactRepo.GetAll(limit, offset)
+	contacts, total, cursors, err := h.ContactRepo.GetAll(scope, filter, page)
 	if err != nil {
 		http.Error(w, "Failed to fetch contacts: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if err := h.loadDetails(contacts...); err != nil {
+		http.Error(w, "Failed to fetch contact details: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 	
 	// Return response
 	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
-		"contacts": contacts,
-		"limit":    limit,
-		"offset":   offset,
+		"contacts":    contacts,
+		"total":       total,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": cursors.Next,
+		"prev_cursor": cursors.Prev,
 	})
 }
 
@@ -65,9 +85,12 @@ func (h *ContactHandler) GetContact(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Fetch contact from repository
-	contact, err := h.ContactRepo.GetByID(id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+	contact, _, ok := h.visibleContact(w, r, id)
+	if !ok {
+		return
+	}
+	if err := h.loadDetails(contact); err != nil {
+		http.Error(w, "Failed to fetch contact details: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 	
@@ -77,16 +100,27 @@ func (h *ContactHandler) GetContact(w http.ResponseWriter, r *http.Request) {
 
 // ContactRequest represents a request to create or update a contact
 type ContactRequest struct {
-	Name           string `json:"name"`
-	Email          string `json:"email,omitempty"`
-	Phone          string `json:"phone,omitempty"`
-	Location       string `json:"location,omitempty"`
-	Notes          string `json:"notes,omitempty"`
-	CurrentStatusID int   `json:"current_status_id"`
+	Name             string `json:"name"`
+	Email            string `json:"email,omitempty"`
+	Phone            string `json:"phone,omitempty"`
+	Location         string `json:"location,omitempty"`
+	Notes            string `json:"notes,omitempty"`
+	CurrentStatusID  int    `json:"current_status_id"`
+	AssignedWorkerID int    `json:"assigned_worker_id,omitempty"` // defaults to the creator; omitted on update keeps the current worker
+	GroupID          int    `json:"group_id,omitempty"`           // omitted on update keeps the current group
+
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"` // by field key; on update, omitted fields are kept and null clears one
 }
 
-// CreateContact handles creating a new contact
+// CreateContact handles creating a new contact owned by the caller
 func (h *ContactHandler) CreateContact(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
 	// Parse request
 	var req ContactRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -118,16 +152,38 @@ func (h *ContactHandler) CreateContact(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	
+	// The creator works with the contact unless someone in their scope is named
+	if req.AssignedWorkerID <= 0 {
+		req.AssignedWorkerID = claims.UserID
+	}
+	scope, err := h.scope(r)
+	if err != nil {
+		http.Error(w, "Failed to determine contact scope: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !checkAssignment(w, scope, req.AssignedWorkerID, req.GroupID) {
+		return
+	}
+	
+	// Validate custom fields, all required ones included
+	fieldValues, ok := h.customFieldValues(w, req.CustomFields, true)
+	if !ok {
+		return
+	}
+	
 	// Create contact
 	contact := &models.Contact{
-		Name:            req.Name,
-		Email:           req.Email,
-		Phone:           req.Phone,
-		Location:        req.Location,
-		Notes:           req.Notes,
-		CurrentStatusID: req.CurrentStatusID,
-		DateAdded:       time.Now(),
-		LastUpdated:     time.Now(),
+		Name:             req.Name,
+		Email:            req.Email,
+		Phone:            req.Phone,
+		Location:         req.Location,
+		Notes:            req.Notes,
+		CurrentStatusID:  req.CurrentStatusID,
+		OwnerID:          claims.UserID,
+		AssignedWorkerID: req.AssignedWorkerID,
+		GroupID:          req.GroupID,
+		DateAdded:        time.Now(),
+		LastUpdated:      time.Now(),
 	}
 	
 	// Save to database
@@ -137,13 +193,23 @@ func (h *ContactHandler) CreateContact(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Also add an entry in the status history
-	err := h.ContactRepo.UpdateStatus(contact.ID, contact.CurrentStatusID, "Initial status")
+	err = h.ContactRepo.UpdateStatus(contact.ID, contact.CurrentStatusID, "Initial status", time.Time{})
 	if err != nil {
 		// Log the error but don't fail the request
 		// In a real app, you might want to use proper logging
 		println("Failed to create status history: " + err.Error())
 	}
 	
+	// Save custom fields
+	if err := h.ContactRepo.SetCustomFields(contact.ID, fieldValues); err != nil {
+		http.Error(w, "Contact created but failed to save custom fields: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.ContactRepo.LoadCustomFields(contact); err != nil {
+		http.Error(w, "Contact created but failed to retrieve custom fields: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
 	// Return response
 	middleware.RespondJSON(w, http.StatusCreated, contact)
 }
@@ -158,10 +224,9 @@ func (h *ContactHandler) UpdateContact(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// Check if contact exists
-	existingContact, err := h.ContactRepo.GetByID(id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+	// Check if contact exists and is visible to the caller
+	existingContact, scope, ok := h.visibleContact(w, r, id)
+	if !ok {
 		return
 	}
 	
@@ -190,19 +255,51 @@ func (h *ContactHandler) UpdateContact(w http.ResponseWriter, r *http.Request) {
 		req.CurrentStatusID = existingContact.CurrentStatusID
 	}
 	
-	// Check if status is being changed
+	// Keep the current worker and group unless new ones are given, and only
+	// hand the contact to someone within the caller's scope
+	if req.AssignedWorkerID <= 0 {
+		req.AssignedWorkerID = existingContact.AssignedWorkerID
+	} else if req.AssignedWorkerID != existingContact.AssignedWorkerID && !checkAssignment(w, scope, req.AssignedWorkerID, 0) {
+		return
+	}
+	if req.GroupID <= 0 {
+		req.GroupID = existingContact.GroupID
+	} else if req.GroupID != existingContact.GroupID && !checkAssignment(w, scope, 0, req.GroupID) {
+		return
+	}
+	
+	// Validate the custom fields being changed
+	fieldValues, ok := h.customFieldValues(w, req.CustomFields, false)
+	if !ok {
+		return
+	}
+	
+	// Check if status is being changed, and that the move is allowed. Edits
+	// carry no status note, so moves that require one must use the status endpoint.
 	statusChanged := existingContact.CurrentStatusID != req.CurrentStatusID
+	if statusChanged {
+		from, since, err := h.ContactRepo.StatusAsOf(existingContact, time.Time{})
+		if err != nil {
+			http.Error(w, "Failed to get status history: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !h.checkTransition(w, from, since, req.CurrentStatusID, "", time.Time{}) {
+			return
+		}
+	}
 	
 	// Update contact
 	contact := &models.Contact{
-		ID:              id,
-		Name:            req.Name,
-		Email:           req.Email,
-		Phone:           req.Phone,
-		Location:        req.Location,
-		Notes:           req.Notes,
-		CurrentStatusID: req.CurrentStatusID,
-		LastUpdated:     time.Now(),
+		ID:               id,
+		Name:             req.Name,
+		Email:            req.Email,
+		Phone:            req.Phone,
+		Location:         req.Location,
+		Notes:            req.Notes,
+		CurrentStatusID:  req.CurrentStatusID,
+		AssignedWorkerID: req.AssignedWorkerID,
+		GroupID:          req.GroupID,
+		LastUpdated:      time.Now(),
 	}
 	
 	// Save to database
@@ -210,10 +307,14 @@ func (h *ContactHandler) UpdateContact(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to update contact: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if err := h.ContactRepo.SetCustomFields(id, fieldValues); err != nil {
+		http.Error(w, "Failed to update custom fields: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 	
 	// If status changed, add an entry to the status history
 	if statusChanged {
-		err := h.ContactRepo.UpdateStatus(id, req.CurrentStatusID, "Status updated via contact edit")
+		err := h.ContactRepo.UpdateStatus(id, req.CurrentStatusID, "Status updated via contact edit", time.Time{})
 		if err != nil {
 			// Log the error but don't fail the request
 			println("Failed to update status history: " + err.Error())
@@ -222,6 +323,9 @@ func (h *ContactHandler) UpdateContact(w http.ResponseWriter, r *http.Request) {
 	
 	// Get the updated contact to return
 	updatedContact, err := h.ContactRepo.GetByID(id)
+	if err == nil {
+		err = h.loadDetails(updatedContact)
+	}
 	if err != nil {
 		http.Error(w, "Contact updated but failed to retrieve: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -241,10 +345,8 @@ func (h *ContactHandler) DeleteContact(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// Check if contact exists
-	_, err = h.ContactRepo.GetByID(id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+	// Check if contact exists and is visible to the caller
+	if _, _, ok := h.visibleContact(w, r, id); !ok {
 		return
 	}
 	
@@ -262,11 +364,14 @@ func (h *ContactHandler) DeleteContact(w http.ResponseWriter, r *http.Request) {
 
 // StatusUpdateRequest represents a request to change a contact's status
 type StatusUpdateRequest struct {
-	StatusID int    `json:"status_id"`
-	Notes    string `json:"notes,omitempty"`
+	StatusID      int    `json:"status_id"`
+	Notes         string `json:"notes,omitempty"`
+	EffectiveDate string `json:"effective_date,omitempty"` // YYYY-MM-DD or RFC 3339, when the change happened; default now
 }
 
-// UpdateContactStatus handles changing a contact's status
+// UpdateContactStatus handles changing a contact's status. A backdated change
+// is checked against the status the contact had then, and only becomes the
+// current status if nothing has been recorded since.
 func (h *ContactHandler) UpdateContactStatus(w http.ResponseWriter, r *http.Request) {
 	// Get ID from URL
 	vars := mux.Vars(r)
@@ -276,10 +381,9 @@ func (h *ContactHandler) UpdateContactStatus(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	
-	// Check if contact exists
-	contact, err := h.ContactRepo.GetByID(id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+	// Check if contact exists and is visible to the caller
+	contact, _, ok := h.visibleContact(w, r, id)
+	if !ok {
 		return
 	}
 	
@@ -296,6 +400,12 @@ func (h *ContactHandler) UpdateContactStatus(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	
+	effective, err := parsePastTime(req.EffectiveDate, "effective_date")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
 	// Check if status exists
 	_, err = h.StatusRepo.GetByID(req.StatusID)
 	if err != nil {
@@ -303,16 +413,28 @@ func (h *ContactHandler) UpdateContactStatus(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	
+	// Get the status the contact had when the change took effect
+	from, since, err := h.ContactRepo.StatusAsOf(contact, effective)
+	if err != nil {
+		http.Error(w, "Failed to get status history: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
 	// Don't update if status hasn't changed
-	if contact.CurrentStatusID == req.StatusID && req.Notes == "" {
+	if from == req.StatusID && req.Notes == "" {
 		middleware.RespondJSON(w, http.StatusOK, map[string]string{
 			"message": "Status unchanged",
 		})
 		return
 	}
 	
+	// Check the move is allowed by the status transitions
+	if !h.checkTransition(w, from, since, req.StatusID, req.Notes, effective) {
+		return
+	}
+	
 	// Update status
-	if err := h.ContactRepo.UpdateStatus(id, req.StatusID, req.Notes); err != nil {
+	if err := h.ContactRepo.UpdateStatus(id, req.StatusID, req.Notes, effective); err != nil {
 		http.Error(w, "Failed to update status: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -338,10 +460,8 @@ func (h *ContactHandler) GetContactStatusHistory(w http.ResponseWriter, r *http.
 		return
 	}
 	
-	// Check if contact exists
-	_, err = h.ContactRepo.GetByID(id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+	// Check if contact exists and is visible to the caller
+	if _, _, ok := h.visibleContact(w, r, id); !ok {
 		return
 	}
 	
@@ -357,4 +477,84 @@ func (h *ContactHandler) GetContactStatusHistory(w http.ResponseWriter, r *http.
 		"contact_id": id,
 		"history":    history,
 	})
-}
\ No newline at end of file
+}
+
+// scope returns the contacts the caller may see. Admins see every contact;
+// everyone else sees their own and those of the users and groups they lead.
+func (h *ContactHandler) scope(r *http.Request) (models.ContactScope, error) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		return models.ContactScope{}, errors.New("no user in request")
+	}
+	if claims.Role == auth.RoleAdmin {
+		return models.ContactScope{All: true}, nil
+	}
+	
+	downline, err := h.Downline.Fetch(r.Header.Get("Authorization"))
+	if err != nil {
+		return models.ContactScope{}, err
+	}
+	return models.ContactScope{
+		UserIDs:  append([]int{claims.UserID}, downline.UserIDs...),
+		GroupIDs: downline.GroupIDs,
+	}, nil
+}
+
+// visibleContact fetches a contact the caller may see. Contacts outside the
+// caller's scope get the same 404 as contacts that do not exist.
+func (h *ContactHandler) visibleContact(w http.ResponseWriter, r *http.Request, id int) (*models.Contact, models.ContactScope, bool) {
+	scope, err := h.scope(r)
+	if err != nil {
+		http.Error(w, "Failed to determine contact scope: "+err.Error(), http.StatusInternalServerError)
+		return nil, scope, false
+	}
+	
+	contact, err := h.ContactRepo.GetByID(id)
+	if err == nil && !scope.Allows(contact) {
+		err = errors.New("contact not found")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, scope, false
+	}
+	return contact, scope, true
+}
+
+// contactFilter reads the search, filter and sort parameters shared by the
+// endpoints that act on a selection of contacts. On failure it responds.
+func (h *ContactHandler) contactFilter(w http.ResponseWriter, r *http.Request) (models.ContactFilter, bool) {
+	fields, err := h.FieldRepo.GetAll()
+	if err != nil {
+		http.Error(w, "Failed to fetch custom fields: "+err.Error(), http.StatusInternalServerError)
+		return models.ContactFilter{}, false
+	}
+	
+	filter, err := parseContactFilter(r, fields)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return filter, false
+	}
+	return filter, true
+}
+
+// loadDetails fills in the custom fields and tags of contacts
+func (h *ContactHandler) loadDetails(contacts ...*models.Contact) error {
+	if err := h.ContactRepo.LoadCustomFields(contacts...); err != nil {
+		return err
+	}
+	return h.ContactRepo.LoadTags(contacts...)
+}
+
+// checkAssignment reports whether a contact may be given to workerID and
+// groupID, either of which may be zero to skip its check
+func checkAssignment(w http.ResponseWriter, scope models.ContactScope, workerID, groupID int) bool {
+	if workerID != 0 && !scope.HasUser(workerID) {
+		http.Error(w, "Assigned worker is outside your scope", http.StatusBadRequest)
+		return false
+	}
+	if groupID != 0 && !scope.HasGroup(groupID) {
+		http.Error(w, "Group is outside your scope", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
+)
+
+// CustomFieldHandler handles requests for custom contact field definitions
+type CustomFieldHandler struct {
+	FieldRepo *models.CustomFieldRepository
+}
+
+// GetAllCustomFields returns every custom field in display order
+func (h *CustomFieldHandler) GetAllCustomFields(w http.ResponseWriter, r *http.Request) {
+	// Fetch fields from repository
+	fields, err := h.FieldRepo.GetAll()
+	if err != nil {
+		http.Error(w, "Failed to fetch custom fields: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"fields": fields,
+	})
+}
+
+// GetCustomField returns a single custom field by ID
+func (h *CustomFieldHandler) GetCustomField(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid custom field ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Fetch field from repository
+	field, err := h.FieldRepo.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, field)
+}
+
+// CustomFieldRequest represents a request to create or update a custom field
+type CustomFieldRequest struct {
+	Key          string   `json:"key"`  // omitted on update keeps the key, which cannot change
+	Type         string   `json:"type"` // omitted on update keeps the type, which cannot change
+	Label        string   `json:"label"`
+	Required     bool     `json:"required"`
+	Options      []string `json:"options,omitempty"`
+	MaxLength    int      `json:"max_length,omitempty"`
+	Pattern      string   `json:"pattern,omitempty"`
+	Min          *float64 `json:"min,omitempty"`
+	Max          *float64 `json:"max,omitempty"`
+	DisplayOrder int      `json:"display_order"`
+}
+
+// field builds the custom field the request describes
+func (req CustomFieldRequest) field() *models.CustomField {
+	return &models.CustomField{
+		Key:          req.Key,
+		Label:        req.Label,
+		Type:         req.Type,
+		Required:     req.Required,
+		Options:      req.Options,
+		MaxLength:    req.MaxLength,
+		Pattern:      req.Pattern,
+		Min:          req.Min,
+		Max:          req.Max,
+		DisplayOrder: req.DisplayOrder,
+	}
+}
+
+// CreateCustomField handles defining a new custom field
+func (h *CustomFieldHandler) CreateCustomField(w http.ResponseWriter, r *http.Request) {
+	// Parse request
+	var req CustomFieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Validate input
+	field := req.field()
+	if err := field.Validate(); err != nil {
+		http.Error(w, "Invalid custom field: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := h.FieldRepo.GetByKey(field.Key); err == nil {
+		http.Error(w, "A custom field with this key already exists", http.StatusConflict)
+		return
+	}
+	
+	// Save to database
+	if err := h.FieldRepo.Create(field); err != nil {
+		http.Error(w, "Failed to create custom field: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusCreated, field)
+}
+
+// UpdateCustomField handles changing a custom field's definition. Values
+// already stored are kept even if they no longer validate.
+func (h *CustomFieldHandler) UpdateCustomField(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid custom field ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Check if field exists
+	existing, err := h.FieldRepo.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	
+	// Parse request
+	var req CustomFieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// The key and type identify stored values, so they cannot change
+	if (req.Key != "" && req.Key != existing.Key) || (req.Type != "" && req.Type != existing.Type) {
+		http.Error(w, "A custom field's key and type cannot be changed", http.StatusBadRequest)
+		return
+	}
+	req.Key = existing.Key
+	req.Type = existing.Type
+	
+	// Validate input
+	field := req.field()
+	field.ID = id
+	if err := field.Validate(); err != nil {
+		http.Error(w, "Invalid custom field: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	// Save to database
+	if err := h.FieldRepo.Update(id, field); err != nil {
+		http.Error(w, "Failed to update custom field: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, field)
+}
+
+// DeleteCustomField handles deleting a custom field and every value of it
+func (h *CustomFieldHandler) DeleteCustomField(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid custom field ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Check if field exists
+	if _, err := h.FieldRepo.GetByID(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	
+	// Delete from database
+	if err := h.FieldRepo.Delete(id); err != nil {
+		http.Error(w, "Failed to delete custom field: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Custom field deleted successfully",
+	})
+}
+
+// customFieldValues validates the custom field values of a contact request
+// with validateCustomFields. On failure it responds with 400 and a message
+// for each bad field.
+func (h *ContactHandler) customFieldValues(w http.ResponseWriter, values map[string]interface{}, creating bool) (map[int]string, bool) {
+	fields, err := h.FieldRepo.GetAll()
+	if err != nil {
+		http.Error(w, "Failed to fetch custom fields: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	
+	normalized, problems := validateCustomFields(fields, values, creating)
+	if len(problems) > 0 {
+		middleware.RespondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":  "Invalid custom fields",
+			"fields": problems,
+		})
+		return nil, false
+	}
+	return normalized, true
+}
+
+// validateCustomFields checks custom field values given by key and returns
+// them normalized and keyed by field ID, along with a message for each bad
+// field. When creating, every required field must be given; when updating,
+// only the fields given are changed, and required ones cannot be cleared.
+func validateCustomFields(fields []*models.CustomField, values map[string]interface{}, creating bool) (map[int]string, map[string]string) {
+	problems := make(map[string]string)
+	known := make(map[string]bool, len(fields))
+	normalized := make(map[int]string)
+	for _, field := range fields {
+		known[field.Key] = true
+		value, given := values[field.Key]
+		if !given && !creating {
+			continue
+		}
+		
+		s, err := field.Normalize(value)
+		if err != nil {
+			problems[field.Key] = err.Error()
+			continue
+		}
+		if s == "" && field.Required {
+			problems[field.Key] = "is required"
+			continue
+		}
+		if given {
+			normalized[field.ID] = s
+		}
+	}
+	for key := range values {
+		if !known[key] {
+			problems[key] = "is not a custom field"
+		}
+	}
+	return normalized, problems
+}
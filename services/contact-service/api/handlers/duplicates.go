@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/pkg/pagination"
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
+)
+
+// FindDuplicates returns pairs of the contacts the caller may see that are
+// likely the same person, best match first. min_score (default 0.5) sets how
+// alike they must be, contact_id limits the pairs to one contact and limit
+// (default 50) caps how many are returned.
+func (h *ContactHandler) FindDuplicates(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	
+	// Get parameters
+	minScore := 0.5
+	if value := q.Get("min_score"); value != "" {
+		score, err := strconv.ParseFloat(value, 64)
+		if err != nil || score <= 0 || score > 1 {
+			http.Error(w, "Invalid min_score: use a number above 0 and at most 1", http.StatusBadRequest)
+			return
+		}
+		minScore = score
+	}
+	contactID, err := parseIDParam(q.Get("contact_id"), "contact_id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := 50
+	if value, err := strconv.Atoi(q.Get("limit")); err == nil && value > 0 {
+		limit = value
+	}
+	
+	// Work out which contacts the caller may see
+	scope, err := h.scope(r)
+	if err != nil {
+		http.Error(w, "Failed to determine contact scope: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Compare every visible contact
+	contacts, _, _, err := h.ContactRepo.GetAll(scope, models.ContactFilter{}, pagination.Request{})
+	if err != nil {
+		http.Error(w, "Failed to fetch contacts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	pairs := models.FindDuplicates(contacts, minScore)
+	
+	// Keep the pairs asked for
+	result := []models.DuplicatePair{}
+	for _, pair := range pairs {
+		if len(result) == limit {
+			break
+		}
+		if contactID == 0 || pair.Contact.ID == contactID || pair.Duplicate.ID == contactID {
+			result = append(result, pair)
+		}
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"duplicates": result,
+		"min_score":  minScore,
+	})
+}
+
+// MergeRequest represents a request to merge a duplicate into a contact
+type MergeRequest struct {
+	DuplicateID int `json:"duplicate_id"`
+}
+
+// MergeContact handles folding a duplicate into the contact in the URL. The
+// duplicate is deleted; the response lists references to it in other services
+// that must be re-pointed to the kept contact.
+func (h *ContactHandler) MergeContact(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Parse request
+	var req MergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Validate input
+	if req.DuplicateID <= 0 {
+		http.Error(w, "duplicate_id is required", http.StatusBadRequest)
+		return
+	}
+	if req.DuplicateID == id {
+		http.Error(w, "A contact cannot be merged into itself", http.StatusBadRequest)
+		return
+	}
+	
+	// Check both contacts exist and are visible to the caller
+	if _, _, ok := h.visibleContact(w, r, id); !ok {
+		return
+	}
+	if _, _, ok := h.visibleContact(w, r, req.DuplicateID); !ok {
+		return
+	}
+	
+	// Merge in database
+	result, err := h.ContactRepo.Merge(id, req.DuplicateID)
+	if err != nil {
+		http.Error(w, "Failed to merge contacts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Get the merged contact to return
+	merged, err := h.ContactRepo.GetByID(id)
+	if err == nil {
+		err = h.loadDetails(merged)
+	}
+	if err != nil {
+		http.Error(w, "Contacts merged but failed to retrieve: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result.Contact = merged
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, result)
+}
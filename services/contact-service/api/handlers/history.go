@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
+)
+
+// HistoryCorrectionRequest represents a request to correct a status history
+// entry. Omitted fields keep what the entry has.
+type HistoryCorrectionRequest struct {
+	StatusID      int     `json:"status_id,omitempty"`
+	Notes         *string `json:"notes,omitempty"`
+	EffectiveDate string  `json:"effective_date,omitempty"`
+	Reason        string  `json:"reason,omitempty"`
+}
+
+// HistoryVoidRequest represents a request to void a status history entry
+type HistoryVoidRequest struct {
+	Reason string `json:"reason"`
+}
+
+// CorrectStatusHistoryEntry handles editing a status history entry. The
+// entry as it was is kept as a revision, and the contact's status is
+// recomputed from its latest entry. Transition rules do not apply to
+// corrections.
+func (h *ContactHandler) CorrectStatusHistoryEntry(w http.ResponseWriter, r *http.Request) {
+	// Get IDs from URL
+	contactID, entryID, ok := historyEntryIDs(w, r)
+	if !ok {
+		return
+	}
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Check if contact exists and is visible to the caller
+	if _, _, ok := h.visibleContact(w, r, contactID); !ok {
+		return
+	}
+	
+	// Parse request
+	var req HistoryCorrectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Validate input
+	if req.StatusID == 0 && req.Notes == nil && req.EffectiveDate == "" {
+		http.Error(w, "Give a status_id, notes or effective_date to change", http.StatusBadRequest)
+		return
+	}
+	if req.StatusID != 0 {
+		if _, err := h.StatusRepo.GetByID(req.StatusID); err != nil {
+			http.Error(w, "Invalid status ID", http.StatusBadRequest)
+			return
+		}
+	}
+	effective, err := parsePastTime(req.EffectiveDate, "effective_date")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.EffectiveDate != "" && effective.IsZero() {
+		effective = time.Now() // today
+	}
+	
+	// Save to database
+	correction := models.HistoryCorrection{
+		StatusID:    req.StatusID,
+		Notes:       req.Notes,
+		DateChanged: effective,
+		Reason:      strings.TrimSpace(req.Reason),
+		RevisedBy:   claims.UserID,
+	}
+	if !historyError(w, h.ContactRepo.CorrectHistoryEntry(contactID, entryID, correction), "correct") {
+		return
+	}
+	
+	// Return response
+	h.respondHistoryEntry(w, contactID, entryID)
+}
+
+// VoidStatusHistoryEntry handles voiding a status history entry, which keeps
+// it in the history but no longer counts it. A reason is required.
+func (h *ContactHandler) VoidStatusHistoryEntry(w http.ResponseWriter, r *http.Request) {
+	// Get IDs from URL
+	contactID, entryID, ok := historyEntryIDs(w, r)
+	if !ok {
+		return
+	}
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Check if contact exists and is visible to the caller
+	if _, _, ok := h.visibleContact(w, r, contactID); !ok {
+		return
+	}
+	
+	// Parse request
+	var req HistoryVoidRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Validate input
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		http.Error(w, "A reason is required", http.StatusBadRequest)
+		return
+	}
+	
+	// Save to database
+	if !historyError(w, h.ContactRepo.VoidHistoryEntry(contactID, entryID, claims.UserID, reason), "void") {
+		return
+	}
+	
+	// Return response
+	h.respondHistoryEntry(w, contactID, entryID)
+}
+
+// GetStatusHistoryRevisions returns the earlier versions of a status history
+// entry, oldest first
+func (h *ContactHandler) GetStatusHistoryRevisions(w http.ResponseWriter, r *http.Request) {
+	// Get IDs from URL
+	contactID, entryID, ok := historyEntryIDs(w, r)
+	if !ok {
+		return
+	}
+	
+	// Check if contact exists and is visible to the caller
+	if _, _, ok := h.visibleContact(w, r, contactID); !ok {
+		return
+	}
+	
+	// Get the entry and its revisions
+	entry, err := h.ContactRepo.GetHistoryEntry(contactID, entryID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	revisions, err := h.ContactRepo.GetHistoryRevisions(entryID)
+	if err != nil {
+		http.Error(w, "Failed to get revisions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"entry":     entry,
+		"revisions": revisions,
+	})
+}
+
+// historyEntryIDs reads the contact and history entry IDs from the URL
+func historyEntryIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	contactID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	entryID, err := strconv.Atoi(vars["entryId"])
+	if err != nil {
+		http.Error(w, "Invalid status history entry ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return contactID, entryID, true
+}
+
+// historyError writes the response for an error correcting or voiding an
+// entry, reporting whether there was none
+func historyError(w http.ResponseWriter, err error, action string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, models.ErrHistoryVoided), errors.Is(err, models.ErrLastHistoryEntry):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, models.ErrHistoryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, "Failed to "+action+" status history entry: "+err.Error(), http.StatusInternalServerError)
+	}
+	return false
+}
+
+// respondHistoryEntry returns a corrected entry with the contact's status
+func (h *ContactHandler) respondHistoryEntry(w http.ResponseWriter, contactID, entryID int) {
+	entry, err := h.ContactRepo.GetHistoryEntry(contactID, entryID)
+	if err != nil {
+		http.Error(w, "Entry updated but failed to retrieve: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	contact, err := h.ContactRepo.GetByID(contactID)
+	if err != nil {
+		http.Error(w, "Entry updated but failed to retrieve contact: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"entry":             entry,
+		"current_status_id": contact.CurrentStatusID,
+	})
+}
+
+// parsePastTime parses when something happened, such as a status change
+// taking effect, from field, either a date or an RFC 3339 time. It returns
+// zero, meaning now, for an empty value or today's date; other dates are
+// taken as noon UTC, so most time zones see the same day. Future times are
+// refused.
+func parsePastTime(value, field string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	now := time.Now().UTC()
+	t, err := time.Parse("2006-01-02", value)
+	if err == nil {
+		if t.Format("2006-01-02") == now.Format("2006-01-02") {
+			return time.Time{}, nil
+		}
+		t = t.Add(12 * time.Hour)
+	} else if t, err = time.Parse(time.RFC3339, value); err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: use YYYY-MM-DD or an RFC 3339 time", field)
+	}
+	if t.After(now.Add(time.Minute)) {
+		return time.Time{}, fmt.Errorf("%s cannot be in the future", field)
+	}
+	return t, nil
+}
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/pkg/pagination"
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/contactio"
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
+)
+
+// maxImportSize caps the size of an uploaded import file
+const maxImportSize = 10 << 20
+
+// importDuplicateScore is the score from which an imported row is reported as
+// a likely duplicate
+const importDuplicateScore = 0.5
+
+// ImportRow reports what happened, or would happen, to one imported record
+type ImportRow struct {
+	Row        int               `json:"row"`
+	Name       string            `json:"name"`
+	Result     string            `json:"result"`               // "import", "error" or "duplicate"
+	ContactID  int               `json:"contact_id,omitempty"` // once imported
+	Errors     []string          `json:"errors,omitempty"`
+	Duplicates []ImportDuplicate `json:"duplicates,omitempty"`
+}
+
+// ImportDuplicate is an existing contact, or an earlier row of the same file,
+// that an imported record may duplicate
+type ImportDuplicate struct {
+	ContactID int      `json:"contact_id,omitempty"`
+	Row       int      `json:"row,omitempty"`
+	Name      string   `json:"name"`
+	Score     float64  `json:"score"`
+	Reasons   []string `json:"reasons"`
+}
+
+// ImportContacts handles importing contacts from an uploaded CSV or vCard
+// file. The multipart form holds the file, an optional format (csv or vcf,
+// otherwise taken from the file name), for CSV an optional JSON mapping of
+// column headers to fields, and the dry_run and import_duplicates flags.
+// Rows with errors are never imported; rows that look like duplicates are
+// skipped unless import_duplicates is set. A dry run reports every row
+// without saving anything.
+func (h *ContactHandler) ImportContacts(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Parse request
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		http.Error(w, "Invalid upload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	file, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "A file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	
+	format := strings.ToLower(r.FormValue("format"))
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(fileHeader.Filename)) {
+		case ".csv":
+			format = "csv"
+		case ".vcf", ".vcard":
+			format = "vcf"
+		}
+	}
+	dryRun, err := parseBoolParam(r.FormValue("dry_run"), "dry_run")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	importDuplicates, err := parseBoolParam(r.FormValue("import_duplicates"), "import_duplicates")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	// Get the custom fields and statuses records are checked against
+	fields, err := h.FieldRepo.GetAll()
+	if err != nil {
+		http.Error(w, "Failed to fetch custom fields: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	statuses, err := h.StatusRepo.GetAll()
+	if err != nil || len(statuses) == 0 {
+		http.Error(w, "Failed to get statuses", http.StatusInternalServerError)
+		return
+	}
+	
+	// Read the file
+	var records []contactio.Record
+	var mapping contactio.Mapping
+	switch format {
+	case "csv":
+		if spec := r.FormValue("mapping"); spec != "" {
+			if err := json.Unmarshal([]byte(spec), &mapping); err != nil {
+				http.Error(w, "Invalid mapping: it must be a JSON object of column headers to fields", http.StatusBadRequest)
+				return
+			}
+		}
+		keys := make([]string, len(fields))
+		for i, field := range fields {
+			keys[i] = field.Key
+		}
+		records, mapping, err = contactio.ReadCSV(file, mapping, keys)
+	case "vcf":
+		records, err = contactio.ReadVCards(file)
+	default:
+		http.Error(w, "Unknown format: use csv or vcf", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to read file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	// Compare against the contacts the caller can see
+	scope, err := h.scope(r)
+	if err != nil {
+		http.Error(w, "Failed to determine contact scope: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	existing, _, _, err := h.ContactRepo.GetAll(scope, models.ContactFilter{}, pagination.Request{})
+	if err != nil {
+		http.Error(w, "Failed to fetch contacts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	index := models.NewDuplicateIndex(existing)
+	fileRows := make(map[*models.Contact]int) // contacts from earlier rows of the file
+	
+	// Validate each record
+	rows := make([]ImportRow, len(records))
+	var imports []*models.ImportedContact
+	var importRows []*ImportRow
+	counts := map[string]int{"import": 0, "error": 0, "duplicate": 0}
+	for i, record := range records {
+		row := &rows[i]
+		row.Row = record.Row
+		row.Name = record.Name
+		row.Errors = record.Errors
+	
+		contact := &models.Contact{
+			Name:             record.Name,
+			Email:            record.Email,
+			Phone:            record.Phone,
+			Location:         record.Location,
+			Notes:            record.Notes,
+			OwnerID:          claims.UserID,
+			AssignedWorkerID: claims.UserID,
+		}
+		if contact.Name == "" && len(row.Errors) == 0 {
+			row.Errors = append(row.Errors, "name is required")
+		}
+	
+		contact.CurrentStatusID = statuses[0].ID
+		if record.Status != "" {
+			if status := findStatus(statuses, record.Status); status != nil {
+				contact.CurrentStatusID = status.ID
+			} else {
+				row.Errors = append(row.Errors, fmt.Sprintf("unknown status %q", record.Status))
+			}
+		}
+	
+		values := make(map[string]interface{}, len(record.Fields))
+		for key, value := range record.Fields {
+			values[key] = value
+		}
+		fieldValues, problems := validateCustomFields(fields, values, true)
+		for _, key := range sortedKeys(problems) {
+			row.Errors = append(row.Errors, key+" "+problems[key])
+		}
+	
+		var tagNames []string
+		for _, name := range record.Tags {
+			name = strings.Join(strings.Fields(name), " ")
+			if utf8.RuneCountInString(name) > 100 {
+				row.Errors = append(row.Errors, fmt.Sprintf("tag %q is longer than 100 characters", name))
+				continue
+			}
+			tagNames = append(tagNames, name)
+		}
+	
+		if contact.Name != "" {
+			for _, match := range index.Matches(contact, importDuplicateScore) {
+				duplicate := ImportDuplicate{
+					ContactID: match.Contact.ID,
+					Row:       fileRows[match.Contact],
+					Name:      match.Contact.Name,
+					Score:     match.Score,
+					Reasons:   match.Reasons,
+				}
+				row.Duplicates = append(row.Duplicates, duplicate)
+			}
+			index.Add(contact)
+			fileRows[contact] = record.Row
+		}
+	
+		switch {
+		case len(row.Errors) > 0:
+			row.Result = "error"
+		case len(row.Duplicates) > 0 && !importDuplicates:
+			row.Result = "duplicate"
+		default:
+			row.Result = "import"
+			imports = append(imports, &models.ImportedContact{
+				Contact:     contact,
+				FieldValues: fieldValues,
+				TagNames:    tagNames,
+			})
+			importRows = append(importRows, row)
+		}
+		counts[row.Result]++
+	}
+	
+	// Save the valid rows unless this is a dry run
+	imported := 0
+	if !dryRun && len(imports) > 0 {
+		if err := h.ContactRepo.Import(imports); err != nil {
+			http.Error(w, "Failed to import contacts: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for i, row := range importRows {
+			row.ContactID = imports[i].Contact.ID
+		}
+		imported = len(imports)
+	}
+	
+	// Return response
+	response := map[string]interface{}{
+		"dry_run":    dryRun,
+		"format":     format,
+		"total":      len(records),
+		"valid":      counts["import"],
+		"errors":     counts["error"],
+		"duplicates": counts["duplicate"],
+		"imported":   imported,
+		"rows":       rows,
+	}
+	if format == "csv" {
+		response["mapping"] = mapping
+	}
+	middleware.RespondJSON(w, http.StatusOK, response)
+}
+
+// ExportContacts returns the contacts the caller may see as a CSV (the
+// default) or vCard file. It accepts ListContacts' filters and sort.
+func (h *ContactHandler) ExportContacts(w http.ResponseWriter, r *http.Request) {
+	// Get format
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "vcf" {
+		http.Error(w, "Unknown format: use csv or vcf", http.StatusBadRequest)
+		return
+	}
+	
+	// Work out which contacts the caller may see
+	scope, err := h.scope(r)
+	if err != nil {
+		http.Error(w, "Failed to determine contact scope: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Get search, filter and sort parameters
+	filter, ok := h.contactFilter(w, r)
+	if !ok {
+		return
+	}
+	
+	// Fetch every matching contact
+	contacts, _, _, err := h.ContactRepo.GetAll(scope, filter, pagination.Request{})
+	if err == nil {
+		err = h.loadDetails(contacts...)
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch contacts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Write the file
+	if format == "vcf" {
+		w.Header().Set("Content-Type", "text/vcard; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="contacts.vcf"`)
+		err = contactio.WriteVCards(w, contacts)
+	} else {
+		statuses, statusErr := h.StatusRepo.GetAll()
+		if statusErr != nil {
+			http.Error(w, "Failed to fetch statuses: "+statusErr.Error(), http.StatusInternalServerError)
+			return
+		}
+		fields, fieldErr := h.FieldRepo.GetAll()
+		if fieldErr != nil {
+			http.Error(w, "Failed to fetch custom fields: "+fieldErr.Error(), http.StatusInternalServerError)
+			return
+		}
+		statusNames := make(map[int]string, len(statuses))
+		for _, status := range statuses {
+			statusNames[status.ID] = status.Name
+		}
+	
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="contacts.csv"`)
+		err = contactio.WriteCSV(w, contacts, statusNames, fields)
+	}
+	if err != nil {
+		// The response has started, so all that can be done is log it
+		log.Printf("Failed to write contact export: %v", err)
+	}
+}
+
+// findStatus finds a status by ID or, ignoring case, by name
+func findStatus(statuses []*models.Status, value string) *models.Status {
+	id, _ := strconv.Atoi(value)
+	for _, status := range statuses {
+		if status.ID == id || strings.EqualFold(status.Name, strings.TrimSpace(value)) {
+			return status
+		}
+	}
+	return nil
+}
+
+// parseBoolParam parses an optional boolean, false if empty
+func parseBoolParam(value, name string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: use true or false", name)
+	}
+	return b, nil
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/cardoza1991/church-management-system/pkg/auth"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
+)
+
+// InteractionRequest represents a request to log or change an interaction
+type InteractionRequest struct {
+	Type            string `json:"type"`
+	OccurredAt      string `json:"occurred_at,omitempty"` // YYYY-MM-DD or RFC 3339; defaults to now, or on update the current time
+	DurationMinutes int    `json:"duration_minutes,omitempty"`
+	Body            string `json:"body"`
+	Reason          string `json:"reason,omitempty"` // why an interaction is edited
+}
+
+// ListInteractions returns a contact's interactions that are not void, latest
+// first
+func (h *ContactHandler) ListInteractions(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	contactID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Check if contact exists and is visible to the caller
+	if _, _, ok := h.visibleContact(w, r, contactID); !ok {
+		return
+	}
+	
+	// Get interactions
+	interactions, err := h.InteractionRepo.GetAll(contactID)
+	if err != nil {
+		http.Error(w, "Failed to get interactions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"contact_id":   contactID,
+		"interactions": interactions,
+	})
+}
+
+// GetInteraction returns one of a contact's interactions
+func (h *ContactHandler) GetInteraction(w http.ResponseWriter, r *http.Request) {
+	// Get the interaction
+	interaction, ok := h.visibleInteraction(w, r)
+	if !ok {
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, interaction)
+}
+
+// CreateInteraction handles logging an interaction with a contact, written
+// by the caller
+func (h *ContactHandler) CreateInteraction(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	contactID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Check if contact exists and is visible to the caller
+	if _, _, ok := h.visibleContact(w, r, contactID); !ok {
+		return
+	}
+	
+	// Parse request
+	var req InteractionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Validate input
+	interaction := &models.Interaction{ContactID: contactID, AuthorID: claims.UserID}
+	if !parseInteraction(w, req, interaction) {
+		return
+	}
+	
+	// Save to database
+	if err := h.InteractionRepo.Create(interaction); err != nil {
+		http.Error(w, "Failed to log interaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	h.respondInteraction(w, contactID, interaction.ID, http.StatusCreated)
+}
+
+// UpdateInteraction handles editing an interaction. The interaction as it was
+// is kept as a revision. Only its author, or someone who can correct status
+// history, may edit it.
+func (h *ContactHandler) UpdateInteraction(w http.ResponseWriter, r *http.Request) {
+	// Get the interaction
+	existing, userID, ok := h.ownInteraction(w, r, "edit")
+	if !ok {
+		return
+	}
+	
+	// Parse request
+	var req InteractionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Validate input
+	interaction := &models.Interaction{}
+	if !parseInteraction(w, req, interaction) {
+		return
+	}
+	
+	// Save to database
+	edit := models.InteractionEdit{
+		Type:            interaction.Type,
+		OccurredAt:      interaction.OccurredAt,
+		DurationMinutes: interaction.DurationMinutes,
+		Body:            interaction.Body,
+		Reason:          strings.TrimSpace(req.Reason),
+		RevisedBy:       userID,
+	}
+	if !interactionError(w, h.InteractionRepo.Edit(existing.ContactID, existing.ID, edit), "edit") {
+		return
+	}
+	
+	// Return response
+	h.respondInteraction(w, existing.ContactID, existing.ID, http.StatusOK)
+}
+
+// InteractionVoidRequest represents a request to void an interaction
+type InteractionVoidRequest struct {
+	Reason string `json:"reason"`
+}
+
+// VoidInteraction handles voiding an interaction, which keeps it but leaves
+// it out of the log and timeline. A reason is required. Only its author, or
+// someone who can correct status history, may void it.
+func (h *ContactHandler) VoidInteraction(w http.ResponseWriter, r *http.Request) {
+	// Get the interaction
+	interaction, userID, ok := h.ownInteraction(w, r, "void")
+	if !ok {
+		return
+	}
+	
+	// Parse request
+	var req InteractionVoidRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Validate input
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		http.Error(w, "A reason is required", http.StatusBadRequest)
+		return
+	}
+	
+	// Save to database
+	if !interactionError(w, h.InteractionRepo.Void(interaction.ContactID, interaction.ID, userID, reason), "void") {
+		return
+	}
+	
+	// Return response
+	h.respondInteraction(w, interaction.ContactID, interaction.ID, http.StatusOK)
+}
+
+// GetInteractionRevisions returns the earlier versions of an interaction,
+// oldest first
+func (h *ContactHandler) GetInteractionRevisions(w http.ResponseWriter, r *http.Request) {
+	// Get the interaction
+	interaction, ok := h.visibleInteraction(w, r)
+	if !ok {
+		return
+	}
+	
+	// Get its revisions
+	revisions, err := h.InteractionRepo.GetRevisions(interaction.ID)
+	if err != nil {
+		http.Error(w, "Failed to get revisions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"interaction": interaction,
+		"revisions":   revisions,
+	})
+}
+
+// GetContactTimeline returns a contact's interactions and status changes
+// interleaved, latest first. Void interactions and status changes are left
+// out.
+func (h *ContactHandler) GetContactTimeline(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	contactID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Check if contact exists and is visible to the caller
+	if _, _, ok := h.visibleContact(w, r, contactID); !ok {
+		return
+	}
+	
+	// Get timeline
+	timeline, err := h.InteractionRepo.Timeline(contactID)
+	if err != nil {
+		http.Error(w, "Failed to get timeline: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"contact_id": contactID,
+		"timeline":   timeline,
+	})
+}
+
+// visibleInteraction gets the interaction in the URL if its contact is
+// visible to the caller. On failure it responds.
+func (h *ContactHandler) visibleInteraction(w http.ResponseWriter, r *http.Request) (*models.Interaction, bool) {
+	vars := mux.Vars(r)
+	contactID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return nil, false
+	}
+	id, err := strconv.Atoi(vars["interactionId"])
+	if err != nil {
+		http.Error(w, "Invalid interaction ID", http.StatusBadRequest)
+		return nil, false
+	}
+	if _, _, ok := h.visibleContact(w, r, contactID); !ok {
+		return nil, false
+	}
+	
+	interaction, err := h.InteractionRepo.GetByID(contactID, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return interaction, true
+}
+
+// ownInteraction gets the interaction in the URL if the caller may revise
+// it, along with the caller's user ID. On failure it responds.
+func (h *ContactHandler) ownInteraction(w http.ResponseWriter, r *http.Request, action string) (*models.Interaction, int, bool) {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, 0, false
+	}
+	interaction, ok := h.visibleInteraction(w, r)
+	if !ok {
+		return nil, 0, false
+	}
+	if interaction.AuthorID != claims.UserID && !auth.Can(claims.Role, auth.HistoryWrite) {
+		http.Error(w, "You can only "+action+" your own interactions", http.StatusForbidden)
+		return nil, 0, false
+	}
+	return interaction, claims.UserID, true
+}
+
+// interactionError writes the response for an error editing or voiding an
+// interaction, reporting whether there was none
+func interactionError(w http.ResponseWriter, err error, action string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, models.ErrInteractionVoided):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, models.ErrInteractionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, "Failed to "+action+" interaction: "+err.Error(), http.StatusInternalServerError)
+	}
+	return false
+}
+
+// respondInteraction returns an interaction as saved
+func (h *ContactHandler) respondInteraction(w http.ResponseWriter, contactID, id, status int) {
+	interaction, err := h.InteractionRepo.GetByID(contactID, id)
+	if err != nil {
+		http.Error(w, "Interaction saved but failed to retrieve: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	middleware.RespondJSON(w, status, interaction)
+}
+
+// parseInteraction validates req and copies it into interaction. On failure
+// it responds.
+func parseInteraction(w http.ResponseWriter, req InteractionRequest, interaction *models.Interaction) bool {
+	known := false
+	for _, t := range models.InteractionTypes {
+		known = known || req.Type == t
+	}
+	if !known {
+		http.Error(w, "Invalid type: use "+strings.Join(models.InteractionTypes, ", "), http.StatusBadRequest)
+		return false
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		http.Error(w, "Body is required", http.StatusBadRequest)
+		return false
+	}
+	if len(body) > 65535 {
+		http.Error(w, "Body cannot be longer than 65535 bytes", http.StatusBadRequest)
+		return false
+	}
+	if req.DurationMinutes < 0 || req.DurationMinutes > 24*60 {
+		http.Error(w, "duration_minutes must be between 0 and 1440", http.StatusBadRequest)
+		return false
+	}
+	occurredAt, err := parsePastTime(req.OccurredAt, "occurred_at")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if req.OccurredAt != "" && occurredAt.IsZero() {
+		occurredAt = time.Now() // today
+	}
+	
+	interaction.Type = req.Type
+	interaction.OccurredAt = occurredAt
+	interaction.DurationMinutes = req.DurationMinutes
+	interaction.Body = body
+	return true
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+)
+
+// GetPipelineReport returns how the contacts the caller may see move through
+// the statuses: counts, conversion rates, time in each status and monthly
+// cohorts. It accepts ListContacts' filters, such as assigned_worker_id,
+// group_id and date_added_from and date_added_to.
+func (h *ContactHandler) GetPipelineReport(w http.ResponseWriter, r *http.Request) {
+	// Work out which contacts the caller may see
+	scope, err := h.scope(r)
+	if err != nil {
+		http.Error(w, "Failed to determine contact scope: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Get filter parameters
+	filter, ok := h.contactFilter(w, r)
+	if !ok {
+		return
+	}
+	
+	// Build the report
+	report, err := h.ContactRepo.Pipeline(scope, filter)
+	if err != nil {
+		http.Error(w, "Failed to build pipeline report: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, report)
+}
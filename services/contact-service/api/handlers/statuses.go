@@ -6,7 +6,7 @@ import (
 	"strconv"
 
 	"github.com/gorilla/mux"
-	"github.com/cardoza1991/church-management-system/services/contact-service/api/middleware"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
 	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
 )
 
@@ -56,17 +56,12 @@ type StatusRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description,omitempty"`
 	DisplayOrder int   `json:"display_order"`
+	IsMilestone bool   `json:"is_milestone"`
+	StallDays   int    `json:"stall_days"`
 }
 
 // CreateStatus handles creating a new status
 func (h *StatusHandler) CreateStatus(w http.ResponseWriter, r *http.Request) {
-	// Only admins can create statuses
-	claims, ok := r.Context().Value("user").(*middleware.Claims)
-	if !ok || claims.Role != "admin" {
-		http.Error(w, "Admin access required", http.StatusForbidden)
-		return
-	}
-	
 	// Parse request
 	var req StatusRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -79,12 +74,18 @@ func (h *StatusHandler) CreateStatus(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Name is required", http.StatusBadRequest)
 		return
 	}
+	if req.StallDays < 0 {
+		http.Error(w, "stall_days cannot be negative", http.StatusBadRequest)
+		return
+	}
 	
 	// Create status
 	status := &models.Status{
 		Name:        req.Name,
 		Description: req.Description,
 		DisplayOrder: req.DisplayOrder,
+		IsMilestone: req.IsMilestone,
+		StallDays:   req.StallDays,
 	}
 	
 	// Save to database
@@ -99,13 +100,6 @@ func (h *StatusHandler) CreateStatus(w http.ResponseWriter, r *http.Request) {
 
 // UpdateStatus handles updating an existing status
 func (h *StatusHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
-	// Only admins can update statuses
-	claims, ok := r.Context().Value("user").(*middleware.Claims)
-	if !ok || claims.Role != "admin" {
-		http.Error(w, "Admin access required", http.StatusForbidden)
-		return
-	}
-	
 	// Get ID from URL
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -133,6 +127,10 @@ func (h *StatusHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Name is required", http.StatusBadRequest)
 		return
 	}
+	if req.StallDays < 0 {
+		http.Error(w, "stall_days cannot be negative", http.StatusBadRequest)
+		return
+	}
 	
 	// Update status
 	status := &models.Status{
@@ -140,6 +138,8 @@ func (h *StatusHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 		Name:        req.Name,
 		Description: req.Description,
 		DisplayOrder: req.DisplayOrder,
+		IsMilestone: req.IsMilestone,
+		StallDays:   req.StallDays,
 	}
 	
 	// Save to database
@@ -154,13 +154,6 @@ func (h *StatusHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 
 // DeleteStatus handles deleting a status
 func (h *StatusHandler) DeleteStatus(w http.ResponseWriter, r *http.Request) {
-	// Only admins can delete statuses
-	claims, ok := r.Context().Value("user").(*middleware.Claims)
-	if !ok || claims.Role != "admin" {
-		http.Error(w, "Admin access required", http.StatusForbidden)
-		return
-	}
-	
 	// Get ID from URL
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
+)
+
+// TagHandler handles tag management requests
+type TagHandler struct {
+	TagRepo *models.TagRepository
+}
+
+// GetAllTags returns every tag by name
+func (h *TagHandler) GetAllTags(w http.ResponseWriter, r *http.Request) {
+	// Fetch tags from repository
+	tags, err := h.TagRepo.GetAll()
+	if err != nil {
+		http.Error(w, "Failed to fetch tags: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"tags": tags,
+	})
+}
+
+// TagRequest represents a request to create or rename a tag
+type TagRequest struct {
+	Name string `json:"name"`
+}
+
+// CreateTag handles creating a new tag
+func (h *TagHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	// Parse request
+	name, ok := h.tagName(w, r, 0)
+	if !ok {
+		return
+	}
+	
+	// Save to database
+	tag := &models.Tag{Name: name}
+	if err := h.TagRepo.Create(tag); err != nil {
+		http.Error(w, "Failed to create tag: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusCreated, tag)
+}
+
+// RenameTag handles renaming a tag
+func (h *TagHandler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Check if tag exists
+	tag, err := h.TagRepo.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	
+	// Parse request
+	name, ok := h.tagName(w, r, id)
+	if !ok {
+		return
+	}
+	
+	// Save to database
+	if err := h.TagRepo.Rename(id, name); err != nil {
+		http.Error(w, "Failed to rename tag: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tag.Name = name
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, tag)
+}
+
+// MergeTagRequest represents a request to merge one tag into another
+type MergeTagRequest struct {
+	IntoTagID int `json:"into_tag_id"`
+}
+
+// MergeTag handles moving every contact from one tag to another and deleting
+// the first
+func (h *TagHandler) MergeTag(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Parse request
+	var req MergeTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Validate input
+	if req.IntoTagID == id {
+		http.Error(w, "A tag cannot be merged into itself", http.StatusBadRequest)
+		return
+	}
+	
+	// Check if tag exists
+	if _, err := h.TagRepo.GetByID(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	target, err := h.TagRepo.GetByID(req.IntoTagID)
+	if err != nil {
+		http.Error(w, "Invalid into_tag_id", http.StatusBadRequest)
+		return
+	}
+	
+	// Merge in database
+	if err := h.TagRepo.Merge(id, target.ID); err != nil {
+		http.Error(w, "Failed to merge tags: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, target)
+}
+
+// DeleteTag handles removing a tag from every contact and deleting it
+func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Check if tag exists
+	if _, err := h.TagRepo.GetByID(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	
+	// Delete from database
+	if err := h.TagRepo.Delete(id); err != nil {
+		http.Error(w, "Failed to delete tag: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Tag deleted successfully",
+	})
+}
+
+// tagName reads and validates the name in a TagRequest. Names are unique
+// regardless of case; id is the tag being renamed, if any. On failure it
+// responds.
+func (h *TagHandler) tagName(w http.ResponseWriter, r *http.Request, id int) (string, bool) {
+	var req TagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return "", false
+	}
+	
+	name, err := normalizeTagName(req.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	if existing, err := h.TagRepo.GetByName(name); err == nil && existing.ID != id {
+		http.Error(w, "A tag with this name already exists", http.StatusConflict)
+		return "", false
+	}
+	return name, true
+}
+
+// normalizeTagName collapses runs of whitespace in a tag name to single
+// spaces and trims it, then checks its length
+func normalizeTagName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", errors.New("Name is required")
+	}
+	if utf8.RuneCountInString(name) > 100 {
+		return "", errors.New("Name must be at most 100 characters")
+	}
+	return name, nil
+}
+
+// GetContactSummary returns how many of the contacts the caller may see are
+// in each status and have each tag. It accepts ListContacts' filters.
+func (h *ContactHandler) GetContactSummary(w http.ResponseWriter, r *http.Request) {
+	// Work out which contacts the caller may see
+	scope, err := h.scope(r)
+	if err != nil {
+		http.Error(w, "Failed to determine contact scope: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Get filter parameters
+	filter, ok := h.contactFilter(w, r)
+	if !ok {
+		return
+	}
+	
+	// Count contacts
+	summary, err := h.ContactRepo.Summary(scope, filter)
+	if err != nil {
+		http.Error(w, "Failed to summarize contacts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, summary)
+}
+
+// TagContact handles adding a tag to a contact
+func (h *ContactHandler) TagContact(w http.ResponseWriter, r *http.Request) {
+	contactID, tagID, ok := h.contactTag(w, r)
+	if !ok {
+		return
+	}
+	
+	// Save to database
+	if err := h.ContactRepo.TagContact(contactID, tagID); err != nil {
+		http.Error(w, "Failed to tag contact: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Contact tagged successfully",
+	})
+}
+
+// UntagContact handles removing a tag from a contact
+func (h *ContactHandler) UntagContact(w http.ResponseWriter, r *http.Request) {
+	contactID, tagID, ok := h.contactTag(w, r)
+	if !ok {
+		return
+	}
+	
+	// Delete from database
+	if err := h.ContactRepo.UntagContact(contactID, tagID); err != nil {
+		http.Error(w, "Failed to untag contact: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Contact untagged successfully",
+	})
+}
+
+// contactTag reads the contact and tag IDs from the URL and checks that the
+// contact is visible to the caller and the tag exists. On failure it responds.
+func (h *ContactHandler) contactTag(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	// Get IDs from URL
+	vars := mux.Vars(r)
+	contactID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	tagID, err := strconv.Atoi(vars["tagId"])
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	
+	// Check if contact exists and is visible to the caller
+	if _, _, ok := h.visibleContact(w, r, contactID); !ok {
+		return 0, 0, false
+	}
+	
+	// Check if tag exists
+	if _, err := h.TagRepo.GetByID(tagID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return 0, 0, false
+	}
+	return contactID, tagID, true
+}
+
+// BulkTagContacts handles tagging every contact the caller may see that
+// matches ListContacts' filters, given as query parameters
+func (h *ContactHandler) BulkTagContacts(w http.ResponseWriter, r *http.Request) {
+	h.bulkTag(w, r, false)
+}
+
+// BulkUntagContacts handles untagging every contact the caller may see that
+// matches ListContacts' filters, given as query parameters
+func (h *ContactHandler) BulkUntagContacts(w http.ResponseWriter, r *http.Request) {
+	h.bulkTag(w, r, true)
+}
+
+func (h *ContactHandler) bulkTag(w http.ResponseWriter, r *http.Request, remove bool) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	tagID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Check if tag exists
+	if _, err := h.TagRepo.GetByID(tagID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	
+	// Work out which contacts are selected
+	scope, err := h.scope(r)
+	if err != nil {
+		http.Error(w, "Failed to determine contact scope: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	filter, ok := h.contactFilter(w, r)
+	if !ok {
+		return
+	}
+	
+	// Tag or untag them
+	var count int64
+	if remove {
+		count, err = h.ContactRepo.BulkUntag(scope, filter, tagID)
+	} else {
+		count, err = h.ContactRepo.BulkTag(scope, filter, tagID)
+	}
+	if err != nil {
+		http.Error(w, "Failed to tag contacts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	key := "tagged"
+	if remove {
+		key = "untagged"
+	}
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"tag_id": tagID,
+		key:      count,
+	})
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestNormalizeTagName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"college", "college", false},
+		{"  met at\toutreach \n", "met at outreach", false},
+		{"Bible   study", "Bible study", false},
+		{"", "", true},
+		{" \t\n", "", true},
+		{strings.Repeat("é", 100), strings.Repeat("é", 100), false}, // counted in characters, not bytes
+		{strings.Repeat("a", 101), "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeTagName(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("normalizeTagName(%q) error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeTagName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMergeTagIntoItself(t *testing.T) {
+	// The handler has no repository, so it must refuse before looking the tags up
+	h := &TagHandler{}
+	r := httptest.NewRequest(http.MethodPost, "/tags/3/merge", strings.NewReader(`{"into_tag_id": 3}`))
+	r = mux.SetURLVars(r, map[string]string{"id": "3"})
+	w := httptest.NewRecorder()
+
+	h.MergeTag(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if !strings.Contains(w.Body.String(), "cannot be merged into itself") {
+		t.Errorf("body = %q", w.Body.String())
+	}
+}
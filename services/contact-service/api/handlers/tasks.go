@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
+)
+
+// ListTasks returns the tasks on contacts the caller may see. assignee_id,
+// contact_id, type and due_before filter them; status is open (the
+// default), completed or all; limit (default 100, at most 500) caps how many
+// are returned.
+func (h *ContactHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
+	// Work out which contacts the caller may see
+	scope, err := h.scope(r)
+	if err != nil {
+		http.Error(w, "Failed to determine contact scope: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Get filter parameters
+	filter, ok := taskFilter(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	if filter.AssigneeID, err = parseIDParam(q.Get("assignee_id"), "assignee_id"); err == nil {
+		filter.ContactID, err = parseIDParam(q.Get("contact_id"), "contact_id")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	h.respondTasks(w, scope, filter)
+}
+
+// ListMyTasks returns the caller's task inbox: tasks assigned to them, including
+// those the sweeper creates for stalled contacts. It accepts ListTasks'
+// status, type, due_before and limit.
+func (h *ContactHandler) ListMyTasks(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Get filter parameters
+	filter, ok := taskFilter(w, r)
+	if !ok {
+		return
+	}
+	filter.AssigneeID = claims.UserID
+	
+	// Tasks assigned to the caller are theirs to see whoever's contact it is
+	h.respondTasks(w, models.ContactScope{All: true}, filter)
+}
+
+// respondTasks fetches and returns tasks
+func (h *ContactHandler) respondTasks(w http.ResponseWriter, scope models.ContactScope, filter models.TaskFilter) {
+	tasks, err := h.TaskRepo.GetAll(scope, filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch tasks: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"tasks": tasks,
+	})
+}
+
+// GetTask returns a single task by ID
+func (h *ContactHandler) GetTask(w http.ResponseWriter, r *http.Request) {
+	// Get the task
+	task, _, ok := h.visibleTask(w, r)
+	if !ok {
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, task)
+}
+
+// TaskRequest represents a request to create or update a task
+type TaskRequest struct {
+	ContactID  int    `json:"contact_id"`  // ignored on update
+	AssigneeID int    `json:"assignee_id"` // defaults to the caller, or on update the current assignee
+	Type       string `json:"type"`        // defaults to follow_up, or on update the current type
+	Title      string `json:"title"`
+	Notes      string `json:"notes,omitempty"`
+	DueDate    string `json:"due_date,omitempty"` // YYYY-MM-DD
+}
+
+// CreateTask handles adding a task for a contact
+func (h *ContactHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Parse request
+	var req TaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Check the contact exists and is visible to the caller
+	if req.ContactID <= 0 {
+		http.Error(w, "contact_id is required", http.StatusBadRequest)
+		return
+	}
+	_, scope, ok := h.visibleContact(w, r, req.ContactID)
+	if !ok {
+		return
+	}
+	
+	// Validate input, assigning the task to the caller unless told otherwise
+	if req.Type == "" {
+		req.Type = models.TaskFollowUp
+	}
+	if req.AssigneeID <= 0 {
+		req.AssigneeID = claims.UserID
+	} else if req.AssigneeID != claims.UserID && !checkAssignment(w, scope, req.AssigneeID, 0) {
+		return
+	}
+	task := &models.Task{
+		ContactID:  req.ContactID,
+		AssigneeID: req.AssigneeID,
+		Type:       req.Type,
+		Title:      strings.TrimSpace(req.Title),
+		Notes:      req.Notes,
+		DueDate:    req.DueDate,
+		CreatedBy:  claims.UserID,
+	}
+	if !validateTask(w, task, "") {
+		return
+	}
+	
+	// Save to database
+	if err := h.TaskRepo.Create(task); err != nil {
+		http.Error(w, "Failed to create task: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	h.respondTask(w, task.ID, http.StatusCreated)
+}
+
+// UpdateTask handles changing a task. Its contact cannot change.
+func (h *ContactHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	// Get the task
+	existing, scope, ok := h.visibleTask(w, r)
+	if !ok {
+		return
+	}
+	
+	// Parse request
+	var req TaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Validate input, keeping the assignee and type unless new ones are given
+	if req.Type == "" {
+		req.Type = existing.Type
+	}
+	if req.AssigneeID <= 0 {
+		req.AssigneeID = existing.AssigneeID
+	} else if req.AssigneeID != existing.AssigneeID && !checkAssignment(w, scope, req.AssigneeID, 0) {
+		return
+	}
+	task := &models.Task{
+		ID:         existing.ID,
+		ContactID:  existing.ContactID,
+		AssigneeID: req.AssigneeID,
+		Type:       req.Type,
+		Title:      strings.TrimSpace(req.Title),
+		Notes:      req.Notes,
+		DueDate:    req.DueDate,
+	}
+	if !validateTask(w, task, existing.Type) {
+		return
+	}
+	
+	// Save to database
+	if err := h.TaskRepo.Update(existing.ID, task); err != nil {
+		http.Error(w, "Failed to update task: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	h.respondTask(w, existing.ID, http.StatusOK)
+}
+
+// CompleteTask handles marking a task as done
+func (h *ContactHandler) CompleteTask(w http.ResponseWriter, r *http.Request) {
+	h.completeTask(w, r, true)
+}
+
+// ReopenTask handles marking a done task as not done
+func (h *ContactHandler) ReopenTask(w http.ResponseWriter, r *http.Request) {
+	h.completeTask(w, r, false)
+}
+
+func (h *ContactHandler) completeTask(w http.ResponseWriter, r *http.Request, done bool) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Get the task
+	task, _, ok := h.visibleTask(w, r)
+	if !ok {
+		return
+	}
+	
+	// Save to database
+	if err := h.TaskRepo.Complete(task.ID, claims.UserID, done); err != nil {
+		http.Error(w, "Failed to update task: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	h.respondTask(w, task.ID, http.StatusOK)
+}
+
+// DeleteTask handles deleting a task
+func (h *ContactHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
+	// Get the task
+	task, _, ok := h.visibleTask(w, r)
+	if !ok {
+		return
+	}
+	
+	// Delete from database
+	if err := h.TaskRepo.Delete(task.ID); err != nil {
+		http.Error(w, "Failed to delete task: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Task deleted successfully",
+	})
+}
+
+// visibleTask gets the task in the URL if it is assigned to the caller or on
+// a contact they may see. On failure it responds.
+func (h *ContactHandler) visibleTask(w http.ResponseWriter, r *http.Request) (*models.Task, models.ContactScope, bool) {
+	var scope models.ContactScope
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return nil, scope, false
+	}
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, scope, false
+	}
+	scope, err = h.scope(r)
+	if err != nil {
+		http.Error(w, "Failed to determine contact scope: "+err.Error(), http.StatusInternalServerError)
+		return nil, scope, false
+	}
+	
+	task, err := h.TaskRepo.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, scope, false
+	}
+	if task.AssigneeID == claims.UserID {
+		return task, scope, true
+	}
+	contact, err := h.ContactRepo.GetByID(task.ContactID)
+	if err != nil || !scope.Allows(contact) {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return nil, scope, false
+	}
+	return task, scope, true
+}
+
+// respondTask returns a task as saved
+func (h *ContactHandler) respondTask(w http.ResponseWriter, id, status int) {
+	task, err := h.TaskRepo.GetByID(id)
+	if err != nil {
+		http.Error(w, "Task saved but failed to retrieve: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	middleware.RespondJSON(w, status, task)
+}
+
+// taskFilter reads the status, type, due_before and limit query parameters.
+// On failure it responds.
+func taskFilter(w http.ResponseWriter, r *http.Request) (models.TaskFilter, bool) {
+	filter, err := parseTaskFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return filter, false
+	}
+	return filter, true
+}
+
+// parseTaskFilter reads the status, type, due_before and limit query
+// parameters. status defaults to open and limit to 100, at most 500.
+func parseTaskFilter(q url.Values) (models.TaskFilter, error) {
+	filter := models.TaskFilter{Type: q.Get("type"), Limit: 100}
+	
+	switch q.Get("status") {
+	case "", "open":
+		done := false
+		filter.Completed = &done
+	case "completed":
+		done := true
+		filter.Completed = &done
+	case "all":
+	default:
+		return filter, errors.New("Invalid status: use open, completed or all")
+	}
+	
+	if value := q.Get("due_before"); value != "" {
+		due, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return filter, errors.New("Invalid due_before: use YYYY-MM-DD")
+		}
+		filter.DueBefore = due
+	}
+	
+	if value, err := strconv.Atoi(q.Get("limit")); err == nil && value > 0 {
+		filter.Limit = min(value, 500)
+	}
+	return filter, nil
+}
+
+// validateTask checks a task with checkTask. On failure it responds.
+func validateTask(w http.ResponseWriter, task *models.Task, currentType string) bool {
+	if err := checkTask(task, currentType); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// checkTask checks a task's type, title and due date. A task may keep its
+// current type even if it cannot be requested, as with sweeper tasks.
+func checkTask(task *models.Task, currentType string) error {
+	known := task.Type == currentType
+	for _, t := range models.TaskTypes {
+		known = known || task.Type == t
+	}
+	if !known {
+		return errors.New("Invalid type: use " + strings.Join(models.TaskTypes, ", "))
+	}
+	if task.Title == "" {
+		return errors.New("Title is required")
+	}
+	if utf8.RuneCountInString(task.Title) > 255 {
+		return errors.New("Title cannot be longer than 255 characters")
+	}
+	if task.DueDate != "" {
+		if _, err := time.Parse("2006-01-02", task.DueDate); err != nil {
+			return errors.New("Invalid due_date: use YYYY-MM-DD")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
+)
+
+func TestParseTaskFilter(t *testing.T) {
+	open, completed := false, true
+	tests := []struct {
+		query   string
+		want    models.TaskFilter
+		wantErr bool
+	}{
+		{"", models.TaskFilter{Completed: &open, Limit: 100}, false},
+		{"status=open&type=call", models.TaskFilter{Type: "call", Completed: &open, Limit: 100}, false},
+		{"status=completed&limit=20", models.TaskFilter{Completed: &completed, Limit: 20}, false},
+		{"status=all&limit=1000", models.TaskFilter{Limit: 500}, false},
+		{"limit=-5", models.TaskFilter{Completed: &open, Limit: 100}, false},
+		{"limit=many", models.TaskFilter{Completed: &open, Limit: 100}, false},
+		{"due_before=2024-04-01", models.TaskFilter{Completed: &open, Limit: 100,
+			DueBefore: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)}, false},
+		{"status=done", models.TaskFilter{}, true},
+		{"due_before=04/01/2024", models.TaskFilter{}, true},
+	}
+
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		got, err := parseTaskFilter(q)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTaskFilter(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if got.Type != tt.want.Type || got.Limit != tt.want.Limit || !got.DueBefore.Equal(tt.want.DueBefore) {
+			t.Errorf("parseTaskFilter(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+		if (got.Completed == nil) != (tt.want.Completed == nil) ||
+			(got.Completed != nil && *got.Completed != *tt.want.Completed) {
+			t.Errorf("parseTaskFilter(%q) Completed = %v, want %v", tt.query, got.Completed, tt.want.Completed)
+		}
+	}
+}
+
+func TestCheckTask(t *testing.T) {
+	tests := []struct {
+		name        string
+		task        models.Task
+		currentType string
+		wantErr     string
+	}{
+		{"valid", models.Task{Type: models.TaskCall, Title: "Call about Sunday", DueDate: "2024-04-01"}, "", ""},
+		{"no due date", models.Task{Type: models.TaskVisit, Title: "Visit"}, "", ""},
+		{"keeps the sweeper's type", models.Task{Type: models.TaskStalled, Title: "Check in"}, models.TaskStalled, ""},
+		{"requests the sweeper's type", models.Task{Type: models.TaskStalled, Title: "Check in"}, "", "Invalid type"},
+		{"unknown type", models.Task{Type: "email", Title: "Write"}, "", "Invalid type"},
+		{"no title", models.Task{Type: models.TaskCall}, "", "Title is required"},
+		{"long title", models.Task{Type: models.TaskCall, Title: strings.Repeat("a", 256)}, "", "Title cannot be longer"},
+		{"bad due date", models.Task{Type: models.TaskCall, Title: "Call", DueDate: "next week"}, "", "Invalid due_date"},
+	}
+
+	for _, tt := range tests {
+		err := checkTask(&tt.task, tt.currentType)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: checkTask = %v, want no error", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)):
+			t.Errorf("%s: checkTask = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
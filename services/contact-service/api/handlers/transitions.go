@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
+)
+
+// TransitionHandler handles requests for the allowed moves between statuses
+type TransitionHandler struct {
+	TransitionRepo *models.TransitionRepository
+	StatusRepo     *models.StatusRepository
+}
+
+// GetAllTransitions returns every status transition, or with from_status_id
+// only those out of that status
+func (h *TransitionHandler) GetAllTransitions(w http.ResponseWriter, r *http.Request) {
+	// Get parameters
+	fromStatusID, err := parseIDParam(r.URL.Query().Get("from_status_id"), "from_status_id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	// Fetch transitions from repository
+	transitions, err := h.TransitionRepo.GetAll(fromStatusID)
+	if err != nil {
+		http.Error(w, "Failed to fetch status transitions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if transitions == nil {
+		transitions = []*models.StatusTransition{}
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"transitions": transitions,
+	})
+}
+
+// TransitionRequest represents a request to create or update a status transition
+type TransitionRequest struct {
+	FromStatusID int  `json:"from_status_id"` // omitted on update keeps the status, which cannot change
+	ToStatusID   int  `json:"to_status_id"`   // omitted on update keeps the status, which cannot change
+	RequiresNote bool `json:"requires_note"`
+	MinDays      int  `json:"min_days"`
+}
+
+// CreateTransition handles allowing contacts to move between two statuses
+func (h *TransitionHandler) CreateTransition(w http.ResponseWriter, r *http.Request) {
+	// Parse request
+	var req TransitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Validate input
+	if req.FromStatusID <= 0 || req.ToStatusID <= 0 {
+		http.Error(w, "from_status_id and to_status_id are required", http.StatusBadRequest)
+		return
+	}
+	if req.FromStatusID == req.ToStatusID {
+		http.Error(w, "A transition must be between two different statuses", http.StatusBadRequest)
+		return
+	}
+	if req.MinDays < 0 {
+		http.Error(w, "min_days cannot be negative", http.StatusBadRequest)
+		return
+	}
+	for _, id := range []int{req.FromStatusID, req.ToStatusID} {
+		if _, err := h.StatusRepo.GetByID(id); err != nil {
+			http.Error(w, "Invalid status ID", http.StatusBadRequest)
+			return
+		}
+	}
+	if _, err := h.TransitionRepo.Get(req.FromStatusID, req.ToStatusID); err == nil {
+		http.Error(w, "A transition between these statuses already exists", http.StatusConflict)
+		return
+	}
+	
+	// Save to database
+	transition := &models.StatusTransition{
+		FromStatusID: req.FromStatusID,
+		ToStatusID:   req.ToStatusID,
+		RequiresNote: req.RequiresNote,
+		MinDays:      req.MinDays,
+	}
+	if err := h.TransitionRepo.Create(transition); err != nil {
+		http.Error(w, "Failed to create status transition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusCreated, transition)
+}
+
+// UpdateTransition handles changing what a status transition requires
+func (h *TransitionHandler) UpdateTransition(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid status transition ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Check if transition exists
+	transition, err := h.TransitionRepo.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	
+	// Parse request
+	var req TransitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Validate input
+	if (req.FromStatusID != 0 && req.FromStatusID != transition.FromStatusID) ||
+		(req.ToStatusID != 0 && req.ToStatusID != transition.ToStatusID) {
+		http.Error(w, "A transition's statuses cannot be changed; delete it and create another", http.StatusBadRequest)
+		return
+	}
+	if req.MinDays < 0 {
+		http.Error(w, "min_days cannot be negative", http.StatusBadRequest)
+		return
+	}
+	
+	// Save to database
+	transition.RequiresNote = req.RequiresNote
+	transition.MinDays = req.MinDays
+	if err := h.TransitionRepo.Update(id, transition); err != nil {
+		http.Error(w, "Failed to update status transition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, transition)
+}
+
+// DeleteTransition handles removing a status transition. If it was the last
+// one out of its status, contacts may again move from that status to any other.
+func (h *TransitionHandler) DeleteTransition(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid status transition ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Check if transition exists
+	if _, err := h.TransitionRepo.GetByID(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	
+	// Delete from database
+	if err := h.TransitionRepo.Delete(id); err != nil {
+		http.Error(w, "Failed to delete status transition: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Status transition deleted successfully",
+	})
+}
+
+// GetContactMilestones returns every milestone status with when the contact
+// first reached it
+func (h *ContactHandler) GetContactMilestones(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid contact ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Check if contact exists and is visible to the caller
+	if _, _, ok := h.visibleContact(w, r, id); !ok {
+		return
+	}
+	
+	// Get milestones
+	milestones, err := h.ContactRepo.GetMilestones(id)
+	if err != nil {
+		http.Error(w, "Failed to get milestones: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"contact_id": id,
+		"milestones": milestones,
+	})
+}
+
+// checkTransition checks that a contact may move from a status it entered
+// at since to another at a time, writing a 409 explaining why not if it may not
+func (h *ContactHandler) checkTransition(w http.ResponseWriter, from int, since time.Time, to int, note string, at time.Time) bool {
+	if from == 0 || from == to {
+		return true
+	}
+	rules, err := h.TransitionRepo.GetAll(from)
+	if err != nil {
+		http.Error(w, "Failed to fetch status transitions: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	
+	failure := models.CheckTransition(rules, from, to, strings.TrimSpace(note), since, at)
+	if failure == nil {
+		return true
+	}
+	
+	// Explain the refusal using status names
+	names := make(map[int]string)
+	if statuses, err := h.StatusRepo.GetAll(); err == nil {
+		for _, status := range statuses {
+			names[status.ID] = status.Name
+		}
+	}
+	name := func(id int) string {
+		if n, ok := names[id]; ok {
+			return fmt.Sprintf("%q", n)
+		}
+		return fmt.Sprintf("status %d", id)
+	}
+	move := fmt.Sprintf("Moving from %s to %s", name(failure.FromStatusID), name(failure.ToStatusID))
+	switch failure.Reason {
+	case models.TransitionNotAllowed:
+		allowed := make([]string, len(failure.AllowedStatusIDs))
+		for i, id := range failure.AllowedStatusIDs {
+			allowed[i] = name(id)
+		}
+		failure.Message = fmt.Sprintf("%s is not allowed; allowed next statuses are %s", move, strings.Join(allowed, ", "))
+	case models.TransitionNoteRequired:
+		failure.Message = move + " requires a note"
+	case models.TransitionTooSoon:
+		failure.Message = fmt.Sprintf("%s requires at least %d days in %s; allowed from %s",
+			move, failure.MinDays, name(failure.FromStatusID), failure.AllowedFrom.UTC().Format(time.RFC3339))
+	}
+	middleware.RespondJSON(w, http.StatusConflict, failure)
+	return false
+}
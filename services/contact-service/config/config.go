@@ -1,44 +1,29 @@
 package config
 
 import (
-	"fmt"
-	"os"
+	"time"
+
+	sharedauth "github.com/cardoza1991/church-management-system/pkg/auth"
+	sharedconfig "github.com/cardoza1991/church-management-system/pkg/config"
 )
 
 // Config holds all configuration for the service
 type Config struct {
-	DBHost      string
-	DBPort      string
-	DBUser      string
-	DBPassword  string
-	DBName      string
-	ServerPort  string
-	AuthService string // URL for the auth service for JWT verification
+	sharedconfig.DB
+	ServerPort    string
+	AuthService   string               // URL for the auth service for JWT verification
+	Keys          sharedauth.KeyConfig // keys used to verify tokens; falls back to the auth service JWKS
+	SweepInterval time.Duration        // how often to look for stalled contacts; 0 disables the sweeper
 }
 
 // Load returns a new Config struct populated with values from environment variables
 func Load() *Config {
+	authService := sharedconfig.GetEnv("AUTH_SERVICE_URL", "http://localhost:8080")
 	return &Config{
-		DBHost:      getEnv("DB_HOST", "localhost"),
-		DBPort:      getEnv("DB_PORT", "3306"),
-		DBUser:      getEnv("DB_USER", "root"),
-		DBPassword:  getEnv("DB_PASSWORD", ""),
-		DBName:      getEnv("DB_NAME", "church_mgmt"),
-		ServerPort:  getEnv("PORT", "8081"), // Different from user-service port
-		AuthService: getEnv("AUTH_SERVICE_URL", "http://localhost:8080"),
+		DB:            sharedconfig.LoadDB(),
+		ServerPort:    sharedconfig.GetEnv("PORT", "8081"), // Different from user-service port
+		AuthService:   authService,
+		Keys:          sharedconfig.LoadKeys(authService + "/.well-known/jwks.json"),
+		SweepInterval: sharedconfig.GetEnvDuration("STALLED_SWEEP_INTERVAL", time.Hour),
 	}
 }
-
-// DSN returns a formatted database connection string
-func (c *Config) DSN() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", 
-		c.DBUser, c.DBPassword, c.DBHost, c.DBPort, c.DBName)
-}
-
-// Helper function to get environment variable with a default value
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
-	}
-	return defaultValue
-}
\ No newline at end of file
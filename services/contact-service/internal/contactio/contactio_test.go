@@ -0,0 +1,192 @@
+package contactio
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
+)
+
+func TestReadCSVDefaultMapping(t *testing.T) {
+	input := "\ufeffName,E-mail,Phone,Status,Tags,Language,Ignored\n" +
+		"John Smith,john@example.com,555-1234,Baptized,college; spanish,Spanish,x\n" +
+		",,,,,,\n" +
+		"Mary Jones,,,,,,\n"
+
+	records, mapping, err := ReadCSV(strings.NewReader(input), nil, []string{"language"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Mapping{"Name": "name", "Phone": "phone", "Status": "status", "Tags": "tags", "Language": "field.language"}
+	if !reflect.DeepEqual(mapping, want) {
+		t.Errorf("mapping = %v, want %v", mapping, want)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2 (blank rows are skipped)", len(records))
+	}
+
+	john := records[0]
+	if john.Row != 2 || john.Name != "John Smith" || john.Phone != "555-1234" || john.Status != "Baptized" {
+		t.Errorf("record = %+v", john)
+	}
+	if john.Email != "" {
+		t.Errorf("unmapped E-mail column was read: %q", john.Email)
+	}
+	if !reflect.DeepEqual(john.Tags, []string{"college", "spanish"}) || john.Fields["language"] != "Spanish" {
+		t.Errorf("tags = %v, fields = %v", john.Tags, john.Fields)
+	}
+	if records[1].Row != 4 {
+		t.Errorf("second record row = %d, want 4", records[1].Row)
+	}
+}
+
+func TestReadCSVMapping(t *testing.T) {
+	input := "First,Last,Mobile\nJohn,Smith,555-1234\n"
+	mapping := Mapping{"First": "first_name", "Last": "last_name", "Mobile": "phone"}
+
+	records, _, err := ReadCSV(strings.NewReader(input), mapping, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records[0].Name != "John Smith" || records[0].Phone != "555-1234" {
+		t.Errorf("record = %+v", records[0])
+	}
+
+	bad := []Mapping{
+		{"First": "nickname"},
+		{"First": "field.unknown", "Last": "name"},
+		{"Mobile": "phone"},
+		{"Missing": "name"},
+	}
+	for _, mapping := range bad {
+		if _, _, err := ReadCSV(strings.NewReader(input), mapping, nil); err == nil {
+			t.Errorf("mapping %v was accepted", mapping)
+		}
+	}
+}
+
+func TestReadVCards(t *testing.T) {
+	input := "BEGIN:VCARD\r\n" +
+		"VERSION:3.0\r\n" +
+		"N:Smith;John;;Dr.;\r\n" +
+		"EMAIL;TYPE=INTERNET:john@work.example\r\n" +
+		"EMAIL;TYPE=INTERNET,pref:john@example.com\r\n" +
+		"item1.TEL;TYPE=CELL:555-1234\r\n" +
+		"ADR;TYPE=HOME:;;1 Main St;Springfield;IL;62701;USA\r\n" +
+		"NOTE:Met at outreach\\, spring\\nLikes music that is long enough to be\r\n" +
+		"  folded\r\n" +
+		"CATEGORIES:college,spanish\r\n" +
+		"END:VCARD\r\n" +
+		"BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"FN:Mary Jones\r\n" +
+		"TEL;VALUE=uri:tel:+1-555-9999\r\n" +
+		"END:VCARD\r\n" +
+		"BEGIN:VCARD\r\n" +
+		"VERSION:2.1\r\n" +
+		"FN:Old Card\r\n" +
+		"END:VCARD\r\n"
+
+	records, err := ReadVCards(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+
+	john := records[0]
+	if john.Name != "Dr. John Smith" || john.Email != "john@example.com" || john.Phone != "555-1234" {
+		t.Errorf("record = %+v", john)
+	}
+	if john.Location != "Springfield, IL, USA" {
+		t.Errorf("location = %q", john.Location)
+	}
+	if john.Notes != "Met at outreach, spring\nLikes music that is long enough to be folded" {
+		t.Errorf("notes = %q", john.Notes)
+	}
+	if !reflect.DeepEqual(john.Tags, []string{"college", "spanish"}) || len(john.Errors) != 0 {
+		t.Errorf("tags = %v, errors = %v", john.Tags, john.Errors)
+	}
+
+	if mary := records[1]; mary.Name != "Mary Jones" || mary.Phone != "+1-555-9999" {
+		t.Errorf("record = %+v", mary)
+	}
+	if old := records[2]; len(old.Errors) != 1 || old.Row != 3 {
+		t.Errorf("2.1 card: row %d, errors %v", old.Row, old.Errors)
+	}
+}
+
+func TestWriteVCardsRoundTrip(t *testing.T) {
+	contact := &models.Contact{
+		ID:          7,
+		Name:        "Ana María López",
+		Email:       "ana@example.com",
+		Phone:       "555-1234",
+		Location:    "Springfield",
+		Notes:       "Prefers Spanish; " + strings.Repeat("ñ", 60) + "\nCall after 5",
+		LastUpdated: time.Now(),
+		Tags:        []*models.Tag{{Name: "spanish"}, {Name: "met at outreach"}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteVCards(&buf, []*models.Contact{contact}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 bytes: %q", line)
+		}
+	}
+
+	records, err := ReadVCards(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := records[0]
+	if got.Name != contact.Name || got.Email != contact.Email || got.Phone != contact.Phone ||
+		got.Location != contact.Location || got.Notes != contact.Notes {
+		t.Errorf("round trip = %+v", got)
+	}
+	if !reflect.DeepEqual(got.Tags, []string{"spanish", "met at outreach"}) {
+		t.Errorf("tags = %v", got.Tags)
+	}
+}
+
+func TestWriteCSVRoundTrip(t *testing.T) {
+	contacts := []*models.Contact{{
+		ID:              3,
+		Name:            "John Smith",
+		Notes:           "Line one\nLine, two",
+		CurrentStatusID: 2,
+		Tags:            []*models.Tag{{Name: "college"}, {Name: "spanish"}},
+		CustomFields:    map[string]interface{}{"age": 42.0, "baptized_at_home": true},
+	}}
+	fields := []*models.CustomField{
+		{Key: "age", Type: models.FieldNumber},
+		{Key: "baptized_at_home", Type: models.FieldBoolean},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, contacts, map[int]string{2: "In Studies"}, fields); err != nil {
+		t.Fatal(err)
+	}
+
+	records, _, err := ReadCSV(&buf, nil, []string{"age", "baptized_at_home"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := records[0]
+	if got.Name != "John Smith" || got.Notes != "Line one\nLine, two" || got.Status != "In Studies" {
+		t.Errorf("round trip = %+v", got)
+	}
+	if !reflect.DeepEqual(got.Tags, []string{"college", "spanish"}) {
+		t.Errorf("tags = %v", got.Tags)
+	}
+	if got.Fields["age"] != "42" || got.Fields["baptized_at_home"] != "true" {
+		t.Errorf("fields = %v", got.Fields)
+	}
+}
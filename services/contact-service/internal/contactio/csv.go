@@ -0,0 +1,277 @@
+// Package contactio reads and writes contacts as CSV and vCard files.
+package contactio
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
+)
+
+// Record is a contact read from a file, before validation
+type Record struct {
+	Row      int // line of a CSV file or position of a vCard, from 1
+	Name     string
+	Email    string
+	Phone    string
+	Location string
+	Notes    string
+	Status   string            // status name or ID
+	Tags     []string          // tag names
+	Fields   map[string]string // custom field values by key
+	Errors   []string          // problems reading the record
+}
+
+// Import targets a CSV column can be mapped to, besides "field.<key>" for
+// custom fields. Columns mapped to "" are ignored. first_name and last_name
+// are joined to make the name when no column maps to name.
+var targets = map[string]bool{
+	"name": true, "first_name": true, "last_name": true,
+	"email": true, "phone": true, "location": true, "notes": true,
+	"status": true, "tags": true,
+}
+
+// Mapping maps CSV column headers to import targets
+type Mapping map[string]string
+
+// DefaultMapping maps each header that names a target or a custom field key,
+// ignoring case and with spaces read as underscores. A "field." prefix picks
+// a custom field whose key is also a target. Other columns are ignored.
+func DefaultMapping(header []string, fieldKeys []string) Mapping {
+	keys := make(map[string]bool, len(fieldKeys))
+	for _, key := range fieldKeys {
+		keys[key] = true
+	}
+
+	mapping := make(Mapping)
+	for _, column := range header {
+		name := strings.ToLower(strings.Join(strings.Fields(column), "_"))
+		switch {
+		case strings.HasPrefix(name, "field."):
+			if key := strings.TrimPrefix(name, "field."); keys[key] {
+				mapping[column] = "field." + key
+			}
+		case targets[name]:
+			mapping[column] = name
+		case name == "status_name" || name == "current_status":
+			mapping[column] = "status"
+		case keys[name]:
+			mapping[column] = "field." + name
+		}
+	}
+	return mapping
+}
+
+// Validate checks that every target is known, that fieldKeys contains every
+// custom field mapped to, and that something provides the name
+func (m Mapping) Validate(fieldKeys []string) error {
+	keys := make(map[string]bool, len(fieldKeys))
+	for _, key := range fieldKeys {
+		keys[key] = true
+	}
+
+	named := false
+	for column, target := range m {
+		switch {
+		case target == "":
+		case strings.HasPrefix(target, "field."):
+			if !keys[strings.TrimPrefix(target, "field.")] {
+				return fmt.Errorf("column %q maps to unknown custom field %q", column, target)
+			}
+		case !targets[target]:
+			return fmt.Errorf("column %q maps to unknown target %q", column, target)
+		}
+		if target == "name" || target == "first_name" || target == "last_name" {
+			named = true
+		}
+	}
+	if !named {
+		return fmt.Errorf("no column maps to name, first_name or last_name")
+	}
+	return nil
+}
+
+// ReadCSV reads contacts from a CSV file whose first row is a header. With a
+// nil mapping, DefaultMapping is used. Columns missing from the mapping are
+// ignored; mapped columns missing from the file are an error.
+func ReadCSV(r io.Reader, mapping Mapping, fieldKeys []string) ([]Record, Mapping, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("the file is empty")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff") // spreadsheets often add a byte order mark
+	}
+
+	if mapping == nil {
+		mapping = DefaultMapping(header, fieldKeys)
+	}
+	if err := mapping.Validate(fieldKeys); err != nil {
+		return nil, mapping, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[column] = i
+	}
+	for column := range mapping {
+		if _, ok := columns[column]; !ok {
+			return nil, mapping, fmt.Errorf("column %q is not in the file", column)
+		}
+	}
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			parseErr, ok := err.(*csv.ParseError)
+			if !ok {
+				return nil, mapping, err
+			}
+			records = append(records, Record{Row: parseErr.StartLine, Errors: []string{parseErr.Err.Error()}})
+			continue
+		}
+		if blank(row) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		record := Record{Row: line, Fields: make(map[string]string)}
+		var firstName, lastName string
+		for column, target := range mapping {
+			i := columns[column]
+			if i >= len(row) {
+				continue
+			}
+			value := strings.TrimSpace(row[i])
+			switch target {
+			case "name":
+				record.Name = value
+			case "first_name":
+				firstName = value
+			case "last_name":
+				lastName = value
+			case "email":
+				record.Email = value
+			case "phone":
+				record.Phone = value
+			case "location":
+				record.Location = value
+			case "notes":
+				record.Notes = value
+			case "status":
+				record.Status = value
+			case "tags":
+				record.Tags = splitList(value)
+			case "":
+			default:
+				record.Fields[strings.TrimPrefix(target, "field.")] = value
+			}
+		}
+		if record.Name == "" {
+			record.Name = strings.TrimSpace(firstName + " " + lastName)
+		}
+		records = append(records, record)
+	}
+	return records, mapping, nil
+}
+
+// blank reports whether every cell of a row is empty
+func blank(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// splitList splits a list of names separated by semicolons or commas
+func splitList(value string) []string {
+	var names []string
+	for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' }) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// WriteCSV writes contacts as CSV with a header row, a "field.<key>" column
+// per custom field and tags separated by semicolons. The file can be imported again with
+// the default mapping. statusNames maps status IDs to names.
+func WriteCSV(w io.Writer, contacts []*models.Contact, statusNames map[int]string, fields []*models.CustomField) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"id", "name", "email", "phone", "location", "notes", "status", "tags",
+		"assigned_worker_id", "group_id", "date_added", "last_updated"}
+	for _, field := range fields {
+		header = append(header, "field."+field.Key)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, contact := range contacts {
+		tags := make([]string, len(contact.Tags))
+		for i, tag := range contact.Tags {
+			tags[i] = tag.Name
+		}
+		row := []string{
+			strconv.Itoa(contact.ID),
+			contact.Name,
+			contact.Email,
+			contact.Phone,
+			contact.Location,
+			contact.Notes,
+			statusNames[contact.CurrentStatusID],
+			strings.Join(tags, "; "),
+			optionalID(contact.AssignedWorkerID),
+			optionalID(contact.GroupID),
+			contact.DateAdded.UTC().Format("2006-01-02T15:04:05Z"),
+			contact.LastUpdated.UTC().Format("2006-01-02T15:04:05Z"),
+		}
+		for _, field := range fields {
+			row = append(row, formatFieldValue(contact.CustomFields[field.Key]))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// optionalID formats an ID, leaving zero (none) blank
+func optionalID(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+// formatFieldValue formats a loaded custom field value as it would be given
+// in a request
+func formatFieldValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,308 @@
+package contactio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
+)
+
+// vcardProperty is one content line of a vCard
+type vcardProperty struct {
+	name   string
+	params []string // as written, e.g. "TYPE=home"
+	value  string   // still escaped
+}
+
+// preferred reports whether the property is marked as the preferred one of
+// its kind, which vCard 3 writes as TYPE=pref and vCard 4 as PREF=1
+func (p vcardProperty) preferred() bool {
+	for _, param := range p.params {
+		param = strings.ToLower(param)
+		if param == "pref=1" || (strings.HasPrefix(param, "type=") && strings.Contains(param, "pref")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadVCards reads contacts from a vCard 3.0 or 4.0 file holding any number
+// of cards. Cards of other versions are returned with an error.
+func ReadVCards(r io.Reader) ([]Record, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	var card []vcardProperty
+	inCard := false
+	for _, line := range lines {
+		property, ok := parseProperty(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case property.name == "BEGIN" && strings.EqualFold(property.value, "VCARD"):
+			if inCard {
+				records = append(records, Record{Row: len(records) + 1, Errors: []string{"card is missing END:VCARD"}})
+			}
+			card, inCard = nil, true
+		case property.name == "END" && strings.EqualFold(property.value, "VCARD"):
+			if inCard {
+				records = append(records, cardRecord(len(records)+1, card))
+			}
+			inCard = false
+		case inCard:
+			card = append(card, property)
+		}
+	}
+	if inCard {
+		records = append(records, Record{Row: len(records) + 1, Errors: []string{"card is missing END:VCARD"}})
+	}
+	return records, nil
+}
+
+// unfold reads content lines, joining folded lines back together
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseProperty splits a content line into its name, parameters and value
+func parseProperty(line string) (vcardProperty, bool) {
+	// The value starts at the first colon outside a quoted parameter value
+	quoted, colon := false, -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return vcardProperty{}, false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	name := strings.ToUpper(parts[0])
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:] // drop groups such as item1.TEL
+	}
+	return vcardProperty{name: name, params: parts[1:], value: line[colon+1:]}, true
+}
+
+// cardRecord builds a record from a card's properties
+func cardRecord(row int, card []vcardProperty) Record {
+	record := Record{Row: row}
+	var version, structuredName string
+	var emailPreferred, phonePreferred bool
+	var notes []string
+
+	for _, p := range card {
+		switch p.name {
+		case "VERSION":
+			version = p.value
+		case "FN":
+			record.Name = unescapeText(p.value)
+		case "N":
+			// Family; Given; Additional; Prefixes; Suffixes
+			parts := splitEscaped(p.value, ';')
+			var names []string
+			for _, i := range []int{3, 1, 2, 0, 4} {
+				if i < len(parts) && parts[i] != "" {
+					names = append(names, parts[i])
+				}
+			}
+			structuredName = strings.Join(names, " ")
+		case "EMAIL":
+			if record.Email == "" || (p.preferred() && !emailPreferred) {
+				record.Email = unescapeText(p.value)
+				emailPreferred = p.preferred()
+			}
+		case "TEL":
+			if record.Phone == "" || (p.preferred() && !phonePreferred) {
+				phone := unescapeText(p.value)
+				if strings.HasPrefix(strings.ToLower(phone), "tel:") {
+					phone = phone[len("tel:"):]
+				}
+				record.Phone = phone
+				phonePreferred = p.preferred()
+			}
+		case "ADR":
+			// PO box; extended; street; locality; region; postal code; country
+			if record.Location == "" {
+				parts := splitEscaped(p.value, ';')
+				var place []string
+				for _, i := range []int{3, 4, 6} {
+					if i < len(parts) && parts[i] != "" {
+						place = append(place, parts[i])
+					}
+				}
+				record.Location = strings.Join(place, ", ")
+			}
+		case "NOTE":
+			notes = append(notes, unescapeText(p.value))
+		case "CATEGORIES":
+			for _, tag := range splitEscaped(p.value, ',') {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					record.Tags = append(record.Tags, tag)
+				}
+			}
+		}
+	}
+
+	if record.Name == "" {
+		record.Name = structuredName
+	}
+	record.Name = strings.TrimSpace(record.Name)
+	record.Notes = strings.Join(notes, "\n")
+
+	switch version {
+	case "3.0", "4.0":
+	case "":
+		record.Errors = append(record.Errors, "card has no VERSION")
+	default:
+		record.Errors = append(record.Errors, fmt.Sprintf("vCard version %s is not supported; use 3.0 or 4.0", version))
+	}
+	return record
+}
+
+// splitEscaped splits a structured value on unescaped separators and
+// unescapes each part
+func splitEscaped(value string, sep rune) []string {
+	var parts []string
+	var part strings.Builder
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			part.WriteRune('\\')
+			part.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == sep:
+			parts = append(parts, unescapeText(part.String()))
+			part.Reset()
+		default:
+			part.WriteRune(r)
+		}
+	}
+	return append(parts, unescapeText(part.String()))
+}
+
+// unescapeText undoes vCard text escaping
+func unescapeText(value string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range value {
+		if !escaped {
+			if r == '\\' {
+				escaped = true
+			} else {
+				b.WriteRune(r)
+			}
+			continue
+		}
+		if r == 'n' || r == 'N' {
+			b.WriteRune('\n')
+		} else {
+			b.WriteRune(r)
+		}
+		escaped = false
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// escapeText escapes a vCard text value
+func escapeText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\r\n", `\n`, "\n", `\n`, ",", `\,`, ";", `\;`).Replace(value)
+}
+
+// WriteVCards writes contacts as vCard 3.0, which phone address books read
+// most widely. Tags become categories.
+func WriteVCards(w io.Writer, contacts []*models.Contact) error {
+	bw := bufio.NewWriter(w)
+	for _, contact := range contacts {
+		// The last word of the name is taken as the family name
+		given, family := "", contact.Name
+		if i := strings.LastIndex(strings.TrimSpace(contact.Name), " "); i >= 0 {
+			given, family = contact.Name[:i], contact.Name[i+1:]
+		}
+
+		lines := []string{
+			"BEGIN:VCARD",
+			"VERSION:3.0",
+			"UID:contact-" + strconv.Itoa(contact.ID),
+			"FN:" + escapeText(contact.Name),
+			"N:" + escapeText(family) + ";" + escapeText(given) + ";;;",
+		}
+		if contact.Email != "" {
+			lines = append(lines, "EMAIL;TYPE=INTERNET:"+escapeText(contact.Email))
+		}
+		if contact.Phone != "" {
+			lines = append(lines, "TEL;TYPE=VOICE:"+escapeText(contact.Phone))
+		}
+		if contact.Location != "" {
+			lines = append(lines, "ADR:;;;"+escapeText(contact.Location)+";;;")
+		}
+		if contact.Notes != "" {
+			lines = append(lines, "NOTE:"+escapeText(contact.Notes))
+		}
+		if len(contact.Tags) > 0 {
+			tags := make([]string, len(contact.Tags))
+			for i, tag := range contact.Tags {
+				tags[i] = escapeText(tag.Name)
+			}
+			lines = append(lines, "CATEGORIES:"+strings.Join(tags, ","))
+		}
+		lines = append(lines,
+			"REV:"+contact.LastUpdated.UTC().Format("2006-01-02T15:04:05Z"),
+			"END:VCARD",
+		)
+
+		for _, line := range lines {
+			if _, err := bw.WriteString(fold(line)); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// fold ends a content line with CRLF, breaking it every 75 bytes without
+// splitting a character
+func fold(line string) string {
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
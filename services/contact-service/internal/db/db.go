@@ -3,32 +3,8 @@ package db
 import (
 	"database/sql"
 	"log"
-	"time"
-
-	_ "github.com/go-sql-driver/mysql"
 )
 
-// Connect establishes a connection to the database
-func Connect(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		return nil, err
-	}
-
-	// Configure connection pool
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
-
-	// Test connection
-	if err := db.Ping(); err != nil {
-		return nil, err
-	}
-
-	log.Println("Connected to database successfully")
-	return db, nil
-}
-
 // EnsureTablesExist creates the necessary tables if they don't exist
 func EnsureTablesExist(db *sql.DB) error {
 	// Create statuses table if it doesn't exist
@@ -38,6 +14,8 @@ func EnsureTablesExist(db *sql.DB) error {
 			name VARCHAR(100) NOT NULL,
 			description TEXT,
 			display_order INT NOT NULL DEFAULT 0,
+			is_milestone BOOLEAN NOT NULL DEFAULT FALSE,
+			stall_days INT NOT NULL DEFAULT 0,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 			UNIQUE KEY (name)
@@ -48,6 +26,39 @@ func EnsureTablesExist(db *sql.DB) error {
 		return err
 	}
 
+	// Add the milestone flag and stall threshold to statuses created before
+	// they existed
+	statusColumns := `
+		ALTER TABLE statuses
+			ADD COLUMN IF NOT EXISTS is_milestone BOOLEAN NOT NULL DEFAULT FALSE,
+			ADD COLUMN IF NOT EXISTS stall_days INT NOT NULL DEFAULT 0;
+	`
+	_, err = db.Exec(statusColumns)
+	if err != nil {
+		return err
+	}
+
+	// Create status transitions table if it doesn't exist. A status with no
+	// transitions out of it may move to any other status.
+	transitionsTable := `
+		CREATE TABLE IF NOT EXISTS status_transitions (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			from_status_id INT NOT NULL,
+			to_status_id INT NOT NULL,
+			requires_note BOOLEAN NOT NULL DEFAULT FALSE,
+			min_days INT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			UNIQUE KEY (from_status_id, to_status_id),
+			FOREIGN KEY (from_status_id) REFERENCES statuses(id) ON DELETE CASCADE,
+			FOREIGN KEY (to_status_id) REFERENCES statuses(id) ON DELETE CASCADE
+		) ENGINE=InnoDB;
+	`
+	_, err = db.Exec(transitionsTable)
+	if err != nil {
+		return err
+	}
+
 	// Create contacts table if it doesn't exist
 	contactsTable := `
 		CREATE TABLE IF NOT EXISTS contacts (
@@ -58,6 +69,9 @@ func EnsureTablesExist(db *sql.DB) error {
 			location VARCHAR(255),
 			notes TEXT,
 			current_status_id INT NOT NULL,
+			owner_id INT NULL,
+			assigned_worker_id INT NULL,
+			group_id INT NULL,
 			date_added TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 			FOREIGN KEY (current_status_id) REFERENCES statuses(id)
@@ -68,6 +82,22 @@ func EnsureTablesExist(db *sql.DB) error {
 		return err
 	}
 
+	// Add ownership columns to contacts created before they existed. Owners,
+	// workers and groups live in user-service, so there are no foreign keys.
+	ownershipColumns := `
+		ALTER TABLE contacts
+			ADD COLUMN IF NOT EXISTS owner_id INT NULL,
+			ADD COLUMN IF NOT EXISTS assigned_worker_id INT NULL,
+			ADD COLUMN IF NOT EXISTS group_id INT NULL,
+			ADD INDEX IF NOT EXISTS idx_contacts_owner (owner_id),
+			ADD INDEX IF NOT EXISTS idx_contacts_assigned_worker (assigned_worker_id),
+			ADD INDEX IF NOT EXISTS idx_contacts_group (group_id);
+	`
+	_, err = db.Exec(ownershipColumns)
+	if err != nil {
+		return err
+	}
+
 	// Create contact status history table if it doesn't exist
 	historyTable := `
 		CREATE TABLE IF NOT EXISTS contact_status_history (
@@ -85,6 +115,195 @@ func EnsureTablesExist(db *sql.DB) error {
 		return err
 	}
 
+	// Add void columns to history created before entries could be voided.
+	// date_changed is when the change took effect, which may be backdated.
+	historyColumns := `
+		ALTER TABLE contact_status_history
+			ADD COLUMN IF NOT EXISTS voided BOOLEAN NOT NULL DEFAULT FALSE,
+			ADD COLUMN IF NOT EXISTS voided_at TIMESTAMP NULL,
+			ADD COLUMN IF NOT EXISTS voided_by INT NULL,
+			ADD COLUMN IF NOT EXISTS void_reason TEXT,
+			ADD INDEX IF NOT EXISTS idx_contact_status_history_contact (contact_id, date_changed);
+	`
+	_, err = db.Exec(historyColumns)
+	if err != nil {
+		return err
+	}
+
+	// Create status history revisions table if it doesn't exist. Each row is
+	// an entry as it was before an edit or void.
+	revisionsTable := `
+		CREATE TABLE IF NOT EXISTS contact_status_history_revisions (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			history_id INT NOT NULL,
+			status_id INT NOT NULL,
+			notes TEXT,
+			date_changed TIMESTAMP NULL,
+			voided BOOLEAN NOT NULL DEFAULT FALSE,
+			revised_by INT NULL,
+			revised_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			reason TEXT,
+			INDEX idx_history_revisions_history (history_id),
+			FOREIGN KEY (history_id) REFERENCES contact_status_history(id) ON DELETE CASCADE,
+			FOREIGN KEY (status_id) REFERENCES statuses(id)
+		) ENGINE=InnoDB;
+	`
+	_, err = db.Exec(revisionsTable)
+	if err != nil {
+		return err
+	}
+
+	// Create custom field definitions table if it doesn't exist
+	customFieldsTable := `
+		CREATE TABLE IF NOT EXISTS custom_fields (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			field_key VARCHAR(64) NOT NULL,
+			label VARCHAR(100) NOT NULL,
+			field_type ENUM('text', 'number', 'date', 'select', 'boolean') NOT NULL,
+			required BOOLEAN NOT NULL DEFAULT FALSE,
+			options TEXT,
+			max_length INT NULL,
+			pattern VARCHAR(255) NULL,
+			min_value DOUBLE NULL,
+			max_value DOUBLE NULL,
+			display_order INT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			UNIQUE KEY (field_key)
+		) ENGINE=InnoDB;
+	`
+	_, err = db.Exec(customFieldsTable)
+	if err != nil {
+		return err
+	}
+
+	// Create custom field values table if it doesn't exist. Values are stored
+	// as text in the form custom fields normalize them to.
+	fieldValuesTable := `
+		CREATE TABLE IF NOT EXISTS contact_field_values (
+			contact_id INT NOT NULL,
+			field_id INT NOT NULL,
+			value TEXT NOT NULL,
+			PRIMARY KEY (contact_id, field_id),
+			INDEX idx_contact_field_values_field (field_id, value(100)),
+			FOREIGN KEY (contact_id) REFERENCES contacts(id) ON DELETE CASCADE,
+			FOREIGN KEY (field_id) REFERENCES custom_fields(id) ON DELETE CASCADE
+		) ENGINE=InnoDB;
+	`
+	_, err = db.Exec(fieldValuesTable)
+	if err != nil {
+		return err
+	}
+
+	// Create tags table if it doesn't exist
+	tagsTable := `
+		CREATE TABLE IF NOT EXISTS tags (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			UNIQUE KEY (name)
+		) ENGINE=InnoDB;
+	`
+	_, err = db.Exec(tagsTable)
+	if err != nil {
+		return err
+	}
+
+	// Create contact tags table if it doesn't exist
+	contactTagsTable := `
+		CREATE TABLE IF NOT EXISTS contact_tags (
+			contact_id INT NOT NULL,
+			tag_id INT NOT NULL,
+			tagged_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (contact_id, tag_id),
+			INDEX idx_contact_tags_tag (tag_id),
+			FOREIGN KEY (contact_id) REFERENCES contacts(id) ON DELETE CASCADE,
+			FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+		) ENGINE=InnoDB;
+	`
+	_, err = db.Exec(contactTagsTable)
+	if err != nil {
+		return err
+	}
+
+	// Create follow-up tasks table if it doesn't exist. Assignees live in
+	// user-service, so there is no foreign key.
+	tasksTable := `
+		CREATE TABLE IF NOT EXISTS tasks (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			contact_id INT NOT NULL,
+			assignee_id INT NULL,
+			task_type VARCHAR(32) NOT NULL,
+			title VARCHAR(255) NOT NULL,
+			notes TEXT,
+			due_date DATE NULL,
+			auto BOOLEAN NOT NULL DEFAULT FALSE,
+			created_by INT NULL,
+			completed_at TIMESTAMP NULL,
+			completed_by INT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			INDEX idx_tasks_assignee (assignee_id, completed_at, due_date),
+			INDEX idx_tasks_contact (contact_id, completed_at),
+			FOREIGN KEY (contact_id) REFERENCES contacts(id) ON DELETE CASCADE
+		) ENGINE=InnoDB;
+	`
+	_, err = db.Exec(tasksTable)
+	if err != nil {
+		return err
+	}
+
+	// Create interactions table if it doesn't exist. The log is append-only:
+	// edits keep the row as it was in interaction_revisions, and rows are
+	// voided rather than deleted.
+	interactionsTable := `
+		CREATE TABLE IF NOT EXISTS interactions (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			contact_id INT NOT NULL,
+			interaction_type VARCHAR(32) NOT NULL,
+			occurred_at DATETIME NOT NULL,
+			author_id INT NULL,
+			duration_minutes INT NULL,
+			body TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			voided BOOLEAN NOT NULL DEFAULT FALSE,
+			voided_at TIMESTAMP NULL,
+			voided_by INT NULL,
+			void_reason TEXT,
+			INDEX idx_interactions_contact (contact_id, occurred_at),
+			FOREIGN KEY (contact_id) REFERENCES contacts(id) ON DELETE CASCADE
+		) ENGINE=InnoDB;
+	`
+	_, err = db.Exec(interactionsTable)
+	if err != nil {
+		return err
+	}
+
+	// Create interaction revisions table if it doesn't exist. Each row is an
+	// interaction as it was before an edit or void.
+	interactionRevisionsTable := `
+		CREATE TABLE IF NOT EXISTS interaction_revisions (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			interaction_id INT NOT NULL,
+			interaction_type VARCHAR(32) NOT NULL,
+			occurred_at DATETIME NOT NULL,
+			duration_minutes INT NULL,
+			body TEXT NOT NULL,
+			voided BOOLEAN NOT NULL DEFAULT FALSE,
+			revised_by INT NULL,
+			revised_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			reason TEXT,
+			INDEX idx_interaction_revisions_interaction (interaction_id),
+			FOREIGN KEY (interaction_id) REFERENCES interactions(id) ON DELETE CASCADE
+		) ENGINE=InnoDB;
+	`
+	_, err = db.Exec(interactionRevisionsTable)
+	if err != nil {
+		return err
+	}
+
 	// Insert default statuses if none exist
 	var count int
 	err = db.QueryRow("SELECT COUNT(*) FROM statuses").Scan(&count)
@@ -94,11 +313,11 @@ func EnsureTablesExist(db *sql.DB) error {
 
 	if count == 0 {
 		defaultStatuses := `
-			INSERT INTO statuses (name, description, display_order) VALUES
-			('New Contact', 'Initial contact with the church', 1),
-			('In Studies', 'Actively participating in Bible studies', 2),
-			('Baptized', 'Has been baptized', 3),
-			('Gospel Worker', 'Actively sharing faith with others', 4);
+			INSERT INTO statuses (name, description, display_order, is_milestone) VALUES
+			('New Contact', 'Initial contact with the church', 1, FALSE),
+			('In Studies', 'Actively participating in Bible studies', 2, FALSE),
+			('Baptized', 'Has been baptized', 3, TRUE),
+			('Gospel Worker', 'Actively sharing faith with others', 4, TRUE);
 		`
 		_, err = db.Exec(defaultStatuses)
 		if err != nil {
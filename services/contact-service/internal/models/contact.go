@@ -4,19 +4,27 @@ import (
 	"database/sql"
 	"errors"
 	"time"
+
+	"github.com/cardoza1991/church-management-system/pkg/pagination"
 )
 
 // Contact represents a contact in the system
 type Contact struct {
-	ID              int       `json:"id"`
-	Name            string    `json:"name"`
-	Email           string    `json:"email,omitempty"`
-	Phone           string    `json:"phone,omitempty"`
-	Location        string    `json:"location,omitempty"`
-	Notes           string    `json:"notes,omitempty"`
-	DateAdded       time.Time `json:"date_added"`
-	LastUpdated     time.Time `json:"last_updated"`
-	CurrentStatusID int       `json:"current_status_id"`
+	ID               int       `json:"id"`
+	Name             string    `json:"name"`
+	Email            string    `json:"email,omitempty"`
+	Phone            string    `json:"phone,omitempty"`
+	Location         string    `json:"location,omitempty"`
+	Notes            string    `json:"notes,omitempty"`
+	DateAdded        time.Time `json:"date_added"`
+	LastUpdated      time.Time `json:"last_updated"`
+	CurrentStatusID  int       `json:"current_status_id"`
+	OwnerID          int       `json:"owner_id,omitempty"`           // user who added the contact
+	AssignedWorkerID int       `json:"assigned_worker_id,omitempty"` // user currently responsible for the contact
+	GroupID          int       `json:"group_id,omitempty"`           // ministry group the contact belongs to
+
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"` // by field key; only set once loaded
+	Tags         []*Tag                 `json:"tags,omitempty"`          // only set once loaded
 }
 
 // ContactRepository provides access to the contact store
@@ -29,49 +37,82 @@ func NewContactRepository(db *sql.DB) *ContactRepository {
 	return &ContactRepository{DB: db}
 }
 
-// GetAll retrieves all contacts
-func (r *ContactRepository) GetAll(limit, offset int) ([]*Contact, error) {
-	query := `SELECT id, name, email, phone, location, notes, date_added, 
-	          last_updated, current_status_id 
-	          FROM contacts ORDER BY name LIMIT ? OFFSET ?`
+// contactColumns lists the columns scanContact expects, in order
+const contactColumns = `c.id, c.name, c.email, c.phone, c.location, c.notes, c.date_added,
+	          c.last_updated, c.current_status_id, c.owner_id, c.assigned_worker_id, c.group_id`
+
+// GetAll retrieves a page of the contacts visible in scope that match filter,
+// along with how many match in total and cursors for the neighbouring pages
+func (r *ContactRepository) GetAll(scope ContactScope, filter ContactFilter, page pagination.Request) ([]*Contact, int, pagination.Cursors, error) {
+	where, args := contactsWhere(scope, filter)
+	
+	// Count every match for pagination
+	var total int
+	if err := r.DB.QueryRow(`SELECT COUNT(*) FROM contacts c WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, pagination.Cursors{}, err
+	}
 	
-	rows, err := r.DB.Query(query, limit, offset)
+	// Fetch the page after the cursor, or at the offset
+	keyset := filter.keyset()
+	cursorWhere, cursorArgs := keyset.Where(page)
+	limit, offset := page.QueryLimit()
+	query := `SELECT ` + contactColumns + `
+	          FROM contacts c WHERE ` + where + ` AND ` + cursorWhere + `
+	          ORDER BY ` + keyset.OrderBy(page) + ` LIMIT ? OFFSET ?`
+	args = append(args, cursorArgs...)
+	
+	rows, err := r.DB.Query(query, append(args, limit, offset)...)
 	if err != nil {
-		return nil, err
+		return nil, 0, pagination.Cursors{}, err
 	}
 	defer rows.Close()
 	
 	var contacts []*Contact
 	for rows.Next() {
-		contact := &Contact{}
-		err := rows.Scan(
-			&contact.ID, 
-			&contact.Name, 
-			&contact.Email, 
-			&contact.Phone, 
-			&contact.Location, 
-			&contact.Notes, 
-			&contact.DateAdded, 
-			&contact.LastUpdated, 
-			&contact.CurrentStatusID,
-		)
+		contact, err := scanContact(rows)
 		if err != nil {
-			return nil, err
+			return nil, 0, pagination.Cursors{}, err
 		}
 		contacts = append(contacts, contact)
 	}
 	
-	return contacts, nil
+	contacts, cursors := pagination.Page(contacts, page, filter.cursor)
+	return contacts, total, cursors, nil
+}
+
+// contactsWhere returns an SQL condition on the contacts table, aliased c,
+// matching the contacts visible in scope that match filter
+func contactsWhere(scope ContactScope, filter ContactFilter) (string, []interface{}) {
+	scopeWhere, args := scope.where()
+	filterWhere, filterArgs := filter.where()
+	return scopeWhere + ` AND ` + filterWhere, append(args, filterArgs...)
 }
 
 // GetByID retrieves a contact by ID
 func (r *ContactRepository) GetByID(id int) (*Contact, error) {
-	query := `SELECT id, name, email, phone, location, notes, date_added, 
-	          last_updated, current_status_id 
-	          FROM contacts WHERE id = ?`
+	query := `SELECT ` + contactColumns + ` FROM contacts c WHERE c.id = ?`
+	
+	contact, err := scanContact(r.DB.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("contact not found")
+		}
+		return nil, err
+	}
 	
+	return contact, nil
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanContact(row rowScanner) (*Contact, error) {
 	contact := &Contact{}
-	err := r.DB.QueryRow(query, id).Scan(
+	var ownerID, assignedWorkerID, groupID sql.NullInt64
+	
+	err := row.Scan(
 		&contact.ID, 
 		&contact.Name, 
 		&contact.Email, 
@@ -81,22 +122,30 @@ func (r *ContactRepository) GetByID(id int) (*Contact, error) {
 		&contact.DateAdded, 
 		&contact.LastUpdated, 
 		&contact.CurrentStatusID,
+		&ownerID,
+		&assignedWorkerID,
+		&groupID,
 	)
-	
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("contact not found")
-		}
 		return nil, err
 	}
 	
+	contact.OwnerID = int(ownerID.Int64)
+	contact.AssignedWorkerID = int(assignedWorkerID.Int64)
+	contact.GroupID = int(groupID.Int64)
 	return contact, nil
 }
 
+// nullInt stores zero IDs as NULL
+func nullInt(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
 // Create adds a new contact to the database
 func (r *ContactRepository) Create(contact *Contact) error {
-	query := `INSERT INTO contacts (name, email, phone, location, notes, current_status_id)
-	          VALUES (?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO contacts (name, email, phone, location, notes, current_status_id,
+	          owner_id, assigned_worker_id, group_id)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	
 	result, err := r.DB.Exec(
 		query, 
@@ -106,6 +155,9 @@ func (r *ContactRepository) Create(contact *Contact) error {
 		contact.Location, 
 		contact.Notes, 
 		contact.CurrentStatusID,
+		nullInt(contact.OwnerID),
+		nullInt(contact.AssignedWorkerID),
+		nullInt(contact.GroupID),
 	)
 	if err != nil {
 		return err
@@ -124,7 +176,7 @@ func (r *ContactRepository) Create(contact *Contact) error {
 func (r *ContactRepository) Update(id int, contact *Contact) error {
 	query := `UPDATE contacts 
 	          SET name = ?, email = ?, phone = ?, location = ?, notes = ?, 
-	          current_status_id = ?, last_updated = NOW()
+	          current_status_id = ?, assigned_worker_id = ?, group_id = ?, last_updated = NOW()
 	          WHERE id = ?`
 	
 	_, err := r.DB.Exec(
@@ -135,6 +187,8 @@ func (r *ContactRepository) Update(id int, contact *Contact) error {
 		contact.Location, 
 		contact.Notes, 
 		contact.CurrentStatusID,
+		nullInt(contact.AssignedWorkerID),
+		nullInt(contact.GroupID),
 		id,
 	)
 	
@@ -157,27 +211,27 @@ func (r *ContactRepository) Delete(id int) error {
 	return nil
 }
 
-// UpdateStatus changes a contact's status and logs the change
-func (r *ContactRepository) UpdateStatus(contactID, statusID int, notes string) error {
+// UpdateStatus logs a status change that took effect at effective, or now if
+// effective is zero, and sets the contact's status from its latest entry. A
+// backdated change older than the latest entry only adds to the history.
+func (r *ContactRepository) UpdateStatus(contactID, statusID int, notes string, effective time.Time) error {
 	// Start a transaction to ensure both operations succeed or fail together
 	tx, err := r.DB.Begin()
 	if err != nil {
 		return err
 	}
 	
-	// Update the contact's current status
-	updateQuery := `UPDATE contacts SET current_status_id = ?, last_updated = NOW() WHERE id = ?`
-	_, err = tx.Exec(updateQuery, statusID, contactID)
+	// Log the status change in the history table
+	historyQuery := `INSERT INTO contact_status_history (contact_id, status_id, notes, date_changed)
+	                 VALUES (?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))`
+	_, err = tx.Exec(historyQuery, contactID, statusID, notes, nullTime(effective))
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 	
-	// Log the status change in the history table
-	historyQuery := `INSERT INTO contact_status_history (contact_id, status_id, notes)
-	                 VALUES (?, ?, ?)`
-	_, err = tx.Exec(historyQuery, contactID, statusID, notes)
-	if err != nil {
+	// Update the contact's current status
+	if err := recomputeStatus(tx, contactID); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -188,21 +242,27 @@ func (r *ContactRepository) UpdateStatus(contactID, statusID int, notes string)
 
 // StatusHistoryEntry represents a status change for a contact
 type StatusHistoryEntry struct {
-	ID          int       `json:"id"`
-	ContactID   int       `json:"contact_id"`
-	StatusID    int       `json:"status_id"`
-	StatusName  string    `json:"status_name"`
-	Notes       string    `json:"notes,omitempty"`
-	DateChanged time.Time `json:"date_changed"`
+	ID          int        `json:"id"`
+	ContactID   int        `json:"contact_id"`
+	StatusID    int        `json:"status_id"`
+	StatusName  string     `json:"status_name"`
+	Notes       string     `json:"notes,omitempty"`
+	DateChanged time.Time  `json:"date_changed"` // when the change took effect
+	Voided      bool       `json:"voided"`
+	VoidedAt    *time.Time `json:"voided_at,omitempty"`
+	VoidedBy    int        `json:"voided_by,omitempty"`
+	VoidReason  string     `json:"void_reason,omitempty"`
+	Revisions   int        `json:"revisions"` // earlier versions kept by edits and voids
 }
 
-// GetStatusHistory retrieves the status history for a contact
+// GetStatusHistory retrieves the status history for a contact, voided entries
+// included, latest first
 func (r *ContactRepository) GetStatusHistory(contactID int) ([]*StatusHistoryEntry, error) {
-	query := `SELECT h.id, h.contact_id, h.status_id, s.name as status_name, h.notes, h.date_changed
+	query := `SELECT ` + historyColumns + `
 	          FROM contact_status_history h
 	          JOIN statuses s ON h.status_id = s.id
 	          WHERE h.contact_id = ?
-	          ORDER BY h.date_changed DESC`
+	          ORDER BY h.date_changed DESC, h.id DESC`
 	
 	rows, err := r.DB.Query(query, contactID)
 	if err != nil {
@@ -212,20 +272,12 @@ func (r *ContactRepository) GetStatusHistory(contactID int) ([]*StatusHistoryEnt
 	
 	var history []*StatusHistoryEntry
 	for rows.Next() {
-		entry := &StatusHistoryEntry{}
-		err := rows.Scan(
-			&entry.ID,
-			&entry.ContactID,
-			&entry.StatusID,
-			&entry.StatusName,
-			&entry.Notes,
-			&entry.DateChanged,
-		)
+		entry, err := scanHistoryEntry(rows)
 		if err != nil {
 			return nil, err
 		}
 		history = append(history, entry)
 	}
 	
-	return history, nil
+	return history, rows.Err()
 }
\ No newline at end of file
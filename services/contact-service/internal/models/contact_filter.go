@@ -0,0 +1,183 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cardoza1991/church-management-system/pkg/pagination"
+)
+
+// ContactSortFields maps the sort names clients may use to columns. Nullable
+// columns sort as empty strings so cursors can compare them.
+var ContactSortFields = map[string]string{
+	"name":              "c.name",
+	"email":             "COALESCE(c.email, '')",
+	"location":          "COALESCE(c.location, '')",
+	"date_added":        "c.date_added",
+	"last_updated":      "c.last_updated",
+	"current_status_id": "c.current_status_id",
+}
+
+// ContactFilter narrows and orders a contact listing. Zero values are ignored.
+type ContactFilter struct {
+	IDs              []int
+	Search           string // matched against name, email, phone, location and notes
+	StatusID         int
+	AssignedWorkerID int
+	GroupID          int
+	AddedFrom        time.Time // inclusive
+	AddedTo          time.Time // exclusive
+	UpdatedFrom      time.Time // inclusive
+	UpdatedTo        time.Time // exclusive
+	TagIDs           []int     // contacts must have every tag
+	Fields           []FieldFilter
+
+	Sort       string // a key of ContactSortFields; defaults to name
+	Descending bool
+}
+
+// where returns SQL conditions on the contacts table, aliased c, matching the filter
+func (f ContactFilter) where() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if len(f.IDs) > 0 {
+		conditions = append(conditions, "c.id IN ("+placeholders(len(f.IDs))+")")
+		for _, id := range f.IDs {
+			args = append(args, id)
+		}
+	}
+	if f.Search != "" {
+		pattern := "%" + escapeLike(f.Search) + "%"
+		conditions = append(conditions, `(c.name LIKE ? OR c.email LIKE ? OR c.phone LIKE ?
+			OR c.location LIKE ? OR c.notes LIKE ?)`)
+		args = append(args, pattern, pattern, pattern, pattern, pattern)
+	}
+	if f.StatusID != 0 {
+		conditions = append(conditions, "c.current_status_id = ?")
+		args = append(args, f.StatusID)
+	}
+	if f.AssignedWorkerID != 0 {
+		conditions = append(conditions, "c.assigned_worker_id = ?")
+		args = append(args, f.AssignedWorkerID)
+	}
+	if f.GroupID != 0 {
+		conditions = append(conditions, "c.group_id = ?")
+		args = append(args, f.GroupID)
+	}
+	if !f.AddedFrom.IsZero() {
+		conditions = append(conditions, "c.date_added >= ?")
+		args = append(args, f.AddedFrom)
+	}
+	if !f.AddedTo.IsZero() {
+		conditions = append(conditions, "c.date_added < ?")
+		args = append(args, f.AddedTo)
+	}
+	if !f.UpdatedFrom.IsZero() {
+		conditions = append(conditions, "c.last_updated >= ?")
+		args = append(args, f.UpdatedFrom)
+	}
+	if !f.UpdatedTo.IsZero() {
+		conditions = append(conditions, "c.last_updated < ?")
+		args = append(args, f.UpdatedTo)
+	}
+	for _, tagID := range f.TagIDs {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM contact_tags ct WHERE ct.contact_id = c.id AND ct.tag_id = ?)")
+		args = append(args, tagID)
+	}
+	for _, field := range f.Fields {
+		condition, fieldArgs := field.where()
+		conditions = append(conditions, condition)
+		args = append(args, fieldArgs...)
+	}
+
+	if len(conditions) == 0 {
+		return "1 = 1", nil
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// FieldFilter matches contacts by a custom field value. Values are normalized
+// by the field's Normalize; empty ones are ignored.
+type FieldFilter struct {
+	Field *CustomField
+	Value string // exact match
+	From  string // inclusive lower bound of a number or date field
+	To    string // inclusive upper bound of a number or date field
+}
+
+// where returns an SQL condition on the contacts table, aliased c, matching
+// the filter
+func (f FieldFilter) where() (string, []interface{}) {
+	// Numbers are stored as text, so compare them as decimals; dates compare
+	// correctly as YYYY-MM-DD strings
+	value := "v.value"
+	if f.Field.Type == FieldNumber {
+		value = "CAST(v.value AS DECIMAL(65, 10))"
+	}
+
+	conditions := []string{"v.contact_id = c.id", "v.field_id = ?"}
+	args := []interface{}{f.Field.ID}
+	if f.Value != "" {
+		conditions = append(conditions, value+" = ?")
+		args = append(args, f.Value)
+	}
+	if f.From != "" {
+		conditions = append(conditions, value+" >= ?")
+		args = append(args, f.From)
+	}
+	if f.To != "" {
+		conditions = append(conditions, value+" <= ?")
+		args = append(args, f.To)
+	}
+	return "EXISTS (SELECT 1 FROM contact_field_values v WHERE " + strings.Join(conditions, " AND ") + ")", args
+}
+
+// SortName identifies the sort and direction; cursors are only valid for
+// the sort they were made with
+func (f ContactFilter) SortName() string {
+	sort := f.Sort
+	if _, ok := ContactSortFields[sort]; !ok {
+		sort = "name"
+	}
+	if f.Descending {
+		return sort + ":desc"
+	}
+	return sort + ":asc"
+}
+
+// keyset orders contacts by the sort field, with the ID breaking ties so
+// the order is stable between pages
+func (f ContactFilter) keyset() pagination.Keyset {
+	column, ok := ContactSortFields[f.Sort]
+	if !ok {
+		column = ContactSortFields["name"]
+	}
+	return pagination.Keyset{Key: column, ID: "c.id", Descending: f.Descending}
+}
+
+// cursor returns the cursor marking contact's position in the sort order
+func (f ContactFilter) cursor(contact *Contact) pagination.Cursor {
+	var key string
+	switch f.Sort {
+	case "email":
+		key = contact.Email
+	case "location":
+		key = contact.Location
+	case "date_added":
+		key = pagination.FormatTime(contact.DateAdded)
+	case "last_updated":
+		key = pagination.FormatTime(contact.LastUpdated)
+	case "current_status_id":
+		key = strconv.Itoa(contact.CurrentStatusID)
+	default:
+		key = contact.Name
+	}
+	return pagination.Cursor{Sort: f.SortName(), Key: key, ID: contact.ID}
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,433 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Custom field types
+const (
+	FieldText    = "text"
+	FieldNumber  = "number"
+	FieldDate    = "date" // YYYY-MM-DD
+	FieldSelect  = "select"
+	FieldBoolean = "boolean"
+)
+
+// fieldKeyPattern restricts keys to names safe in query parameters
+var fieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// CustomField is an admin-defined field stored on every contact
+type CustomField struct {
+	ID           int      `json:"id"`
+	Key          string   `json:"key"` // identifies the field in requests and filters; cannot change
+	Label        string   `json:"label"`
+	Type         string   `json:"type"` // cannot change once values may exist
+	Required     bool     `json:"required"`
+	Options      []string `json:"options,omitempty"`    // the allowed values of a select field
+	MaxLength    int      `json:"max_length,omitempty"` // text fields only; zero means no limit
+	Pattern      string   `json:"pattern,omitempty"`    // text fields only; must match the whole value
+	Min          *float64 `json:"min,omitempty"`        // number fields only
+	Max          *float64 `json:"max,omitempty"`        // number fields only
+	DisplayOrder int      `json:"display_order"`
+}
+
+// Validate checks that the field definition itself is usable
+func (f *CustomField) Validate() error {
+	if !fieldKeyPattern.MatchString(f.Key) {
+		return errors.New("key must start with a lowercase letter and contain only lowercase letters, digits and underscores")
+	}
+	if strings.TrimSpace(f.Label) == "" {
+		return errors.New("label is required")
+	}
+
+	switch f.Type {
+	case FieldText, FieldNumber, FieldDate, FieldBoolean:
+		if len(f.Options) > 0 {
+			return errors.New("options are only allowed on select fields")
+		}
+	case FieldSelect:
+		if len(f.Options) == 0 {
+			return errors.New("select fields need at least one option")
+		}
+		seen := make(map[string]bool)
+		for _, option := range f.Options {
+			if option == "" || seen[option] {
+				return errors.New("options must be non-empty and distinct")
+			}
+			seen[option] = true
+		}
+	default:
+		return fmt.Errorf("type must be one of %s, %s, %s, %s or %s",
+			FieldText, FieldNumber, FieldDate, FieldSelect, FieldBoolean)
+	}
+
+	if f.Type != FieldText && (f.MaxLength != 0 || f.Pattern != "") {
+		return errors.New("max_length and pattern are only allowed on text fields")
+	}
+	if f.MaxLength < 0 {
+		return errors.New("max_length cannot be negative")
+	}
+	if f.Pattern != "" {
+		if _, err := f.pattern(); err != nil {
+			return fmt.Errorf("invalid pattern: %v", err)
+		}
+	}
+	if f.Type != FieldNumber && (f.Min != nil || f.Max != nil) {
+		return errors.New("min and max are only allowed on number fields")
+	}
+	if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+		return errors.New("min cannot be greater than max")
+	}
+	return nil
+}
+
+// pattern compiles Pattern anchored to the whole value
+func (f *CustomField) pattern() (*regexp.Regexp, error) {
+	return regexp.Compile(`^(?:` + f.Pattern + `)$`)
+}
+
+// Normalize validates a value from a request and returns it in the form it is
+// stored and compared in. Nil and empty values normalize to "", meaning unset;
+// whether that is allowed for required fields is up to the caller.
+func (f *CustomField) Normalize(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return "", nil
+		}
+	}
+
+	switch f.Type {
+	case FieldNumber:
+		var n float64
+		switch v := value.(type) {
+		case float64:
+			n = v
+		case string:
+			var err error
+			if n, err = strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+				return "", errors.New("must be a number")
+			}
+		default:
+			return "", errors.New("must be a number")
+		}
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return "", errors.New("must be a number")
+		}
+		if f.Min != nil && n < *f.Min {
+			return "", fmt.Errorf("must be at least %v", *f.Min)
+		}
+		if f.Max != nil && n > *f.Max {
+			return "", fmt.Errorf("must be at most %v", *f.Max)
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+
+	case FieldBoolean:
+		switch v := value.(type) {
+		case bool:
+			return strconv.FormatBool(v), nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return strconv.FormatBool(b), nil
+			}
+		}
+		return "", errors.New("must be true or false")
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return "", errors.New("must be a string")
+	}
+	s = strings.TrimSpace(s)
+
+	switch f.Type {
+	case FieldDate:
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return "", errors.New("must be a date in the form YYYY-MM-DD")
+		}
+		return t.Format("2006-01-02"), nil
+
+	case FieldSelect:
+		for _, option := range f.Options {
+			if s == option {
+				return s, nil
+			}
+		}
+		return "", fmt.Errorf("must be one of %s", strings.Join(f.Options, ", "))
+
+	default:
+		if f.MaxLength > 0 && utf8.RuneCountInString(s) > f.MaxLength {
+			return "", fmt.Errorf("must be at most %d characters", f.MaxLength)
+		}
+		if f.Pattern != "" {
+			re, err := f.pattern()
+			if err != nil || !re.MatchString(s) {
+				return "", errors.New("does not match the required format")
+			}
+		}
+		return s, nil
+	}
+}
+
+// decodeFieldValue converts a stored value back to its JSON type
+func decodeFieldValue(fieldType, value string) interface{} {
+	switch fieldType {
+	case FieldNumber:
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case FieldBoolean:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// CustomFieldRepository provides access to custom field definitions
+type CustomFieldRepository struct {
+	DB *sql.DB
+}
+
+// NewCustomFieldRepository creates a new CustomFieldRepository
+func NewCustomFieldRepository(db *sql.DB) *CustomFieldRepository {
+	return &CustomFieldRepository{DB: db}
+}
+
+// customFieldColumns lists the columns scanCustomField expects, in order
+const customFieldColumns = `id, field_key, label, field_type, required, options,
+	max_length, pattern, min_value, max_value, display_order`
+
+// GetAll retrieves every custom field in display order
+func (r *CustomFieldRepository) GetAll() ([]*CustomField, error) {
+	rows, err := r.DB.Query(`SELECT ` + customFieldColumns + ` FROM custom_fields ORDER BY display_order, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fields []*CustomField
+	for rows.Next() {
+		field, err := scanCustomField(rows)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, rows.Err()
+}
+
+// GetByID retrieves a custom field by ID
+func (r *CustomFieldRepository) GetByID(id int) (*CustomField, error) {
+	return r.getOne(`SELECT `+customFieldColumns+` FROM custom_fields WHERE id = ?`, id)
+}
+
+// GetByKey retrieves a custom field by key
+func (r *CustomFieldRepository) GetByKey(key string) (*CustomField, error) {
+	return r.getOne(`SELECT `+customFieldColumns+` FROM custom_fields WHERE field_key = ?`, key)
+}
+
+func (r *CustomFieldRepository) getOne(query string, arg interface{}) (*CustomField, error) {
+	field, err := scanCustomField(r.DB.QueryRow(query, arg))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("custom field not found")
+		}
+		return nil, err
+	}
+	return field, nil
+}
+
+func scanCustomField(row rowScanner) (*CustomField, error) {
+	field := &CustomField{}
+	var options, pattern sql.NullString
+	var maxLength sql.NullInt64
+	var min, max sql.NullFloat64
+
+	err := row.Scan(
+		&field.ID,
+		&field.Key,
+		&field.Label,
+		&field.Type,
+		&field.Required,
+		&options,
+		&maxLength,
+		&pattern,
+		&min,
+		&max,
+		&field.DisplayOrder,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.String != "" {
+		if err := json.Unmarshal([]byte(options.String), &field.Options); err != nil {
+			return nil, fmt.Errorf("custom field %d has invalid options: %v", field.ID, err)
+		}
+	}
+	field.MaxLength = int(maxLength.Int64)
+	field.Pattern = pattern.String
+	if min.Valid {
+		field.Min = &min.Float64
+	}
+	if max.Valid {
+		field.Max = &max.Float64
+	}
+	return field, nil
+}
+
+// fieldArgs returns the values of the columns a create or update writes
+func fieldArgs(field *CustomField) ([]interface{}, error) {
+	var options sql.NullString
+	if len(field.Options) > 0 {
+		b, err := json.Marshal(field.Options)
+		if err != nil {
+			return nil, err
+		}
+		options = sql.NullString{String: string(b), Valid: true}
+	}
+	var min, max sql.NullFloat64
+	if field.Min != nil {
+		min = sql.NullFloat64{Float64: *field.Min, Valid: true}
+	}
+	if field.Max != nil {
+		max = sql.NullFloat64{Float64: *field.Max, Valid: true}
+	}
+	return []interface{}{
+		field.Label,
+		field.Required,
+		options,
+		nullInt(field.MaxLength),
+		sql.NullString{String: field.Pattern, Valid: field.Pattern != ""},
+		min,
+		max,
+		field.DisplayOrder,
+	}, nil
+}
+
+// Create adds a new custom field
+func (r *CustomFieldRepository) Create(field *CustomField) error {
+	args, err := fieldArgs(field)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO custom_fields (label, required, options, max_length, pattern,
+	          min_value, max_value, display_order, field_key, field_type)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := r.DB.Exec(query, append(args, field.Key, field.Type)...)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	field.ID = int(id)
+	return nil
+}
+
+// Update modifies a custom field's definition. Its key and type are kept.
+func (r *CustomFieldRepository) Update(id int, field *CustomField) error {
+	args, err := fieldArgs(field)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE custom_fields SET label = ?, required = ?, options = ?, max_length = ?,
+	          pattern = ?, min_value = ?, max_value = ?, display_order = ?
+	          WHERE id = ?`
+
+	_, err = r.DB.Exec(query, append(args, id)...)
+	return err
+}
+
+// Delete removes a custom field along with every contact's value for it
+func (r *CustomFieldRepository) Delete(id int) error {
+	_, err := r.DB.Exec(`DELETE FROM custom_fields WHERE id = ?`, id)
+	return err
+}
+
+// LoadCustomFields fills in the custom field values of contacts
+func (r *ContactRepository) LoadCustomFields(contacts ...*Contact) error {
+	if len(contacts) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*Contact, len(contacts))
+	args := make([]interface{}, 0, len(contacts))
+	for _, contact := range contacts {
+		byID[contact.ID] = contact
+		args = append(args, contact.ID)
+	}
+
+	query := `SELECT v.contact_id, f.field_key, f.field_type, v.value
+	          FROM contact_field_values v
+	          JOIN custom_fields f ON f.id = v.field_id
+	          WHERE v.contact_id IN (` + placeholders(len(args)) + `)`
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var contactID int
+		var key, fieldType, value string
+		if err := rows.Scan(&contactID, &key, &fieldType, &value); err != nil {
+			return err
+		}
+		contact := byID[contactID]
+		if contact.CustomFields == nil {
+			contact.CustomFields = make(map[string]interface{})
+		}
+		contact.CustomFields[key] = decodeFieldValue(fieldType, value)
+	}
+	return rows.Err()
+}
+
+// SetCustomFields saves a contact's custom field values, keyed by field ID
+// and normalized by CustomField.Normalize. Empty values are removed; fields
+// not in values are left alone.
+func (r *ContactRepository) SetCustomFields(contactID int, values map[int]string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	for fieldID, value := range values {
+		if value == "" {
+			_, err = tx.Exec(`DELETE FROM contact_field_values WHERE contact_id = ? AND field_id = ?`,
+				contactID, fieldID)
+		} else {
+			_, err = tx.Exec(`INSERT INTO contact_field_values (contact_id, field_id, value)
+			                  VALUES (?, ?, ?)
+			                  ON DUPLICATE KEY UPDATE value = VALUES(value)`,
+				contactID, fieldID, value)
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,81 @@
+package models
+
+import "testing"
+
+func TestCustomFieldNormalize(t *testing.T) {
+	min, max := 0.0, 120.0
+	tests := []struct {
+		field   CustomField
+		value   interface{}
+		want    string
+		wantErr bool
+	}{
+		{CustomField{Type: FieldText}, "  hello ", "hello", false},
+		{CustomField{Type: FieldText}, nil, "", false},
+		{CustomField{Type: FieldText}, "   ", "", false},
+		{CustomField{Type: FieldText}, 3.0, "", true},
+		{CustomField{Type: FieldText, MaxLength: 3}, "héllo", "", true},
+		{CustomField{Type: FieldText, Pattern: `[0-9]{3}`}, "123", "123", false},
+		{CustomField{Type: FieldText, Pattern: `[0-9]{3}`}, "1234", "", true},
+		{CustomField{Type: FieldNumber}, 42.0, "42", false},
+		{CustomField{Type: FieldNumber}, "2.50", "2.5", false},
+		{CustomField{Type: FieldNumber}, "many", "", true},
+		{CustomField{Type: FieldNumber, Min: &min, Max: &max}, 121.0, "", true},
+		{CustomField{Type: FieldNumber, Min: &min, Max: &max}, -1.0, "", true},
+		{CustomField{Type: FieldDate}, "2024-02-29", "2024-02-29", false},
+		{CustomField{Type: FieldDate}, "2023-02-29", "", true},
+		{CustomField{Type: FieldDate}, "02/01/2024", "", true},
+		{CustomField{Type: FieldSelect, Options: []string{"English", "Spanish"}}, "Spanish", "Spanish", false},
+		{CustomField{Type: FieldSelect, Options: []string{"English", "Spanish"}}, "spanish", "", true},
+		{CustomField{Type: FieldBoolean}, true, "true", false},
+		{CustomField{Type: FieldBoolean}, "FALSE", "false", false},
+		{CustomField{Type: FieldBoolean}, "yes", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.field.Normalize(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s Normalize(%#v) = %q, %v; want %q, error %v", tt.field.Type, tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCustomFieldValidate(t *testing.T) {
+	min, max := 10.0, 1.0
+	tests := []struct {
+		name  string
+		field CustomField
+		ok    bool
+	}{
+		{"text", CustomField{Key: "nickname", Label: "Nickname", Type: FieldText}, true},
+		{"select", CustomField{Key: "language", Label: "Language", Type: FieldSelect, Options: []string{"English"}}, true},
+		{"bad key", CustomField{Key: "Nick Name", Label: "Nickname", Type: FieldText}, false},
+		{"no label", CustomField{Key: "nickname", Type: FieldText}, false},
+		{"unknown type", CustomField{Key: "nickname", Label: "Nickname", Type: "email"}, false},
+		{"select without options", CustomField{Key: "language", Label: "Language", Type: FieldSelect}, false},
+		{"duplicate options", CustomField{Key: "language", Label: "Language", Type: FieldSelect, Options: []string{"a", "a"}}, false},
+		{"options on text", CustomField{Key: "nickname", Label: "Nickname", Type: FieldText, Options: []string{"a"}}, false},
+		{"bad pattern", CustomField{Key: "code", Label: "Code", Type: FieldText, Pattern: "("}, false},
+		{"min above max", CustomField{Key: "age", Label: "Age", Type: FieldNumber, Min: &min, Max: &max}, false},
+		{"min on date", CustomField{Key: "met", Label: "Met", Type: FieldDate, Min: &min}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.field.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate() = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestFieldFilterWhere(t *testing.T) {
+	filter := FieldFilter{Field: &CustomField{ID: 3, Type: FieldNumber}, From: "1", To: "5"}
+	where, args := filter.where()
+	want := "EXISTS (SELECT 1 FROM contact_field_values v WHERE v.contact_id = c.id AND v.field_id = ? AND " +
+		"CAST(v.value AS DECIMAL(65, 10)) >= ? AND CAST(v.value AS DECIMAL(65, 10)) <= ?)"
+	if where != want {
+		t.Errorf("where = %q, want %q", where, want)
+	}
+	if len(args) != 3 || args[0] != 3 || args[1] != "1" || args[2] != "5" {
+		t.Errorf("args = %v", args)
+	}
+}
@@ -0,0 +1,269 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Weights of the signals a duplicate score adds up. A shared email or phone
+// number alone is not enough, since families often share both; the same
+// name alone is.
+const (
+	duplicateEmailWeight = 0.45
+	duplicatePhoneWeight = 0.4
+	duplicateNameWeight  = 0.5
+
+	// minNameSimilarity is the similarity below which names count for nothing
+	minNameSimilarity = 0.8
+)
+
+// DuplicatePair is two contacts that may be the same person
+type DuplicatePair struct {
+	Contact   *Contact `json:"contact"`   // the one entered first, suggested to keep
+	Duplicate *Contact `json:"duplicate"` // suggested to merge into Contact
+	Score     float64  `json:"score"`     // 0 to 1
+	Reasons   []string `json:"reasons"`   // "email", "phone" and "name"
+}
+
+// NormalizePhone reduces a phone number to its last ten digits so formatting
+// and country codes do not matter. Numbers with fewer than seven digits are
+// not comparable and normalize to "".
+func NormalizePhone(phone string) string {
+	var digits []rune
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) < 7 {
+		return ""
+	}
+	if len(digits) > 10 {
+		digits = digits[len(digits)-10:]
+	}
+	return string(digits)
+}
+
+// NormalizeEmail trims and lowercases an email address
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// nameTokens lowercases a name and splits it into words, dropping punctuation
+func nameTokens(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// NameSimilarity compares two names from 0 (unrelated) to 1 (the same),
+// ignoring case, punctuation and word order
+func NameSimilarity(a, b string) float64 {
+	ta, tb := nameTokens(a), nameTokens(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	sort.Strings(ta)
+	sort.Strings(tb)
+	return jaroWinkler(strings.Join(ta, " "), strings.Join(tb, " "))
+}
+
+// jaroWinkler returns the Jaro-Winkler similarity of two strings
+func jaroWinkler(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	window := max(len(ra), len(rb))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		for j := max(0, i-window); j < min(len(rb), i+window+1); j++ {
+			if !matchedB[j] && ra[i] == rb[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, j := 0, 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions/2))/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// ScoreDuplicate scores how likely two contacts are the same person and says
+// which signals matched
+func ScoreDuplicate(a, b *Contact) (float64, []string) {
+	score := 0.0
+	reasons := []string{}
+
+	if email := NormalizeEmail(a.Email); email != "" && email == NormalizeEmail(b.Email) {
+		score += duplicateEmailWeight
+		reasons = append(reasons, "email")
+	}
+	if phone := NormalizePhone(a.Phone); phone != "" && phone == NormalizePhone(b.Phone) {
+		score += duplicatePhoneWeight
+		reasons = append(reasons, "phone")
+	}
+	if similarity := NameSimilarity(a.Name, b.Name); similarity >= minNameSimilarity {
+		score += duplicateNameWeight * similarity
+		reasons = append(reasons, "name")
+	}
+	return math.Round(min(score, 1)*100) / 100, reasons
+}
+
+// duplicateKeys returns the blocks a contact is compared within: its email,
+// its phone number and the start of each word of its name
+func duplicateKeys(contact *Contact) []string {
+	var keys []string
+	if email := NormalizeEmail(contact.Email); email != "" {
+		keys = append(keys, "e:"+email)
+	}
+	if phone := NormalizePhone(contact.Phone); phone != "" {
+		keys = append(keys, "p:"+phone)
+	}
+	for _, token := range nameTokens(contact.Name) {
+		if r := []rune(token); len(r) > 3 {
+			token = string(r[:3])
+		}
+		keys = append(keys, "n:"+token)
+	}
+	return keys
+}
+
+// FindDuplicates returns the pairs of contacts scoring at least minScore,
+// best first. Only contacts sharing an email, a phone number or the start of
+// a name word are compared, which keeps large lists fast.
+func FindDuplicates(contacts []*Contact, minScore float64) []DuplicatePair {
+	blocks := make(map[string][]int)
+	for i, contact := range contacts {
+		for _, key := range duplicateKeys(contact) {
+			blocks[key] = append(blocks[key], i)
+		}
+	}
+
+	compared := make(map[[2]int]bool)
+	var pairs []DuplicatePair
+	for _, block := range blocks {
+		for x := 0; x < len(block); x++ {
+			for y := x + 1; y < len(block); y++ {
+				i, j := block[x], block[y]
+				if i > j {
+					i, j = j, i
+				}
+				if i == j || compared[[2]int{i, j}] {
+					continue
+				}
+				compared[[2]int{i, j}] = true
+
+				score, reasons := ScoreDuplicate(contacts[i], contacts[j])
+				if score >= minScore && score > 0 {
+					// Suggest keeping the contact entered first
+					keep, duplicate := contacts[i], contacts[j]
+					if duplicate.DateAdded.Before(keep.DateAdded) {
+						keep, duplicate = duplicate, keep
+					}
+					pairs = append(pairs, DuplicatePair{
+						Contact:   keep,
+						Duplicate: duplicate,
+						Score:     score,
+						Reasons:   reasons,
+					})
+				}
+			}
+		}
+	}
+
+	sort.Slice(pairs, func(a, b int) bool {
+		if pairs[a].Score != pairs[b].Score {
+			return pairs[a].Score > pairs[b].Score
+		}
+		if pairs[a].Contact.ID != pairs[b].Contact.ID {
+			return pairs[a].Contact.ID < pairs[b].Contact.ID
+		}
+		return pairs[a].Duplicate.ID < pairs[b].Duplicate.ID
+	})
+	return pairs
+}
+
+// DuplicateMatch is a contact that may be the same person as another
+type DuplicateMatch struct {
+	Contact *Contact
+	Score   float64
+	Reasons []string
+}
+
+// DuplicateIndex finds the likely duplicates of new contacts among a set
+type DuplicateIndex struct {
+	contacts []*Contact
+	blocks   map[string][]int
+}
+
+// NewDuplicateIndex indexes contacts
+func NewDuplicateIndex(contacts []*Contact) *DuplicateIndex {
+	index := &DuplicateIndex{blocks: make(map[string][]int)}
+	for _, contact := range contacts {
+		index.Add(contact)
+	}
+	return index
+}
+
+// Add adds a contact to the index
+func (x *DuplicateIndex) Add(contact *Contact) {
+	for _, key := range duplicateKeys(contact) {
+		x.blocks[key] = append(x.blocks[key], len(x.contacts))
+	}
+	x.contacts = append(x.contacts, contact)
+}
+
+// Matches returns the indexed contacts scoring at least minScore against
+// contact, best first
+func (x *DuplicateIndex) Matches(contact *Contact, minScore float64) []DuplicateMatch {
+	seen := make(map[int]bool)
+	var matches []DuplicateMatch
+	for _, key := range duplicateKeys(contact) {
+		for _, i := range x.blocks[key] {
+			if seen[i] {
+				continue
+			}
+			seen[i] = true
+
+			score, reasons := ScoreDuplicate(contact, x.contacts[i])
+			if score >= minScore && score > 0 {
+				matches = append(matches, DuplicateMatch{Contact: x.contacts[i], Score: score, Reasons: reasons})
+			}
+		}
+	}
+	sort.SliceStable(matches, func(a, b int) bool { return matches[a].Score > matches[b].Score })
+	return matches
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := map[string]string{
+		"(555) 123-4567":  "5551234567",
+		"+1 555 123 4567": "5551234567",
+		"555.123.4567":    "5551234567",
+		"123-4567":        "1234567",
+		"12345":           "",
+		"":                "",
+	}
+	for phone, want := range tests {
+		if got := NormalizePhone(phone); got != want {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", phone, got, want)
+		}
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	if got := NameSimilarity("John Smith", "smith, john"); got != 1 {
+		t.Errorf("reordered names scored %v, want 1", got)
+	}
+	if got := NameSimilarity("Jon Smith", "John Smith"); got < minNameSimilarity {
+		t.Errorf("misspelled names scored %v, want at least %v", got, minNameSimilarity)
+	}
+	if got := NameSimilarity("Maria Garcia", "Peter Jones"); got >= minNameSimilarity {
+		t.Errorf("different names scored %v, want below %v", got, minNameSimilarity)
+	}
+	if got := NameSimilarity("", "Peter Jones"); got != 0 {
+		t.Errorf("empty name scored %v, want 0", got)
+	}
+}
+
+func TestScoreDuplicate(t *testing.T) {
+	a := &Contact{Name: "John Smith", Email: "John@Example.com ", Phone: "(555) 123-4567"}
+	b := &Contact{Name: "John Smith", Email: "john@example.com", Phone: "+1 555-123-4567"}
+	if score, reasons := ScoreDuplicate(a, b); score != 1 || !reflect.DeepEqual(reasons, []string{"email", "phone", "name"}) {
+		t.Errorf("identical contacts scored %v %v", score, reasons)
+	}
+
+	// A family sharing an email is not a duplicate on that alone
+	spouse := &Contact{Name: "Mary Smith", Email: "john@example.com"}
+	if score, _ := ScoreDuplicate(a, spouse); score >= 0.5 {
+		t.Errorf("shared email alone scored %v", score)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	now := time.Now()
+	contacts := []*Contact{
+		{ID: 1, Name: "Jon Smith", Phone: "555-123-4567", DateAdded: now},
+		{ID: 2, Name: "Maria Garcia", DateAdded: now},
+		{ID: 3, Name: "John Smith", Phone: "5551234567", DateAdded: now.Add(-time.Hour)},
+		{ID: 4, Name: "Peter Jones", DateAdded: now},
+	}
+
+	pairs := FindDuplicates(contacts, 0.5)
+	if len(pairs) != 1 {
+		t.Fatalf("got %d pairs, want 1", len(pairs))
+	}
+	// The earlier contact is suggested to keep
+	if pairs[0].Contact.ID != 3 || pairs[0].Duplicate.ID != 1 {
+		t.Errorf("pair = %d, %d; want 3, 1", pairs[0].Contact.ID, pairs[0].Duplicate.ID)
+	}
+}
+
+func TestMergeContacts(t *testing.T) {
+	earlier := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	target := &Contact{ID: 1, Name: "John Smith", Phone: "555-1234", Notes: "Met at outreach", DateAdded: earlier.AddDate(0, 1, 0)}
+	source := &Contact{ID: 2, Name: "Jon Smith", Email: "jon@example.com", Phone: "555-9999", Notes: "Likes music", GroupID: 7, DateAdded: earlier}
+
+	merged := mergeContacts(target, source)
+	if merged.Name != "John Smith" || merged.Phone != "555-1234" {
+		t.Errorf("target's values were replaced: %+v", merged)
+	}
+	if merged.Email != "jon@example.com" || merged.GroupID != 7 {
+		t.Errorf("gaps were not filled from the source: %+v", merged)
+	}
+	if !merged.DateAdded.Equal(earlier) {
+		t.Errorf("DateAdded = %v, want %v", merged.DateAdded, earlier)
+	}
+	if !strings.HasPrefix(merged.Notes, "Met at outreach\n\n") || !strings.HasSuffix(merged.Notes, "\nLikes music") {
+		t.Errorf("Notes = %q", merged.Notes)
+	}
+	if target.Email != "" {
+		t.Error("mergeContacts changed the target")
+	}
+
+	// Identical notes are not repeated
+	source.Notes = target.Notes
+	if merged := mergeContacts(target, source); merged.Notes != target.Notes {
+		t.Errorf("Notes = %q, want %q", merged.Notes, target.Notes)
+	}
+}
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Errors returned when correcting status history
+var (
+	ErrHistoryNotFound  = errors.New("status history entry not found")
+	ErrHistoryVoided    = errors.New("status history entry is voided")
+	ErrLastHistoryEntry = errors.New("a contact's last remaining status history entry cannot be voided")
+)
+
+const historyColumns = `h.id, h.contact_id, h.status_id, s.name, h.notes, h.date_changed,
+	h.voided, h.voided_at, h.voided_by, h.void_reason,
+	(SELECT COUNT(*) FROM contact_status_history_revisions v WHERE v.history_id = h.id)`
+
+// scanHistoryEntry scans a row selected with historyColumns
+func scanHistoryEntry(row rowScanner) (*StatusHistoryEntry, error) {
+	entry := &StatusHistoryEntry{}
+	var notes, voidReason sql.NullString
+	var voidedAt sql.NullTime
+	var voidedBy sql.NullInt64
+	err := row.Scan(&entry.ID, &entry.ContactID, &entry.StatusID, &entry.StatusName, &notes, &entry.DateChanged,
+		&entry.Voided, &voidedAt, &voidedBy, &voidReason, &entry.Revisions)
+	if err != nil {
+		return nil, err
+	}
+	entry.Notes = notes.String
+	entry.VoidReason = voidReason.String
+	entry.VoidedBy = int(voidedBy.Int64)
+	if voidedAt.Valid {
+		entry.VoidedAt = &voidedAt.Time
+	}
+	return entry, nil
+}
+
+// nullTime stores a zero time as NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// recomputeStatus sets a contact's current status from its latest entry by
+// date_changed that is not voided, keeping the status if there is none
+func recomputeStatus(tx *sql.Tx, contactID int) error {
+	_, err := tx.Exec(`UPDATE contacts
+	                   SET current_status_id = COALESCE((SELECT h.status_id FROM contact_status_history h
+	                                                     WHERE h.contact_id = contacts.id AND NOT h.voided
+	                                                     ORDER BY h.date_changed DESC, h.id DESC LIMIT 1),
+	                                                    current_status_id),
+	                   last_updated = NOW()
+	                   WHERE id = ?`, contactID)
+	return err
+}
+
+// GetHistoryEntry retrieves one of a contact's status history entries
+func (r *ContactRepository) GetHistoryEntry(contactID, entryID int) (*StatusHistoryEntry, error) {
+	row := r.DB.QueryRow(`SELECT `+historyColumns+`
+	                      FROM contact_status_history h
+	                      JOIN statuses s ON h.status_id = s.id
+	                      WHERE h.contact_id = ? AND h.id = ?`, contactID, entryID)
+	entry, err := scanHistoryEntry(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrHistoryNotFound
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// HistoryCorrection is a change to a status history entry. Zero values keep
+// what the entry has.
+type HistoryCorrection struct {
+	StatusID    int
+	Notes       *string
+	DateChanged time.Time
+	Reason      string // why the entry was corrected
+	RevisedBy   int    // the user correcting it
+}
+
+// CorrectHistoryEntry changes a status history entry, keeping the entry as
+// it was as a revision, and recomputes the contact's current status
+func (r *ContactRepository) CorrectHistoryEntry(contactID, entryID int, correction HistoryCorrection) error {
+	return r.reviseHistoryEntry(contactID, entryID, correction.RevisedBy, correction.Reason, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE contact_status_history
+		                   SET status_id = COALESCE(?, status_id), notes = COALESCE(?, notes),
+		                   date_changed = COALESCE(?, date_changed)
+		                   WHERE id = ?`,
+			nullInt(correction.StatusID), correction.Notes, nullTime(correction.DateChanged), entryID)
+		return err
+	})
+}
+
+// VoidHistoryEntry marks a status history entry as void, keeping the entry as
+// it was as a revision, and recomputes the contact's current status. A
+// contact's last entry that is not void cannot be voided.
+func (r *ContactRepository) VoidHistoryEntry(contactID, entryID, userID int, reason string) error {
+	return r.reviseHistoryEntry(contactID, entryID, userID, reason, func(tx *sql.Tx) error {
+		var others int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM contact_status_history
+		                    WHERE contact_id = ? AND id <> ? AND NOT voided`, contactID, entryID).Scan(&others)
+		if err != nil {
+			return err
+		}
+		if others == 0 {
+			return ErrLastHistoryEntry
+		}
+		_, err = tx.Exec(`UPDATE contact_status_history
+		                  SET voided = TRUE, voided_at = NOW(), voided_by = ?, void_reason = ?
+		                  WHERE id = ?`, nullInt(userID), reason, entryID)
+		return err
+	})
+}
+
+// reviseHistoryEntry copies an entry that is not void to its revisions, lets
+// change alter it and recomputes the contact's status, all in one transaction
+func (r *ContactRepository) reviseHistoryEntry(contactID, entryID, userID int, reason string, change func(tx *sql.Tx) error) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var voided bool
+	err = tx.QueryRow(`SELECT voided FROM contact_status_history WHERE id = ? AND contact_id = ? FOR UPDATE`,
+		entryID, contactID).Scan(&voided)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrHistoryNotFound
+		}
+		return err
+	}
+	if voided {
+		return ErrHistoryVoided
+	}
+
+	_, err = tx.Exec(`INSERT INTO contact_status_history_revisions
+	                  (history_id, status_id, notes, date_changed, voided, revised_by, reason)
+	                  SELECT id, status_id, notes, date_changed, voided, ?, ?
+	                  FROM contact_status_history WHERE id = ?`, nullInt(userID), reason, entryID)
+	if err != nil {
+		return err
+	}
+	if err := change(tx); err != nil {
+		return err
+	}
+	if err := recomputeStatus(tx, contactID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// StatusHistoryRevision is a status history entry as it was before an edit
+// or void
+type StatusHistoryRevision struct {
+	ID          int       `json:"id"`
+	HistoryID   int       `json:"history_id"`
+	StatusID    int       `json:"status_id"`
+	StatusName  string    `json:"status_name"`
+	Notes       string    `json:"notes,omitempty"`
+	DateChanged time.Time `json:"date_changed"`
+	Voided      bool      `json:"voided"`
+	RevisedBy   int       `json:"revised_by,omitempty"`
+	RevisedAt   time.Time `json:"revised_at"`
+	Reason      string    `json:"reason,omitempty"` // why it was revised
+}
+
+// GetHistoryRevisions lists the earlier versions of a status history entry,
+// oldest first
+func (r *ContactRepository) GetHistoryRevisions(entryID int) ([]*StatusHistoryRevision, error) {
+	rows, err := r.DB.Query(`SELECT v.id, v.history_id, v.status_id, s.name, v.notes, v.date_changed,
+	                         v.voided, v.revised_by, v.revised_at, v.reason
+	                         FROM contact_status_history_revisions v
+	                         JOIN statuses s ON v.status_id = s.id
+	                         WHERE v.history_id = ?
+	                         ORDER BY v.id`, entryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*StatusHistoryRevision{}
+	for rows.Next() {
+		revision := &StatusHistoryRevision{}
+		var notes, reason sql.NullString
+		var revisedBy sql.NullInt64
+		err := rows.Scan(&revision.ID, &revision.HistoryID, &revision.StatusID, &revision.StatusName, &notes,
+			&revision.DateChanged, &revision.Voided, &revisedBy, &revision.RevisedAt, &reason)
+		if err != nil {
+			return nil, err
+		}
+		revision.Notes = notes.String
+		revision.Reason = reason.String
+		revision.RevisedBy = int(revisedBy.Int64)
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// statusChange is when a contact moved to a status
+type statusChange struct {
+	StatusID int
+	At       time.Time
+}
+
+// StatusAsOf returns the status a contact had at a time, or now if at is
+// zero, and when it entered that status, according to its entries that are
+// not void. A contact with no history has had its current status since it
+// was added; one whose history starts later than at has no status, zero.
+func (r *ContactRepository) StatusAsOf(contact *Contact, at time.Time) (int, time.Time, error) {
+	rows, err := r.DB.Query(`SELECT status_id, date_changed FROM contact_status_history
+	                         WHERE contact_id = ? AND NOT voided
+	                         ORDER BY date_changed, id`, contact.ID)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer rows.Close()
+
+	var changes []statusChange
+	for rows.Next() {
+		var change statusChange
+		if err := rows.Scan(&change.StatusID, &change.At); err != nil {
+			return 0, time.Time{}, err
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, time.Time{}, err
+	}
+
+	if len(changes) == 0 {
+		return contact.CurrentStatusID, contact.DateAdded, nil
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	statusID, since := statusAt(changes, at)
+	return statusID, since, nil
+}
+
+// statusAt finds the status in effect at a time from changes in date order,
+// and when the run of changes to it that was in effect began. Repeated
+// entries for the same status, such as notes, do not restart the run.
+func statusAt(changes []statusChange, at time.Time) (int, time.Time) {
+	statusID, since := 0, time.Time{}
+	for _, change := range changes {
+		if change.At.After(at) {
+			break
+		}
+		if change.StatusID != statusID {
+			statusID, since = change.StatusID, change.At
+		}
+	}
+	return statusID, since
+}
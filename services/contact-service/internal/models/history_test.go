@@ -0,0 +1,57 @@
+package models
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestStatusAt(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 12, 0, 0, 0, time.UTC) }
+	changes := []statusChange{
+		{1, day(1)},
+		{2, day(5)},
+		{2, day(8)}, // a note, not a new run
+		{3, day(12)},
+		{2, day(20)},
+	}
+
+	tests := []struct {
+		at     time.Time
+		status int
+		since  time.Time
+	}{
+		{day(1).Add(-time.Hour), 0, time.Time{}},
+		{day(1), 1, day(1)},
+		{day(10), 2, day(5)},
+		{day(15), 3, day(12)},
+		{day(25), 2, day(20)},
+	}
+	for _, tt := range tests {
+		status, since := statusAt(changes, tt.at)
+		if status != tt.status || !since.Equal(tt.since) {
+			t.Errorf("statusAt(%s) = %d since %s, want %d since %s",
+				tt.at.Format(time.RFC3339), status, since.Format(time.RFC3339), tt.status, tt.since.Format(time.RFC3339))
+		}
+	}
+}
+
+func TestStatusAtAfterMerge(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 12, 0, 0, 0, time.UTC) }
+	target := []statusChange{{1, day(1)}, {2, day(6)}}
+	duplicate := []statusChange{{1, day(3)}, {2, day(4)}, {3, day(9)}}
+
+	// Merging moves the duplicate's entries onto the kept contact, whose status
+	// is then recomputed from the combined history in date order
+	changes := append(append([]statusChange{}, target...), duplicate...)
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].At.Before(changes[j].At) })
+
+	if status, since := statusAt(changes, day(20)); status != 3 || !since.Equal(day(9)) {
+		t.Errorf("status after merge = %d since %s, want 3 since %s",
+			status, since.Format(time.RFC3339), day(9).Format(time.RFC3339))
+	}
+	if status, since := statusAt(changes, day(7)); status != 2 || !since.Equal(day(4)) {
+		t.Errorf("status on day 7 = %d since %s, want 2 since %s",
+			status, since.Format(time.RFC3339), day(4).Format(time.RFC3339))
+	}
+}
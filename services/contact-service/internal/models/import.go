@@ -0,0 +1,83 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// ImportedContact is a validated contact ready to be saved by Import
+type ImportedContact struct {
+	Contact     *Contact
+	FieldValues map[int]string // normalized custom field values by field ID
+	TagNames    []string       // created if they do not exist
+}
+
+// Import saves contacts in a single transaction, so either every contact is
+// imported or none is. Each gets an "Imported" status history entry.
+func (r *ContactRepository) Import(contacts []*ImportedContact) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tagIDs := make(map[string]int) // by lowercased name
+	for _, imported := range contacts {
+		contact := imported.Contact
+		result, err := tx.Exec(`INSERT INTO contacts (name, email, phone, location, notes, current_status_id,
+		                        owner_id, assigned_worker_id, group_id)
+		                        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			contact.Name, contact.Email, contact.Phone, contact.Location, contact.Notes, contact.CurrentStatusID,
+			nullInt(contact.OwnerID), nullInt(contact.AssignedWorkerID), nullInt(contact.GroupID))
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		contact.ID = int(id)
+
+		_, err = tx.Exec(`INSERT INTO contact_status_history (contact_id, status_id, notes) VALUES (?, ?, ?)`,
+			contact.ID, contact.CurrentStatusID, "Imported")
+		if err != nil {
+			return err
+		}
+
+		for fieldID, value := range imported.FieldValues {
+			if value == "" {
+				continue
+			}
+			_, err = tx.Exec(`INSERT INTO contact_field_values (contact_id, field_id, value) VALUES (?, ?, ?)`,
+				contact.ID, fieldID, value)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, name := range imported.TagNames {
+			tagID, ok := tagIDs[strings.ToLower(name)]
+			if !ok {
+				if tagID, err = ensureTag(tx, name); err != nil {
+					return err
+				}
+				tagIDs[strings.ToLower(name)] = tagID
+			}
+			if _, err = tx.Exec(`INSERT IGNORE INTO contact_tags (contact_id, tag_id) VALUES (?, ?)`, contact.ID, tagID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ensureTag returns the ID of the tag named name, creating it if needed
+func ensureTag(tx *sql.Tx, name string) (int, error) {
+	if _, err := tx.Exec(`INSERT IGNORE INTO tags (name) VALUES (?)`, name); err != nil {
+		return 0, err
+	}
+	var id int
+	err := tx.QueryRow(`SELECT id FROM tags WHERE name = ?`, name).Scan(&id)
+	return id, err
+}
@@ -0,0 +1,327 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+)
+
+// Interaction types
+const (
+	InteractionCall    = "call"
+	InteractionVisit   = "visit"
+	InteractionMessage = "message"
+	InteractionNote    = "note"
+)
+
+// InteractionTypes lists the known interaction types
+var InteractionTypes = []string{InteractionCall, InteractionVisit, InteractionMessage, InteractionNote}
+
+// Errors returned when revising interactions
+var (
+	ErrInteractionNotFound = errors.New("interaction not found")
+	ErrInteractionVoided   = errors.New("interaction is voided")
+)
+
+// Interaction is an entry in a contact's log of calls, visits and messages.
+// The log is append-only: edits keep the entry as it was as a revision, and
+// entries are voided rather than deleted.
+type Interaction struct {
+	ID              int        `json:"id"`
+	ContactID       int        `json:"contact_id"`
+	Type            string     `json:"type"`
+	OccurredAt      time.Time  `json:"occurred_at"`
+	AuthorID        int        `json:"author_id,omitempty"`
+	DurationMinutes int        `json:"duration_minutes,omitempty"`
+	Body            string     `json:"body"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	Voided          bool       `json:"voided"`
+	VoidedAt        *time.Time `json:"voided_at,omitempty"`
+	VoidedBy        int        `json:"voided_by,omitempty"`
+	VoidReason      string     `json:"void_reason,omitempty"`
+	Revisions       int        `json:"revisions"` // earlier versions kept by edits and voids
+}
+
+// InteractionEdit is a change to an interaction. A zero OccurredAt keeps the
+// time it has.
+type InteractionEdit struct {
+	Type            string
+	OccurredAt      time.Time
+	DurationMinutes int
+	Body            string
+	Reason          string // why the interaction was edited
+	RevisedBy       int    // the user editing it
+}
+
+// InteractionRevision is an interaction as it was before an edit or void
+type InteractionRevision struct {
+	ID              int       `json:"id"`
+	InteractionID   int       `json:"interaction_id"`
+	Type            string    `json:"type"`
+	OccurredAt      time.Time `json:"occurred_at"`
+	DurationMinutes int       `json:"duration_minutes,omitempty"`
+	Body            string    `json:"body"`
+	Voided          bool      `json:"voided"`
+	RevisedBy       int       `json:"revised_by,omitempty"`
+	RevisedAt       time.Time `json:"revised_at"`
+	Reason          string    `json:"reason,omitempty"` // why it was revised
+}
+
+// revision returns the interaction as it is, to keep before revising it
+func (i *Interaction) revision(revisedBy int, reason string) *InteractionRevision {
+	return &InteractionRevision{
+		InteractionID:   i.ID,
+		Type:            i.Type,
+		OccurredAt:      i.OccurredAt,
+		DurationMinutes: i.DurationMinutes,
+		Body:            i.Body,
+		Voided:          i.Voided,
+		RevisedBy:       revisedBy,
+		Reason:          reason,
+	}
+}
+
+// edited returns a copy of the interaction with edit applied
+func (i *Interaction) edited(edit InteractionEdit) *Interaction {
+	edited := *i
+	edited.Type = edit.Type
+	edited.DurationMinutes = edit.DurationMinutes
+	edited.Body = edit.Body
+	if !edit.OccurredAt.IsZero() {
+		edited.OccurredAt = edit.OccurredAt
+	}
+	return &edited
+}
+
+// InteractionRepository provides access to contacts' interaction logs
+type InteractionRepository struct {
+	DB *sql.DB
+}
+
+// NewInteractionRepository creates a new InteractionRepository
+func NewInteractionRepository(db *sql.DB) *InteractionRepository {
+	return &InteractionRepository{DB: db}
+}
+
+const interactionColumns = `i.id, i.contact_id, i.interaction_type, i.occurred_at, i.author_id, i.duration_minutes,
+	i.body, i.created_at, i.updated_at, i.voided, i.voided_at, i.voided_by, i.void_reason,
+	(SELECT COUNT(*) FROM interaction_revisions v WHERE v.interaction_id = i.id)`
+
+// scanInteraction scans a row selected with interactionColumns
+func scanInteraction(row rowScanner) (*Interaction, error) {
+	interaction := &Interaction{}
+	var authorID, duration, voidedBy sql.NullInt64
+	var voidReason sql.NullString
+	var voidedAt sql.NullTime
+	err := row.Scan(&interaction.ID, &interaction.ContactID, &interaction.Type, &interaction.OccurredAt, &authorID,
+		&duration, &interaction.Body, &interaction.CreatedAt, &interaction.UpdatedAt, &interaction.Voided,
+		&voidedAt, &voidedBy, &voidReason, &interaction.Revisions)
+	if err != nil {
+		return nil, err
+	}
+	interaction.AuthorID = int(authorID.Int64)
+	interaction.DurationMinutes = int(duration.Int64)
+	interaction.VoidedBy = int(voidedBy.Int64)
+	interaction.VoidReason = voidReason.String
+	if voidedAt.Valid {
+		interaction.VoidedAt = &voidedAt.Time
+	}
+	return interaction, nil
+}
+
+// GetAll retrieves a contact's interactions that are not void, latest first
+func (r *InteractionRepository) GetAll(contactID int) ([]*Interaction, error) {
+	rows, err := r.DB.Query(`SELECT `+interactionColumns+`
+	                         FROM interactions i
+	                         WHERE i.contact_id = ? AND NOT i.voided
+	                         ORDER BY i.occurred_at DESC, i.id DESC`, contactID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	interactions := []*Interaction{}
+	for rows.Next() {
+		interaction, err := scanInteraction(rows)
+		if err != nil {
+			return nil, err
+		}
+		interactions = append(interactions, interaction)
+	}
+	return interactions, rows.Err()
+}
+
+// GetByID retrieves one of a contact's interactions, void or not
+func (r *InteractionRepository) GetByID(contactID, id int) (*Interaction, error) {
+	row := r.DB.QueryRow(`SELECT `+interactionColumns+` FROM interactions i WHERE i.contact_id = ? AND i.id = ?`,
+		contactID, id)
+	interaction, err := scanInteraction(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInteractionNotFound
+		}
+		return nil, err
+	}
+	return interaction, nil
+}
+
+// Create adds an interaction. A zero OccurredAt means now.
+func (r *InteractionRepository) Create(interaction *Interaction) error {
+	result, err := r.DB.Exec(`INSERT INTO interactions (contact_id, interaction_type, occurred_at, author_id,
+	                          duration_minutes, body)
+	                          VALUES (?, ?, COALESCE(?, CURRENT_TIMESTAMP), ?, ?, ?)`,
+		interaction.ContactID, interaction.Type, nullTime(interaction.OccurredAt), nullInt(interaction.AuthorID),
+		nullInt(interaction.DurationMinutes), interaction.Body)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	interaction.ID = int(id)
+	return nil
+}
+
+// Edit changes an interaction's type, time, duration and body, keeping the
+// interaction as it was as a revision
+func (r *InteractionRepository) Edit(contactID, id int, edit InteractionEdit) error {
+	return r.revise(contactID, id, edit.RevisedBy, edit.Reason, func(tx *sql.Tx, old *Interaction) error {
+		edited := old.edited(edit)
+		_, err := tx.Exec(`UPDATE interactions
+		                   SET interaction_type = ?, occurred_at = ?, duration_minutes = ?, body = ?
+		                   WHERE id = ?`,
+			edited.Type, edited.OccurredAt, nullInt(edited.DurationMinutes), edited.Body, id)
+		return err
+	})
+}
+
+// Void marks an interaction as void, keeping the interaction as it was as a
+// revision. Void interactions are left out of the log and timeline.
+func (r *InteractionRepository) Void(contactID, id, userID int, reason string) error {
+	return r.revise(contactID, id, userID, reason, func(tx *sql.Tx, old *Interaction) error {
+		_, err := tx.Exec(`UPDATE interactions
+		                   SET voided = TRUE, voided_at = NOW(), voided_by = ?, void_reason = ?
+		                   WHERE id = ?`, nullInt(userID), reason, id)
+		return err
+	})
+}
+
+// revise copies an interaction that is not void to its revisions and lets
+// change alter it, all in one transaction
+func (r *InteractionRepository) revise(contactID, id, userID int, reason string, change func(tx *sql.Tx, old *Interaction) error) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRow(`SELECT `+interactionColumns+` FROM interactions i
+	                    WHERE i.contact_id = ? AND i.id = ? FOR UPDATE`, contactID, id)
+	old, err := scanInteraction(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInteractionNotFound
+		}
+		return err
+	}
+	if old.Voided {
+		return ErrInteractionVoided
+	}
+
+	revision := old.revision(userID, reason)
+	_, err = tx.Exec(`INSERT INTO interaction_revisions
+	                  (interaction_id, interaction_type, occurred_at, duration_minutes, body, voided, revised_by, reason)
+	                  VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		revision.InteractionID, revision.Type, revision.OccurredAt, nullInt(revision.DurationMinutes), revision.Body,
+		revision.Voided, nullInt(revision.RevisedBy), revision.Reason)
+	if err != nil {
+		return err
+	}
+	if err := change(tx, old); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetRevisions lists the earlier versions of an interaction, oldest first
+func (r *InteractionRepository) GetRevisions(id int) ([]*InteractionRevision, error) {
+	rows, err := r.DB.Query(`SELECT id, interaction_id, interaction_type, occurred_at, duration_minutes, body,
+	                         voided, revised_by, revised_at, reason
+	                         FROM interaction_revisions
+	                         WHERE interaction_id = ?
+	                         ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*InteractionRevision{}
+	for rows.Next() {
+		revision := &InteractionRevision{}
+		var duration, revisedBy sql.NullInt64
+		var reason sql.NullString
+		err := rows.Scan(&revision.ID, &revision.InteractionID, &revision.Type, &revision.OccurredAt, &duration,
+			&revision.Body, &revision.Voided, &revisedBy, &revision.RevisedAt, &reason)
+		if err != nil {
+			return nil, err
+		}
+		revision.DurationMinutes = int(duration.Int64)
+		revision.RevisedBy = int(revisedBy.Int64)
+		revision.Reason = reason.String
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// Timeline entry kinds
+const (
+	TimelineInteraction  = "interaction"
+	TimelineStatusChange = "status_change"
+)
+
+// TimelineEntry is an interaction or a status change in a contact's timeline.
+// Exactly one of Interaction and StatusChange is set, as Kind says.
+type TimelineEntry struct {
+	Kind         string              `json:"kind"`
+	At           time.Time           `json:"at"`
+	Interaction  *Interaction        `json:"interaction,omitempty"`
+	StatusChange *StatusHistoryEntry `json:"status_change,omitempty"`
+}
+
+// mergeTimeline interleaves interactions and status history, both latest
+// first, into one timeline, latest first. Void entries of either kind are
+// left out.
+func mergeTimeline(interactions []*Interaction, history []*StatusHistoryEntry) []TimelineEntry {
+	timeline := make([]TimelineEntry, 0, len(interactions)+len(history))
+	for _, interaction := range interactions {
+		if !interaction.Voided {
+			timeline = append(timeline, TimelineEntry{Kind: TimelineInteraction, At: interaction.OccurredAt, Interaction: interaction})
+		}
+	}
+	for _, entry := range history {
+		if !entry.Voided {
+			timeline = append(timeline, TimelineEntry{Kind: TimelineStatusChange, At: entry.DateChanged, StatusChange: entry})
+		}
+	}
+	sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].At.After(timeline[j].At) })
+	return timeline
+}
+
+// Timeline retrieves a contact's interactions and status changes that are not
+// void, latest first
+func (r *InteractionRepository) Timeline(contactID int) ([]TimelineEntry, error) {
+	interactions, err := r.GetAll(contactID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := NewContactRepository(r.DB).GetStatusHistory(contactID)
+	if err != nil {
+		return nil, err
+	}
+	return mergeTimeline(interactions, history), nil
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeTimeline(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	interactions := []*Interaction{
+		{ID: 4, OccurredAt: day(10), Voided: true},
+		{ID: 3, OccurredAt: day(9)},
+		{ID: 2, OccurredAt: day(5)},
+		{ID: 1, OccurredAt: day(1)},
+	}
+	history := []*StatusHistoryEntry{
+		{ID: 12, DateChanged: day(7)},
+		{ID: 11, DateChanged: day(5), Voided: true},
+		{ID: 10, DateChanged: day(1)},
+	}
+
+	timeline := mergeTimeline(interactions, history)
+
+	want := []struct {
+		kind string
+		id   int
+	}{
+		{TimelineInteraction, 3},
+		{TimelineStatusChange, 12},
+		{TimelineInteraction, 2},
+		{TimelineInteraction, 1},
+		{TimelineStatusChange, 10},
+	}
+	if len(timeline) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(timeline), len(want))
+	}
+	for i, entry := range timeline {
+		var id int
+		if entry.Interaction != nil {
+			id = entry.Interaction.ID
+		} else {
+			id = entry.StatusChange.ID
+		}
+		if entry.Kind != want[i].kind || id != want[i].id {
+			t.Errorf("entry %d = %s %d, want %s %d", i, entry.Kind, id, want[i].kind, want[i].id)
+		}
+	}
+}
+
+func TestInteractionEditKeepsOldVersion(t *testing.T) {
+	logged := time.Date(2024, 3, 1, 18, 30, 0, 0, time.UTC)
+	old := &Interaction{ID: 7, ContactID: 42, AuthorID: 3, Type: InteractionCall, OccurredAt: logged,
+		DurationMinutes: 20, Body: "Asked about the Saturday study"}
+
+	revision := old.revision(5, "wrong length")
+	edited := old.edited(InteractionEdit{Type: InteractionVisit, DurationMinutes: 45, Body: "Visited at home"})
+
+	if revision.InteractionID != 7 || revision.Type != InteractionCall || !revision.OccurredAt.Equal(logged) ||
+		revision.DurationMinutes != 20 || revision.Body != "Asked about the Saturday study" {
+		t.Errorf("revision = %+v, want the interaction as it was", revision)
+	}
+	if revision.RevisedBy != 5 || revision.Reason != "wrong length" {
+		t.Errorf("revision by %d for %q, want 5 for %q", revision.RevisedBy, revision.Reason, "wrong length")
+	}
+	if edited.Type != InteractionVisit || edited.DurationMinutes != 45 || edited.Body != "Visited at home" {
+		t.Errorf("edited = %+v, want the edit applied", edited)
+	}
+	if !edited.OccurredAt.Equal(logged) || edited.AuthorID != 3 {
+		t.Errorf("edited = %+v, want the time and author kept", edited)
+	}
+	if old.Body != "Asked about the Saturday study" || old.DurationMinutes != 20 {
+		t.Errorf("old = %+v, changed by the edit", old)
+	}
+}
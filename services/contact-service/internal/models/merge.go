@@ -0,0 +1,168 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ContactReference is a column in another service's database that refers to
+// contacts by ID. contact-service cannot update them itself.
+type ContactReference struct {
+	Service string `json:"service"`
+	Table   string `json:"table"`
+	Column  string `json:"column"`
+}
+
+// ContactReferences lists every reference to contacts outside this service
+var ContactReferences = []ContactReference{
+	{Service: "study-service", Table: "studies", Column: "contact_id"},
+	{Service: "reservation-service", Table: "reservations", Column: "contact_id"},
+}
+
+// MergeResult describes a completed merge
+type MergeResult struct {
+	Contact                *Contact           `json:"contact"`
+	MergedContactID        int                `json:"merged_contact_id"`         // deleted
+	StatusHistoryMoved     int64              `json:"status_history_moved"`      // entries re-pointed to Contact
+	RepointInOtherServices []ContactReference `json:"repoint_in_other_services"` // still referring to MergedContactID
+}
+
+// mergeContacts returns target with the gaps filled in from source: empty
+// contact details and assignments are taken from source, notes are combined
+// and the earlier date added is kept. The name and owner are target's; Merge
+// sets the status from the combined status history.
+func mergeContacts(target, source *Contact) *Contact {
+	merged := *target
+	if merged.Email == "" {
+		merged.Email = source.Email
+	}
+	if merged.Phone == "" {
+		merged.Phone = source.Phone
+	}
+	if merged.Location == "" {
+		merged.Location = source.Location
+	}
+	if merged.AssignedWorkerID == 0 {
+		merged.AssignedWorkerID = source.AssignedWorkerID
+	}
+	if merged.GroupID == 0 {
+		merged.GroupID = source.GroupID
+	}
+	if source.DateAdded.Before(merged.DateAdded) {
+		merged.DateAdded = source.DateAdded
+	}
+
+	sourceNotes := strings.TrimSpace(source.Notes)
+	switch {
+	case sourceNotes == "" || sourceNotes == strings.TrimSpace(merged.Notes):
+	case strings.TrimSpace(merged.Notes) == "":
+		merged.Notes = source.Notes
+	default:
+		merged.Notes = fmt.Sprintf("%s\n\n--- Merged from contact #%d (%s) ---\n%s",
+			strings.TrimRight(merged.Notes, "\n"), source.ID, source.Name, sourceNotes)
+	}
+	return &merged
+}
+
+// Merge folds the contact sourceID into targetID and deletes it. Status
+// history, tasks and interactions move to the target, and custom field values
+// and tags the target lacks are copied over. References in other services are
+// not touched; see ContactReferences.
+func (r *ContactRepository) Merge(targetID, sourceID int) (*MergeResult, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock both contacts, in ID order so concurrent merges cannot deadlock
+	query := `SELECT ` + contactColumns + ` FROM contacts c WHERE c.id IN (?, ?) ORDER BY c.id FOR UPDATE`
+	rows, err := tx.Query(query, targetID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	var target, source *Contact
+	for rows.Next() {
+		contact, err := scanContact(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if contact.ID == targetID {
+			target = contact
+		} else {
+			source = contact
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if target == nil || source == nil {
+		return nil, errors.New("contact not found")
+	}
+
+	merged := mergeContacts(target, source)
+	_, err = tx.Exec(`UPDATE contacts
+	                  SET email = ?, phone = ?, location = ?, notes = ?, date_added = ?,
+	                  assigned_worker_id = ?, group_id = ?, last_updated = NOW()
+	                  WHERE id = ?`,
+		merged.Email, merged.Phone, merged.Location, merged.Notes, merged.DateAdded,
+		nullInt(merged.AssignedWorkerID), nullInt(merged.GroupID), targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`UPDATE contact_status_history SET contact_id = ? WHERE contact_id = ?`, targetID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	moved, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec(`UPDATE tasks SET contact_id = ? WHERE contact_id = ?`, targetID, sourceID); err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec(`UPDATE interactions SET contact_id = ? WHERE contact_id = ?`, targetID, sourceID); err != nil {
+		return nil, err
+	}
+
+	// Where both have a value for a field the target's wins; the source's is
+	// deleted with it
+	_, err = tx.Exec(`INSERT IGNORE INTO contact_field_values (contact_id, field_id, value)
+	                  SELECT ?, field_id, value FROM contact_field_values WHERE contact_id = ?`, targetID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`INSERT IGNORE INTO contact_tags (contact_id, tag_id, tagged_at)
+	                  SELECT ?, tag_id, tagged_at FROM contact_tags WHERE contact_id = ?`, targetID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec(`DELETE FROM contacts WHERE id = ?`, sourceID); err != nil {
+		return nil, err
+	}
+
+	// The moved history may hold the latest change, so the status follows the
+	// combined history
+	if err := recomputeStatus(tx, targetID); err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(`SELECT current_status_id FROM contacts WHERE id = ?`, targetID).Scan(&merged.CurrentStatusID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &MergeResult{
+		Contact:                merged,
+		MergedContactID:        sourceID,
+		StatusHistoryMoved:     moved,
+		RepointInOtherServices: ContactReferences,
+	}, nil
+}
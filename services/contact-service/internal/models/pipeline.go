@@ -0,0 +1,239 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// PipelineReport describes how contacts move through the statuses, in status
+// display order
+type PipelineReport struct {
+	Total       int                  `json:"total"`
+	Statuses    []PipelineStatus     `json:"statuses"`
+	Conversions []PipelineConversion `json:"conversions"`
+	Cohorts     []PipelineCohort     `json:"cohorts"`
+}
+
+// PipelineStatus counts the contacts in and through a status
+type PipelineStatus struct {
+	StatusID     int           `json:"status_id"`
+	Name         string        `json:"name"`
+	Current      int           `json:"current"` // contacts now in the status
+	Reached      int           `json:"reached"` // contacts that reached the status or a later one
+	DaysInStatus DurationStats `json:"days_in_status"`
+}
+
+// DurationStats summarizes completed stays in a status, in days. Median and
+// P90 are null when there are no stays.
+type DurationStats struct {
+	Stays  int      `json:"stays"`
+	Median *float64 `json:"median"`
+	P90    *float64 `json:"p90"`
+}
+
+// PipelineConversion is the share of contacts that reached a status and went
+// on to reach the next one. Rate is null when none reached the first.
+type PipelineConversion struct {
+	FromStatusID int      `json:"from_status_id"`
+	ToStatusID   int      `json:"to_status_id"`
+	Rate         *float64 `json:"rate"`
+}
+
+// PipelineCohort follows the contacts added in one month
+type PipelineCohort struct {
+	Month   string        `json:"month"` // YYYY-MM, UTC
+	Added   int           `json:"added"`
+	Reached []CohortCount `json:"reached"`
+}
+
+// CohortCount is how many of a cohort reached a status or a later one
+type CohortCount struct {
+	StatusID int     `json:"status_id"`
+	Count    int     `json:"count"`
+	Rate     float64 `json:"rate"` // of the cohort
+}
+
+// pipelineContact is a contact with its status changes in date order
+type pipelineContact struct {
+	ID              int
+	CurrentStatusID int
+	DateAdded       time.Time
+	Changes         []statusChange
+}
+
+// Pipeline reports on the contacts visible in scope that match filter. Voided
+// status history entries are left out.
+func (r *ContactRepository) Pipeline(scope ContactScope, filter ContactFilter) (*PipelineReport, error) {
+	statuses, err := NewStatusRepository(r.DB).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := contactsWhere(scope, filter)
+	rows, err := r.DB.Query(`SELECT c.id, c.current_status_id, c.date_added FROM contacts c WHERE `+where+`
+	                         ORDER BY c.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contacts []*pipelineContact
+	byID := make(map[int]*pipelineContact)
+	for rows.Next() {
+		contact := &pipelineContact{}
+		if err := rows.Scan(&contact.ID, &contact.CurrentStatusID, &contact.DateAdded); err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, contact)
+		byID[contact.ID] = contact
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	historyRows, err := r.DB.Query(`SELECT h.contact_id, h.status_id, h.date_changed
+	                                FROM contact_status_history h
+	                                JOIN contacts c ON c.id = h.contact_id
+	                                WHERE NOT h.voided AND `+where+`
+	                                ORDER BY h.contact_id, h.date_changed, h.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer historyRows.Close()
+	for historyRows.Next() {
+		var contactID int
+		var change statusChange
+		if err := historyRows.Scan(&contactID, &change.StatusID, &change.At); err != nil {
+			return nil, err
+		}
+		if contact, ok := byID[contactID]; ok {
+			contact.Changes = append(contact.Changes, change)
+		}
+	}
+	if err := historyRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return buildPipeline(statuses, contacts), nil
+}
+
+// buildPipeline computes the report. A contact has reached every status up to
+// the furthest one in its history or its current status, so contacts that
+// skipped a status still count as having passed through it.
+func buildPipeline(statuses []*Status, contacts []*pipelineContact) *PipelineReport {
+	position := make(map[int]int, len(statuses))
+	for i, status := range statuses {
+		position[status.ID] = i
+	}
+
+	report := &PipelineReport{
+		Total:       len(contacts),
+		Statuses:    make([]PipelineStatus, len(statuses)),
+		Conversions: []PipelineConversion{},
+		Cohorts:     []PipelineCohort{},
+	}
+	for i, status := range statuses {
+		report.Statuses[i] = PipelineStatus{StatusID: status.ID, Name: status.Name}
+	}
+
+	stays := make([][]float64, len(statuses))
+	cohorts := make(map[string]*PipelineCohort)
+	for _, contact := range contacts {
+		// Where the contact is and how far it has got
+		furthest := -1
+		if i, ok := position[contact.CurrentStatusID]; ok {
+			report.Statuses[i].Current++
+			furthest = i
+		}
+		for _, change := range contact.Changes {
+			if i, ok := position[change.StatusID]; ok && i > furthest {
+				furthest = i
+			}
+		}
+		for i := 0; i <= furthest; i++ {
+			report.Statuses[i].Reached++
+		}
+
+		// How long each finished stay lasted; repeated entries for the same
+		// status, such as notes, continue a stay
+		if len(contact.Changes) > 0 {
+			start := contact.Changes[0]
+			for _, change := range contact.Changes[1:] {
+				if change.StatusID == start.StatusID {
+					continue
+				}
+				if i, ok := position[start.StatusID]; ok {
+					stays[i] = append(stays[i], change.At.Sub(start.At).Hours()/24)
+				}
+				start = change
+			}
+		}
+
+		// Which cohort the contact belongs to
+		month := contact.DateAdded.UTC().Format("2006-01")
+		cohort, ok := cohorts[month]
+		if !ok {
+			cohort = &PipelineCohort{Month: month, Reached: make([]CohortCount, len(statuses))}
+			for i, status := range statuses {
+				cohort.Reached[i].StatusID = status.ID
+			}
+			cohorts[month] = cohort
+		}
+		cohort.Added++
+		for i := 0; i <= furthest; i++ {
+			cohort.Reached[i].Count++
+		}
+	}
+
+	for i := range report.Statuses {
+		report.Statuses[i].DaysInStatus = durationStats(stays[i])
+		if i == 0 {
+			continue
+		}
+		conversion := PipelineConversion{FromStatusID: statuses[i-1].ID, ToStatusID: statuses[i].ID}
+		if from := report.Statuses[i-1].Reached; from > 0 {
+			rate := round(float64(report.Statuses[i].Reached)/float64(from), 3)
+			conversion.Rate = &rate
+		}
+		report.Conversions = append(report.Conversions, conversion)
+	}
+
+	for _, cohort := range cohorts {
+		for i := range cohort.Reached {
+			cohort.Reached[i].Rate = round(float64(cohort.Reached[i].Count)/float64(cohort.Added), 3)
+		}
+		report.Cohorts = append(report.Cohorts, *cohort)
+	}
+	sort.Slice(report.Cohorts, func(i, j int) bool { return report.Cohorts[i].Month < report.Cohorts[j].Month })
+	return report
+}
+
+// durationStats summarizes stays, in days
+func durationStats(days []float64) DurationStats {
+	stats := DurationStats{Stays: len(days)}
+	if len(days) == 0 {
+		return stats
+	}
+	sort.Float64s(days)
+	median := round(percentile(days, 0.5), 1)
+	p90 := round(percentile(days, 0.9), 1)
+	stats.Median, stats.P90 = &median, &p90
+	return stats
+}
+
+// percentile interpolates the pth percentile, 0 to 1, of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	if lower+1 >= len(sorted) {
+		return sorted[lower]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// round rounds x to a number of decimal places
+func round(x float64, places int) float64 {
+	scale := math.Pow10(places)
+	return math.Round(x*scale) / scale
+}
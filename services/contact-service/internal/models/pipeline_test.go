@@ -0,0 +1,64 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildPipeline(t *testing.T) {
+	statuses := []*Status{{ID: 1, Name: "New Contact"}, {ID: 2, Name: "In Studies"}, {ID: 3, Name: "Baptized"}}
+	day := func(month time.Month, d int) time.Time { return time.Date(2024, month, d, 12, 0, 0, 0, time.UTC) }
+	contacts := []*pipelineContact{
+		{ID: 1, CurrentStatusID: 3, DateAdded: day(1, 1), Changes: []statusChange{
+			{1, day(1, 1)}, {2, day(1, 11)}, {2, day(1, 20)}, {3, day(2, 10)},
+		}},
+		{ID: 2, CurrentStatusID: 2, DateAdded: day(1, 15), Changes: []statusChange{
+			{1, day(1, 15)}, {2, day(1, 19)},
+		}},
+		{ID: 3, CurrentStatusID: 1, DateAdded: day(2, 3), Changes: []statusChange{
+			{1, day(2, 3)},
+		}},
+		{ID: 4, CurrentStatusID: 3, DateAdded: day(2, 5), Changes: []statusChange{
+			{1, day(2, 5)}, {3, day(2, 7)}, // skipped In Studies
+		}},
+	}
+
+	report := buildPipeline(statuses, contacts)
+
+	if report.Total != 4 {
+		t.Errorf("total = %d, want 4", report.Total)
+	}
+	wantCurrent := []int{1, 1, 2}
+	wantReached := []int{4, 3, 2}
+	for i, status := range report.Statuses {
+		if status.Current != wantCurrent[i] || status.Reached != wantReached[i] {
+			t.Errorf("%s: current %d reached %d, want %d and %d",
+				status.Name, status.Current, status.Reached, wantCurrent[i], wantReached[i])
+		}
+	}
+
+	// New Contact stays: 10, 4 and 2 days; In Studies: 30 days (a repeated entry does not end it)
+	newContact := report.Statuses[0].DaysInStatus
+	if newContact.Stays != 3 || *newContact.Median != 4 || *newContact.P90 != 8.8 {
+		t.Errorf("New Contact days = %d stays, median %v, p90 %v; want 3, 4 and 8.8",
+			newContact.Stays, *newContact.Median, *newContact.P90)
+	}
+	if inStudies := report.Statuses[1].DaysInStatus; inStudies.Stays != 1 || *inStudies.Median != 30 {
+		t.Errorf("In Studies days = %+v, want one stay of 30", inStudies)
+	}
+	if baptized := report.Statuses[2].DaysInStatus; baptized.Stays != 0 || baptized.Median != nil {
+		t.Errorf("Baptized days = %+v, want no stays", baptized)
+	}
+
+	if len(report.Conversions) != 2 || *report.Conversions[0].Rate != 0.75 || *report.Conversions[1].Rate != 0.667 {
+		t.Errorf("conversions = %+v, want rates 0.75 and 0.667", report.Conversions)
+	}
+
+	if len(report.Cohorts) != 2 || report.Cohorts[0].Month != "2024-01" || report.Cohorts[1].Month != "2024-02" {
+		t.Fatalf("cohorts = %+v, want 2024-01 and 2024-02", report.Cohorts)
+	}
+	february := report.Cohorts[1]
+	if february.Added != 2 || february.Reached[2].Count != 1 || february.Reached[2].Rate != 0.5 {
+		t.Errorf("2024-02 cohort = %+v, want 2 added and 1 baptized", february)
+	}
+}
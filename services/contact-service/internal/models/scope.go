@@ -0,0 +1,90 @@
+package models
+
+import "strings"
+
+// ContactScope limits which contacts a user may see. A contact is visible if
+// it is owned by or assigned to one of UserIDs, or belongs to one of GroupIDs.
+type ContactScope struct {
+	All      bool  // admins see every contact
+	UserIDs  []int // the user and everyone in the groups they lead
+	GroupIDs []int // the groups the user leads and the groups below them
+}
+
+// Allows reports whether contact is visible in the scope
+func (s ContactScope) Allows(contact *Contact) bool {
+	if s.All {
+		return true
+	}
+	for _, id := range s.UserIDs {
+		if id != 0 && (contact.OwnerID == id || contact.AssignedWorkerID == id) {
+			return true
+		}
+	}
+	for _, id := range s.GroupIDs {
+		if id != 0 && contact.GroupID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// HasUser reports whether userID is in the scope
+func (s ContactScope) HasUser(userID int) bool {
+	if s.All {
+		return true
+	}
+	for _, id := range s.UserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// HasGroup reports whether groupID is in the scope
+func (s ContactScope) HasGroup(groupID int) bool {
+	if s.All {
+		return true
+	}
+	for _, id := range s.GroupIDs {
+		if id == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+// where returns an SQL condition on the contacts table, aliased c, matching
+// the scope
+func (s ContactScope) where() (string, []interface{}) {
+	if s.All {
+		return "1 = 1", nil
+	}
+
+	var conditions []string
+	var args []interface{}
+	if len(s.UserIDs) > 0 {
+		in := placeholders(len(s.UserIDs))
+		conditions = append(conditions, "c.owner_id IN ("+in+")", "c.assigned_worker_id IN ("+in+")")
+		for i := 0; i < 2; i++ {
+			for _, id := range s.UserIDs {
+				args = append(args, id)
+			}
+		}
+	}
+	if len(s.GroupIDs) > 0 {
+		conditions = append(conditions, "c.group_id IN ("+placeholders(len(s.GroupIDs))+")")
+		for _, id := range s.GroupIDs {
+			args = append(args, id)
+		}
+	}
+	if len(conditions) == 0 {
+		return "1 = 0", nil
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// placeholders returns n comma-separated SQL placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
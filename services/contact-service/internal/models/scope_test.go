@@ -0,0 +1,73 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContactScopeAllows(t *testing.T) {
+	contact := &Contact{OwnerID: 3, AssignedWorkerID: 5, GroupID: 8}
+	unowned := &Contact{} // no owner, worker or group
+
+	tests := []struct {
+		name    string
+		scope   ContactScope
+		contact *Contact
+		want    bool
+	}{
+		{"all", ContactScope{All: true}, contact, true},
+		{"all, unowned", ContactScope{All: true}, unowned, true},
+		{"owner", ContactScope{UserIDs: []int{1, 3}}, contact, true},
+		{"assignee", ContactScope{UserIDs: []int{5}}, contact, true},
+		{"group", ContactScope{UserIDs: []int{1}, GroupIDs: []int{7, 8}}, contact, true},
+		{"someone else's", ContactScope{UserIDs: []int{1}, GroupIDs: []int{7}}, contact, false},
+		{"zero user ID, unowned", ContactScope{UserIDs: []int{0}}, unowned, false},
+		{"zero group ID, no group", ContactScope{GroupIDs: []int{0}}, unowned, false},
+		{"empty", ContactScope{}, contact, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.scope.Allows(tt.contact); got != tt.want {
+			t.Errorf("%s: Allows = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestContactScopeWhere(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope ContactScope
+		want  string
+		args  []interface{}
+	}{
+		{"all", ContactScope{All: true, UserIDs: []int{1}}, "1 = 1", nil},
+		{"empty", ContactScope{}, "1 = 0", nil},
+		{"users", ContactScope{UserIDs: []int{3, 5}},
+			"(c.owner_id IN (?, ?) OR c.assigned_worker_id IN (?, ?))",
+			[]interface{}{3, 5, 3, 5}},
+		{"groups", ContactScope{GroupIDs: []int{8}},
+			"(c.group_id IN (?))",
+			[]interface{}{8}},
+		{"users and groups", ContactScope{UserIDs: []int{3}, GroupIDs: []int{7, 8}},
+			"(c.owner_id IN (?) OR c.assigned_worker_id IN (?) OR c.group_id IN (?, ?))",
+			[]interface{}{3, 3, 7, 8}},
+		// A zero ID is bound as 0, which never matches a NULL column
+		{"zero user ID", ContactScope{UserIDs: []int{0}},
+			"(c.owner_id IN (?) OR c.assigned_worker_id IN (?))",
+			[]interface{}{0, 0}},
+	}
+
+	for _, tt := range tests {
+		where, args := tt.scope.where()
+		if where != tt.want {
+			t.Errorf("%s: where = %q, want %q", tt.name, where, tt.want)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: args = %v, want %v", tt.name, args, tt.args)
+		}
+		if n := strings.Count(where, "?"); n != len(args) {
+			t.Errorf("%s: %d placeholders for %d args", tt.name, n, len(args))
+		}
+	}
+}
@@ -11,6 +11,8 @@ type Status struct {
 	Name        string `json:"name"`
 	Description string `json:"description,omitempty"`
 	DisplayOrder int   `json:"display_order"`
+	IsMilestone bool   `json:"is_milestone"`
+	StallDays   int    `json:"stall_days"` // days without activity before a follow-up task is created; 0 for never
 }
 
 // StatusRepository provides access to the status store
@@ -25,7 +27,7 @@ func NewStatusRepository(db *sql.DB) *StatusRepository {
 
 // GetAll retrieves all statuses ordered by display_order
 func (r *StatusRepository) GetAll() ([]*Status, error) {
-	query := `SELECT id, name, description, display_order FROM statuses ORDER BY display_order`
+	query := `SELECT id, name, description, display_order, is_milestone, stall_days FROM statuses ORDER BY display_order`
 	
 	rows, err := r.DB.Query(query)
 	if err != nil {
@@ -41,6 +43,8 @@ func (r *StatusRepository) GetAll() ([]*Status, error) {
 			&status.Name, 
 			&status.Description, 
 			&status.DisplayOrder,
+		&status.IsMilestone,
+		&status.StallDays,
 		)
 		if err != nil {
 			return nil, err
@@ -53,7 +57,7 @@ func (r *StatusRepository) GetAll() ([]*Status, error) {
 
 // GetByID retrieves a status by ID
 func (r *StatusRepository) GetByID(id int) (*Status, error) {
-	query := `SELECT id, name, description, display_order FROM statuses WHERE id = ?`
+	query := `SELECT id, name, description, display_order, is_milestone, stall_days FROM statuses WHERE id = ?`
 	
 	status := &Status{}
 	err := r.DB.QueryRow(query, id).Scan(
@@ -61,6 +65,8 @@ func (r *StatusRepository) GetByID(id int) (*Status, error) {
 		&status.Name, 
 		&status.Description, 
 		&status.DisplayOrder,
+		&status.IsMilestone,
+		&status.StallDays,
 	)
 	
 	if err != nil {
@@ -75,9 +81,9 @@ func (r *StatusRepository) GetByID(id int) (*Status, error) {
 
 // Create adds a new status to the database
 func (r *StatusRepository) Create(status *Status) error {
-	query := `INSERT INTO statuses (name, description, display_order) VALUES (?, ?, ?)`
+	query := `INSERT INTO statuses (name, description, display_order, is_milestone, stall_days) VALUES (?, ?, ?, ?, ?)`
 	
-	result, err := r.DB.Exec(query, status.Name, status.Description, status.DisplayOrder)
+	result, err := r.DB.Exec(query, status.Name, status.Description, status.DisplayOrder, status.IsMilestone, status.StallDays)
 	if err != nil {
 		return err
 	}
@@ -93,9 +99,9 @@ func (r *StatusRepository) Create(status *Status) error {
 
 // Update modifies an existing status
 func (r *StatusRepository) Update(id int, status *Status) error {
-	query := `UPDATE statuses SET name = ?, description = ?, display_order = ? WHERE id = ?`
+	query := `UPDATE statuses SET name = ?, description = ?, display_order = ?, is_milestone = ?, stall_days = ? WHERE id = ?`
 	
-	_, err := r.DB.Exec(query, status.Name, status.Description, status.DisplayOrder, id)
+	_, err := r.DB.Exec(query, status.Name, status.Description, status.DisplayOrder, status.IsMilestone, status.StallDays, id)
 	if err != nil {
 		return err
 	}
@@ -118,8 +124,9 @@ func (r *StatusRepository) Delete(id int) error {
 	}
 	
 	// Also check status history
-	checkHistoryQuery := `SELECT COUNT(*) FROM contact_status_history WHERE status_id = ?`
-	err = r.DB.QueryRow(checkHistoryQuery, id).Scan(&count)
+	checkHistoryQuery := `SELECT (SELECT COUNT(*) FROM contact_status_history WHERE status_id = ?) +
+	                             (SELECT COUNT(*) FROM contact_status_history_revisions WHERE status_id = ?)`
+	err = r.DB.QueryRow(checkHistoryQuery, id, id).Scan(&count)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,73 @@
+package models
+
+// ContactSummary counts contacts by status and by tag
+type ContactSummary struct {
+	Total    int           `json:"total"`
+	Statuses []StatusCount `json:"statuses"`
+	Tags     []TagCount    `json:"tags"`
+}
+
+// StatusCount is the number of contacts currently in a status
+type StatusCount struct {
+	StatusID int    `json:"status_id"`
+	Name     string `json:"name"`
+	Count    int    `json:"count"`
+}
+
+// TagCount is the number of contacts with a tag
+type TagCount struct {
+	TagID int    `json:"tag_id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Summary counts the contacts visible in scope that match filter. Every
+// status and tag is listed, including those with no contacts.
+func (r *ContactRepository) Summary(scope ContactScope, filter ContactFilter) (*ContactSummary, error) {
+	where, args := contactsWhere(scope, filter)
+	summary := &ContactSummary{Statuses: []StatusCount{}, Tags: []TagCount{}}
+
+	err := r.DB.QueryRow(`SELECT COUNT(*) FROM contacts c WHERE `+where, args...).Scan(&summary.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.DB.Query(`SELECT s.id, s.name, COUNT(c.id)
+	                         FROM statuses s
+	                         LEFT JOIN contacts c ON c.current_status_id = s.id AND `+where+`
+	                         GROUP BY s.id, s.name, s.display_order
+	                         ORDER BY s.display_order, s.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var count StatusCount
+		if err := rows.Scan(&count.StatusID, &count.Name, &count.Count); err != nil {
+			return nil, err
+		}
+		summary.Statuses = append(summary.Statuses, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tagRows, err := r.DB.Query(`SELECT t.id, t.name, COUNT(c.id)
+	                            FROM tags t
+	                            LEFT JOIN contact_tags ct ON ct.tag_id = t.id
+	                            LEFT JOIN contacts c ON c.id = ct.contact_id AND `+where+`
+	                            GROUP BY t.id, t.name
+	                            ORDER BY t.name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var count TagCount
+		if err := tagRows.Scan(&count.TagID, &count.Name, &count.Count); err != nil {
+			return nil, err
+		}
+		summary.Tags = append(summary.Tags, count)
+	}
+	return summary, tagRows.Err()
+}
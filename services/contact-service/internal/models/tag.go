@@ -0,0 +1,201 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Tag is a free-form label on contacts, such as "college" or "met at outreach"
+type Tag struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// ErrTagMergeIntoSelf is returned when merging a tag into itself, which would
+// delete it
+var ErrTagMergeIntoSelf = errors.New("a tag cannot be merged into itself")
+
+// TagRepository provides access to tags
+type TagRepository struct {
+	DB *sql.DB
+}
+
+// NewTagRepository creates a new TagRepository
+func NewTagRepository(db *sql.DB) *TagRepository {
+	return &TagRepository{DB: db}
+}
+
+// GetAll retrieves every tag by name
+func (r *TagRepository) GetAll() ([]*Tag, error) {
+	rows, err := r.DB.Query(`SELECT id, name FROM tags ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []*Tag
+	for rows.Next() {
+		tag := &Tag{}
+		if err := rows.Scan(&tag.ID, &tag.Name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// GetByID retrieves a tag by ID
+func (r *TagRepository) GetByID(id int) (*Tag, error) {
+	return r.getOne(`SELECT id, name FROM tags WHERE id = ?`, id)
+}
+
+// GetByName retrieves a tag by name, ignoring case
+func (r *TagRepository) GetByName(name string) (*Tag, error) {
+	return r.getOne(`SELECT id, name FROM tags WHERE name = ?`, name)
+}
+
+func (r *TagRepository) getOne(query string, arg interface{}) (*Tag, error) {
+	tag := &Tag{}
+	err := r.DB.QueryRow(query, arg).Scan(&tag.ID, &tag.Name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("tag not found")
+		}
+		return nil, err
+	}
+	return tag, nil
+}
+
+// Create adds a new tag
+func (r *TagRepository) Create(tag *Tag) error {
+	result, err := r.DB.Exec(`INSERT INTO tags (name) VALUES (?)`, tag.Name)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	tag.ID = int(id)
+	return nil
+}
+
+// Rename changes a tag's name
+func (r *TagRepository) Rename(id int, name string) error {
+	_, err := r.DB.Exec(`UPDATE tags SET name = ? WHERE id = ?`, name, id)
+	return err
+}
+
+// Merge moves every contact tagged with sourceID to targetID and deletes the
+// source tag. Contacts with both tags keep the target's tagging.
+func (r *TagRepository) Merge(sourceID, targetID int) error {
+	if sourceID == targetID {
+		return ErrTagMergeIntoSelf
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT IGNORE INTO contact_tags (contact_id, tag_id, tagged_at)
+	                  SELECT contact_id, ?, tagged_at FROM contact_tags WHERE tag_id = ?`,
+		targetID, sourceID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Deleting the tag removes its links too
+	if _, err = tx.Exec(`DELETE FROM tags WHERE id = ?`, sourceID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a tag from every contact and deletes it
+func (r *TagRepository) Delete(id int) error {
+	_, err := r.DB.Exec(`DELETE FROM tags WHERE id = ?`, id)
+	return err
+}
+
+// LoadTags fills in the tags of contacts
+func (r *ContactRepository) LoadTags(contacts ...*Contact) error {
+	if len(contacts) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*Contact, len(contacts))
+	args := make([]interface{}, 0, len(contacts))
+	for _, contact := range contacts {
+		byID[contact.ID] = contact
+		args = append(args, contact.ID)
+	}
+
+	query := `SELECT ct.contact_id, t.id, t.name
+	          FROM contact_tags ct
+	          JOIN tags t ON t.id = ct.tag_id
+	          WHERE ct.contact_id IN (` + placeholders(len(args)) + `)
+	          ORDER BY t.name`
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var contactID int
+		tag := &Tag{}
+		if err := rows.Scan(&contactID, &tag.ID, &tag.Name); err != nil {
+			return err
+		}
+		contact := byID[contactID]
+		contact.Tags = append(contact.Tags, tag)
+	}
+	return rows.Err()
+}
+
+// TagContact adds a tag to a contact; tagging it twice has no effect
+func (r *ContactRepository) TagContact(contactID, tagID int) error {
+	_, err := r.DB.Exec(`INSERT IGNORE INTO contact_tags (contact_id, tag_id) VALUES (?, ?)`, contactID, tagID)
+	return err
+}
+
+// UntagContact removes a tag from a contact
+func (r *ContactRepository) UntagContact(contactID, tagID int) error {
+	_, err := r.DB.Exec(`DELETE FROM contact_tags WHERE contact_id = ? AND tag_id = ?`, contactID, tagID)
+	return err
+}
+
+// BulkTag tags every contact visible in scope that matches filter and returns
+// how many were newly tagged
+func (r *ContactRepository) BulkTag(scope ContactScope, filter ContactFilter, tagID int) (int64, error) {
+	where, args := contactsWhere(scope, filter)
+	query := `INSERT IGNORE INTO contact_tags (contact_id, tag_id)
+	          SELECT c.id, ? FROM contacts c WHERE ` + where
+
+	result, err := r.DB.Exec(query, append([]interface{}{tagID}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// BulkUntag removes a tag from every contact visible in scope that matches
+// filter and returns how many were untagged
+func (r *ContactRepository) BulkUntag(scope ContactScope, filter ContactFilter, tagID int) (int64, error) {
+	where, args := contactsWhere(scope, filter)
+	query := `DELETE ct FROM contact_tags ct
+	          JOIN contacts c ON c.id = ct.contact_id
+	          WHERE ct.tag_id = ? AND ` + where
+
+	result, err := r.DB.Exec(query, append([]interface{}{tagID}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
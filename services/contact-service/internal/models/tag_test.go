@@ -0,0 +1,14 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTagMergeIntoSelf(t *testing.T) {
+	// Refused before the database is touched
+	r := &TagRepository{}
+	if err := r.Merge(3, 3); !errors.Is(err, ErrTagMergeIntoSelf) {
+		t.Errorf("Merge(3, 3) = %v, want ErrTagMergeIntoSelf", err)
+	}
+}
@@ -0,0 +1,300 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Task types
+const (
+	TaskFollowUp = "follow_up"
+	TaskCall     = "call"
+	TaskVisit    = "visit"
+	TaskMessage  = "message"
+	TaskStalled  = "stalled" // created by the stalled contact sweeper
+)
+
+// TaskTypes lists the task types that can be given in requests
+var TaskTypes = []string{TaskFollowUp, TaskCall, TaskVisit, TaskMessage}
+
+// Task is a follow-up to do for a contact
+type Task struct {
+	ID          int        `json:"id"`
+	ContactID   int        `json:"contact_id"`
+	ContactName string     `json:"contact_name"`
+	AssigneeID  int        `json:"assignee_id,omitempty"`
+	Type        string     `json:"type"`
+	Title       string     `json:"title"`
+	Notes       string     `json:"notes,omitempty"`
+	DueDate     string     `json:"due_date,omitempty"` // YYYY-MM-DD
+	Auto        bool       `json:"auto"`               // created by the sweeper
+	CreatedBy   int        `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CompletedBy int        `json:"completed_by,omitempty"`
+}
+
+// TaskFilter selects tasks. Zero values match everything.
+type TaskFilter struct {
+	AssigneeID int
+	ContactID  int
+	Type       string
+	Completed  *bool     // nil for open and completed tasks
+	DueBefore  time.Time // exclusive
+	Limit      int
+}
+
+// query returns the SELECT for the tasks matching the filter on contacts
+// visible in scope
+func (f TaskFilter) query(scope ContactScope) (string, []interface{}) {
+	where, args := scope.where()
+	conditions := []string{where}
+	if f.AssigneeID != 0 {
+		conditions = append(conditions, "t.assignee_id = ?")
+		args = append(args, f.AssigneeID)
+	}
+	if f.ContactID != 0 {
+		conditions = append(conditions, "t.contact_id = ?")
+		args = append(args, f.ContactID)
+	}
+	if f.Type != "" {
+		conditions = append(conditions, "t.task_type = ?")
+		args = append(args, f.Type)
+	}
+	if f.Completed != nil {
+		if *f.Completed {
+			conditions = append(conditions, "t.completed_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "t.completed_at IS NULL")
+		}
+	}
+	if !f.DueBefore.IsZero() {
+		conditions = append(conditions, "t.due_date < ?")
+		args = append(args, f.DueBefore.Format("2006-01-02"))
+	}
+
+	query := `SELECT ` + taskColumns + `
+	          FROM tasks t
+	          JOIN contacts c ON c.id = t.contact_id
+	          WHERE ` + strings.Join(conditions, " AND ") + `
+	          ORDER BY t.completed_at IS NOT NULL, t.due_date IS NULL, t.due_date, t.id`
+	if f.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, f.Limit)
+	}
+	return query, args
+}
+
+// TaskRepository provides access to follow-up tasks
+type TaskRepository struct {
+	DB *sql.DB
+}
+
+// NewTaskRepository creates a new TaskRepository
+func NewTaskRepository(db *sql.DB) *TaskRepository {
+	return &TaskRepository{DB: db}
+}
+
+const taskColumns = `t.id, t.contact_id, c.name, t.assignee_id, t.task_type, t.title, t.notes, t.due_date,
+	t.auto, t.created_by, t.created_at, t.completed_at, t.completed_by`
+
+func scanTask(row rowScanner) (*Task, error) {
+	task := &Task{}
+	var assigneeID, createdBy, completedBy sql.NullInt64
+	var notes sql.NullString
+	var dueDate, completedAt sql.NullTime
+	err := row.Scan(&task.ID, &task.ContactID, &task.ContactName, &assigneeID, &task.Type, &task.Title, &notes,
+		&dueDate, &task.Auto, &createdBy, &task.CreatedAt, &completedAt, &completedBy)
+	if err != nil {
+		return nil, err
+	}
+	task.AssigneeID = int(assigneeID.Int64)
+	task.CreatedBy = int(createdBy.Int64)
+	task.CompletedBy = int(completedBy.Int64)
+	task.Notes = notes.String
+	if dueDate.Valid {
+		task.DueDate = dueDate.Time.Format("2006-01-02")
+	}
+	if completedAt.Valid {
+		task.CompletedAt = &completedAt.Time
+	}
+	return task, nil
+}
+
+// GetAll retrieves the tasks matching filter on contacts visible in scope,
+// open tasks first, then by due date with undated tasks last
+func (r *TaskRepository) GetAll(scope ContactScope, filter TaskFilter) ([]*Task, error) {
+	query, args := filter.query(scope)
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []*Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// GetByID retrieves a task by ID
+func (r *TaskRepository) GetByID(id int) (*Task, error) {
+	row := r.DB.QueryRow(`SELECT `+taskColumns+`
+	                      FROM tasks t
+	                      JOIN contacts c ON c.id = t.contact_id
+	                      WHERE t.id = ?`, id)
+	task, err := scanTask(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("task not found")
+		}
+		return nil, err
+	}
+	return task, nil
+}
+
+// Create adds a new task
+func (r *TaskRepository) Create(task *Task) error {
+	result, err := r.DB.Exec(`INSERT INTO tasks (contact_id, assignee_id, task_type, title, notes, due_date, created_by)
+	                          VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		task.ContactID, nullInt(task.AssigneeID), task.Type, task.Title, task.Notes, nullDate(task.DueDate),
+		nullInt(task.CreatedBy))
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	task.ID = int(id)
+	return nil
+}
+
+// Update changes a task's assignee, type, title, notes and due date
+func (r *TaskRepository) Update(id int, task *Task) error {
+	_, err := r.DB.Exec(`UPDATE tasks SET assignee_id = ?, task_type = ?, title = ?, notes = ?, due_date = ?
+	                     WHERE id = ?`,
+		nullInt(task.AssigneeID), task.Type, task.Title, task.Notes, nullDate(task.DueDate), id)
+	return err
+}
+
+// Complete marks a task as done by userID, or reopens it if done is false
+func (r *TaskRepository) Complete(id, userID int, done bool) error {
+	var err error
+	if done {
+		_, err = r.DB.Exec(`UPDATE tasks SET completed_at = NOW(), completed_by = ? WHERE id = ? AND completed_at IS NULL`,
+			nullInt(userID), id)
+	} else {
+		_, err = r.DB.Exec(`UPDATE tasks SET completed_at = NULL, completed_by = NULL WHERE id = ?`, id)
+	}
+	return err
+}
+
+// Delete removes a task
+func (r *TaskRepository) Delete(id int) error {
+	_, err := r.DB.Exec(`DELETE FROM tasks WHERE id = ?`, id)
+	return err
+}
+
+// stallCandidate is a contact in a status with a stall threshold
+type stallCandidate struct {
+	ContactID    int
+	AssigneeID   int // the assigned worker, or else the owner; zero if neither
+	StatusName   string
+	StallDays    int
+	LastActivity time.Time
+	HasOpenTask  bool // an open stalled task already exists
+}
+
+// stalledTasks returns the tasks to create for candidates whose last activity
+// is longer ago than their status's stall_days, due on now's date. Contacts
+// that already have an open stalled task are skipped, so they get one
+// reminder at a time. So are contacts with neither a worker nor an owner:
+// their task would be in no one's inbox and would hold back later ones.
+func stalledTasks(candidates []stallCandidate, now time.Time) []*Task {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var tasks []*Task
+	for _, c := range candidates {
+		if c.StallDays <= 0 || c.HasOpenTask || c.AssigneeID == 0 {
+			continue
+		}
+		if !c.LastActivity.Before(now.AddDate(0, 0, -c.StallDays)) {
+			continue
+		}
+		last := c.LastActivity.In(now.Location())
+		days := int(today.Sub(time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, now.Location())).Hours()/24 + 0.5)
+		tasks = append(tasks, &Task{
+			ContactID:  c.ContactID,
+			AssigneeID: c.AssigneeID,
+			Type:       TaskStalled,
+			Title:      fmt.Sprintf("No activity for %d days in %s", days, c.StatusName),
+			DueDate:    today.Format("2006-01-02"),
+			Auto:       true,
+		})
+	}
+	return tasks
+}
+
+// CreateStalledTasks creates a task for each contact whose last activity is
+// longer ago than its status's stall_days, following stalledTasks' rules.
+// Activity is an update to the contact, a status change, a completed task or
+// a logged interaction.
+func (r *TaskRepository) CreateStalledTasks(now time.Time) (int64, error) {
+	rows, err := r.DB.Query(`SELECT c.id, COALESCE(c.assigned_worker_id, c.owner_id, 0), s.name, s.stall_days,
+	                                GREATEST(c.last_updated,
+	                                         COALESCE((SELECT MAX(h.date_changed) FROM contact_status_history h
+	                                                   WHERE h.contact_id = c.id AND NOT h.voided), c.date_added),
+	                                         COALESCE((SELECT MAX(d.completed_at) FROM tasks d
+	                                                   WHERE d.contact_id = c.id), c.date_added),
+	                                         COALESCE((SELECT MAX(i.occurred_at) FROM interactions i
+	                                                   WHERE i.contact_id = c.id AND NOT i.voided), c.date_added)),
+	                                EXISTS (SELECT 1 FROM tasks o
+	                                        WHERE o.contact_id = c.id AND o.task_type = ? AND o.completed_at IS NULL)
+	                         FROM contacts c
+	                         JOIN statuses s ON s.id = c.current_status_id
+	                         WHERE s.stall_days > 0`, TaskStalled)
+	if err != nil {
+		return 0, err
+	}
+	var candidates []stallCandidate
+	for rows.Next() {
+		var c stallCandidate
+		if err := rows.Scan(&c.ContactID, &c.AssigneeID, &c.StatusName, &c.StallDays, &c.LastActivity,
+			&c.HasOpenTask); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var created int64
+	for _, task := range stalledTasks(candidates, now) {
+		_, err := r.DB.Exec(`INSERT INTO tasks (contact_id, assignee_id, task_type, title, due_date, auto)
+		                     VALUES (?, ?, ?, ?, ?, TRUE)`,
+			task.ContactID, task.AssigneeID, task.Type, task.Title, task.DueDate)
+		if err != nil {
+			return created, err
+		}
+		created++
+	}
+	return created, nil
+}
+
+// nullDate stores an empty YYYY-MM-DD date as NULL
+func nullDate(date string) sql.NullString {
+	return sql.NullString{String: date, Valid: date != ""}
+}
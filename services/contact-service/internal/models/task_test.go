@@ -0,0 +1,75 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStalledTasks(t *testing.T) {
+	now := time.Date(2024, 3, 20, 9, 0, 0, 0, time.UTC)
+	daysAgo := func(d int) time.Time { return now.AddDate(0, 0, -d) }
+	candidates := []stallCandidate{
+		{ContactID: 1, AssigneeID: 5, StatusName: "Studying", StallDays: 14, LastActivity: daysAgo(15)},
+		{ContactID: 2, AssigneeID: 5, StatusName: "Studying", StallDays: 14, LastActivity: daysAgo(13)},
+		{ContactID: 3, AssigneeID: 5, StatusName: "Studying", StallDays: 14, LastActivity: daysAgo(30), HasOpenTask: true},
+		{ContactID: 4, StatusName: "Studying", StallDays: 14, LastActivity: daysAgo(30)}, // no worker or owner
+		{ContactID: 5, AssigneeID: 6, StatusName: "Contacted", StallDays: 0, LastActivity: daysAgo(300)},
+		{ContactID: 6, AssigneeID: 6, StatusName: "Contacted", StallDays: 7, LastActivity: daysAgo(7).Add(-time.Hour)},
+	}
+
+	tasks := stalledTasks(candidates, now)
+
+	want := []Task{
+		{ContactID: 1, AssigneeID: 5, Type: TaskStalled, Title: "No activity for 15 days in Studying",
+			DueDate: "2024-03-20", Auto: true},
+		{ContactID: 6, AssigneeID: 6, Type: TaskStalled, Title: "No activity for 7 days in Contacted",
+			DueDate: "2024-03-20", Auto: true},
+	}
+	if len(tasks) != len(want) {
+		t.Fatalf("got %d tasks, want %d: %+v", len(tasks), len(want), tasks)
+	}
+	for i, task := range tasks {
+		if !reflect.DeepEqual(*task, want[i]) {
+			t.Errorf("task %d = %+v, want %+v", i, *task, want[i])
+		}
+	}
+}
+
+func TestTaskFilterQuery(t *testing.T) {
+	open := false
+	filter := TaskFilter{
+		AssigneeID: 5,
+		ContactID:  9,
+		Type:       TaskCall,
+		Completed:  &open,
+		DueBefore:  time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+		Limit:      50,
+	}
+
+	query, args := filter.query(ContactScope{UserIDs: []int{5}, GroupIDs: []int{2}})
+
+	for _, part := range []string{
+		"(c.owner_id IN (?) OR c.assigned_worker_id IN (?) OR c.group_id IN (?))",
+		"t.assignee_id = ?", "t.contact_id = ?", "t.task_type = ?", "t.completed_at IS NULL", "t.due_date < ?",
+		"ORDER BY t.completed_at IS NOT NULL, t.due_date IS NULL, t.due_date, t.id",
+		"LIMIT ?",
+	} {
+		if !strings.Contains(query, part) {
+			t.Errorf("query lacks %q:\n%s", part, query)
+		}
+	}
+	wantArgs := []interface{}{5, 5, 2, 5, 9, TaskCall, "2024-04-01", 50}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args = %v, want %v", args, wantArgs)
+	}
+	if n := strings.Count(query, "?"); n != len(args) {
+		t.Errorf("%d placeholders for %d args", n, len(args))
+	}
+
+	query, args = TaskFilter{}.query(ContactScope{All: true})
+	if strings.Contains(query, " AND ") || strings.Contains(query, "LIMIT") || len(args) != 0 {
+		t.Errorf("empty filter added conditions: %s %v", query, args)
+	}
+}
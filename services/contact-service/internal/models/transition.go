@@ -0,0 +1,217 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// StatusTransition allows contacts to move from one status to another. Once a
+// status has any transitions out of it, contacts may only leave it by one of
+// them; a status with none may move to any other status.
+type StatusTransition struct {
+	ID           int  `json:"id"`
+	FromStatusID int  `json:"from_status_id"`
+	ToStatusID   int  `json:"to_status_id"`
+	RequiresNote bool `json:"requires_note"`
+	MinDays      int  `json:"min_days"` // days the contact must have been in the from status
+}
+
+// Reasons a status change is refused
+const (
+	TransitionNotAllowed   = "not_allowed"
+	TransitionNoteRequired = "note_required"
+	TransitionTooSoon      = "too_soon"
+)
+
+// TransitionError explains why a contact may not move between two statuses
+type TransitionError struct {
+	Message          string     `json:"error"`
+	Reason           string     `json:"reason"`
+	FromStatusID     int        `json:"from_status_id"`
+	ToStatusID       int        `json:"to_status_id"`
+	AllowedStatusIDs []int      `json:"allowed_status_ids,omitempty"` // when not allowed
+	MinDays          int        `json:"min_days,omitempty"`           // when too soon
+	AllowedFrom      *time.Time `json:"allowed_from,omitempty"`       // when too soon
+}
+
+func (e *TransitionError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return "status change " + e.Reason
+}
+
+// CheckTransition checks a move from one status to another against the
+// transitions out of the from status. since is when the contact entered the
+// from status. It returns nil if the move is allowed.
+func CheckTransition(rules []*StatusTransition, from, to int, note string, since, now time.Time) *TransitionError {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var rule *StatusTransition
+	allowed := make([]int, 0, len(rules))
+	for _, r := range rules {
+		if r.FromStatusID != from {
+			continue
+		}
+		allowed = append(allowed, r.ToStatusID)
+		if r.ToStatusID == to {
+			rule = r
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	failure := &TransitionError{FromStatusID: from, ToStatusID: to}
+	switch {
+	case rule == nil:
+		failure.Reason = TransitionNotAllowed
+		failure.AllowedStatusIDs = allowed
+	case rule.RequiresNote && note == "":
+		failure.Reason = TransitionNoteRequired
+	case rule.MinDays > 0 && now.Before(since.AddDate(0, 0, rule.MinDays)):
+		allowedFrom := since.AddDate(0, 0, rule.MinDays)
+		failure.Reason = TransitionTooSoon
+		failure.MinDays = rule.MinDays
+		failure.AllowedFrom = &allowedFrom
+	default:
+		return nil
+	}
+	return failure
+}
+
+// TransitionRepository provides access to status transitions
+type TransitionRepository struct {
+	DB *sql.DB
+}
+
+// NewTransitionRepository creates a new TransitionRepository
+func NewTransitionRepository(db *sql.DB) *TransitionRepository {
+	return &TransitionRepository{DB: db}
+}
+
+const transitionColumns = `t.id, t.from_status_id, t.to_status_id, t.requires_note, t.min_days`
+
+// GetAll retrieves every transition, or with fromStatusID above zero only
+// those out of that status, in status display order
+func (r *TransitionRepository) GetAll(fromStatusID int) ([]*StatusTransition, error) {
+	query := `SELECT ` + transitionColumns + `
+	          FROM status_transitions t
+	          JOIN statuses f ON t.from_status_id = f.id
+	          JOIN statuses s ON t.to_status_id = s.id`
+	var args []interface{}
+	if fromStatusID > 0 {
+		query += ` WHERE t.from_status_id = ?`
+		args = append(args, fromStatusID)
+	}
+	query += ` ORDER BY f.display_order, f.id, s.display_order, s.id`
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transitions []*StatusTransition
+	for rows.Next() {
+		t := &StatusTransition{}
+		if err := rows.Scan(&t.ID, &t.FromStatusID, &t.ToStatusID, &t.RequiresNote, &t.MinDays); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, rows.Err()
+}
+
+// GetByID retrieves a transition by ID
+func (r *TransitionRepository) GetByID(id int) (*StatusTransition, error) {
+	return r.getOne(`SELECT `+transitionColumns+` FROM status_transitions t WHERE t.id = ?`, id)
+}
+
+// Get retrieves the transition between two statuses
+func (r *TransitionRepository) Get(fromStatusID, toStatusID int) (*StatusTransition, error) {
+	return r.getOne(`SELECT `+transitionColumns+` FROM status_transitions t
+	                 WHERE t.from_status_id = ? AND t.to_status_id = ?`, fromStatusID, toStatusID)
+}
+
+func (r *TransitionRepository) getOne(query string, args ...interface{}) (*StatusTransition, error) {
+	t := &StatusTransition{}
+	err := r.DB.QueryRow(query, args...).Scan(&t.ID, &t.FromStatusID, &t.ToStatusID, &t.RequiresNote, &t.MinDays)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("transition not found")
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// Create adds a new transition
+func (r *TransitionRepository) Create(t *StatusTransition) error {
+	result, err := r.DB.Exec(`INSERT INTO status_transitions (from_status_id, to_status_id, requires_note, min_days)
+	                          VALUES (?, ?, ?, ?)`, t.FromStatusID, t.ToStatusID, t.RequiresNote, t.MinDays)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	t.ID = int(id)
+	return nil
+}
+
+// Update changes a transition's requirements; the statuses it joins are kept
+func (r *TransitionRepository) Update(id int, t *StatusTransition) error {
+	_, err := r.DB.Exec(`UPDATE status_transitions SET requires_note = ?, min_days = ? WHERE id = ?`,
+		t.RequiresNote, t.MinDays, id)
+	return err
+}
+
+// Delete removes a transition
+func (r *TransitionRepository) Delete(id int) error {
+	_, err := r.DB.Exec(`DELETE FROM status_transitions WHERE id = ?`, id)
+	return err
+}
+
+// Milestone is a milestone status and when a contact first reached it
+type Milestone struct {
+	StatusID   int        `json:"status_id"`
+	StatusName string     `json:"status_name"`
+	ReachedAt  *time.Time `json:"reached_at"` // null if not reached
+}
+
+// GetMilestones lists every milestone status in display order with when the
+// contact first reached it, according to its status history. Voided entries
+// do not count.
+func (r *ContactRepository) GetMilestones(contactID int) ([]*Milestone, error) {
+	rows, err := r.DB.Query(`SELECT s.id, s.name, MIN(h.date_changed)
+	                         FROM statuses s
+	                         LEFT JOIN contact_status_history h ON h.status_id = s.id AND h.contact_id = ? AND NOT h.voided
+	                         WHERE s.is_milestone
+	                         GROUP BY s.id, s.name, s.display_order
+	                         ORDER BY s.display_order, s.id`, contactID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	milestones := []*Milestone{}
+	for rows.Next() {
+		milestone := &Milestone{}
+		var reached sql.NullTime
+		if err := rows.Scan(&milestone.StatusID, &milestone.StatusName, &reached); err != nil {
+			return nil, err
+		}
+		if reached.Valid {
+			milestone.ReachedAt = &reached.Time
+		}
+		milestones = append(milestones, milestone)
+	}
+	return milestones, rows.Err()
+}
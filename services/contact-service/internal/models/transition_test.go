@@ -0,0 +1,53 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckTransition(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	rules := []*StatusTransition{
+		{FromStatusID: 1, ToStatusID: 2},
+		{FromStatusID: 1, ToStatusID: 3, RequiresNote: true},
+		{FromStatusID: 2, ToStatusID: 3, MinDays: 30},
+	}
+
+	tests := []struct {
+		name     string
+		from, to int
+		note     string
+		since    time.Time
+		reason   string // "" if allowed
+	}{
+		{"allowed", 1, 2, "", now, ""},
+		{"not allowed", 1, 4, "", now, TransitionNotAllowed},
+		{"note missing", 1, 3, "", now, TransitionNoteRequired},
+		{"note given", 1, 3, "met again", now, ""},
+		{"too soon", 2, 3, "", now.AddDate(0, 0, -29), TransitionTooSoon},
+		{"long enough", 2, 3, "", now.AddDate(0, 0, -30), ""},
+		{"unrestricted status", 4, 1, "", now, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failure := CheckTransition(rules, tt.from, tt.to, tt.note, tt.since, now)
+			switch {
+			case tt.reason == "" && failure != nil:
+				t.Fatalf("got %q, want allowed", failure.Reason)
+			case tt.reason != "" && failure == nil:
+				t.Fatalf("allowed, want %q", tt.reason)
+			case failure != nil && failure.Reason != tt.reason:
+				t.Fatalf("got %q, want %q", failure.Reason, tt.reason)
+			}
+		})
+	}
+
+	failure := CheckTransition(rules, 1, 4, "", now, now)
+	if len(failure.AllowedStatusIDs) != 2 || failure.AllowedStatusIDs[0] != 2 || failure.AllowedStatusIDs[1] != 3 {
+		t.Errorf("allowed statuses = %v, want [2 3]", failure.AllowedStatusIDs)
+	}
+	failure = CheckTransition(rules, 2, 3, "", now.AddDate(0, 0, -10), now)
+	if want := now.AddDate(0, 0, 20); failure.AllowedFrom == nil || !failure.AllowedFrom.Equal(want) {
+		t.Errorf("allowed from = %v, want %v", failure.AllowedFrom, want)
+	}
+}
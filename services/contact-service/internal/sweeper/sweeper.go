@@ -0,0 +1,30 @@
+// Package sweeper creates follow-up tasks for stalled contacts in the
+// background.
+package sweeper
+
+import (
+	"log"
+	"time"
+
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
+)
+
+// Start looks for stalled contacts straight away and then every interval,
+// creating a task for each. It does nothing if interval is not positive.
+func Start(tasks *models.TaskRepository, interval time.Duration) {
+	if interval <= 0 {
+		log.Println("Stalled contact sweeper disabled")
+		return
+	}
+	go func() {
+		for {
+			created, err := tasks.CreateStalledTasks(time.Now())
+			if err != nil {
+				log.Printf("Failed to sweep for stalled contacts: %v", err)
+			} else if created > 0 {
+				log.Printf("Created %d follow-up tasks for stalled contacts", created)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
@@ -3,22 +3,36 @@ package main
 import (
 	"log"
 	"net/http"
+	"time"
 	"github.com/rs/cors"
 
 	"github.com/gorilla/mux"
 	"github.com/cardoza1991/church-management-system/services/contact-service/api/handlers"
-	"github.com/cardoza1991/church-management-system/services/contact-service/api/middleware"
+	"github.com/cardoza1991/church-management-system/pkg/auth"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
 	"github.com/cardoza1991/church-management-system/services/contact-service/config"
 	"github.com/cardoza1991/church-management-system/services/contact-service/internal/db"
+	shareddb "github.com/cardoza1991/church-management-system/pkg/db"
 	"github.com/cardoza1991/church-management-system/services/contact-service/internal/models"
+	"github.com/cardoza1991/church-management-system/services/contact-service/internal/sweeper"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
 	
+	// Load token signing and verification keys
+	keyring, err := auth.LoadKeyring(cfg.Keys)
+	if err != nil {
+		log.Fatalf("Failed to load token keys: %v", err)
+	}
+	auth.UseKeyring(keyring)
+	
+	// Reject access tokens whose session has been revoked in user-service
+	auth.UseRevocationList(auth.NewRemoteRevocationList(cfg.AuthService+"/sessions/revoked", 30*time.Second))
+	
 	// Connect to database
-	database, err := db.Connect(cfg.DSN())
+	database, err := shareddb.Connect(cfg.DSN())
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -32,15 +46,39 @@ func main() {
 	// Create repositories
 	contactRepo := models.NewContactRepository(database)
 	statusRepo := models.NewStatusRepository(database)
+	fieldRepo := models.NewCustomFieldRepository(database)
+	tagRepo := models.NewTagRepository(database)
+	transitionRepo := models.NewTransitionRepository(database)
+	taskRepo := models.NewTaskRepository(database)
+	interactionRepo := models.NewInteractionRepository(database)
+	
+	// Create follow-up tasks for stalled contacts in the background
+	sweeper.Start(taskRepo, cfg.SweepInterval)
 	
 	// Create handlers
 	contactHandler := &handlers.ContactHandler{
 		ContactRepo: contactRepo,
 		StatusRepo:  statusRepo,
+		FieldRepo:   fieldRepo,
+		TagRepo:     tagRepo,
+		TransitionRepo: transitionRepo,
+		TaskRepo:    taskRepo,
+		InteractionRepo: interactionRepo,
+		Downline:    auth.NewDownlineClient(cfg.AuthService),
 	}
 	statusHandler := &handlers.StatusHandler{
 		StatusRepo: statusRepo,
 	}
+	fieldHandler := &handlers.CustomFieldHandler{
+		FieldRepo: fieldRepo,
+	}
+	tagHandler := &handlers.TagHandler{
+		TagRepo: tagRepo,
+	}
+	transitionHandler := &handlers.TransitionHandler{
+		TransitionRepo: transitionRepo,
+		StatusRepo:     statusRepo,
+	}
 	
 	// Create router
 	r := mux.NewRouter()
@@ -54,26 +92,89 @@ func main() {
 	// Public endpoints (no authentication required)
 	r.HandleFunc("/statuses", statusHandler.GetAllStatuses).Methods("GET")
 	r.HandleFunc("/statuses/{id:[0-9]+}", statusHandler.GetStatus).Methods("GET")
+	r.HandleFunc("/status-transitions", transitionHandler.GetAllTransitions).Methods("GET")
+	
+	// Protected endpoints, each group gated by a permission from the role matrix
+	readRouter := r.PathPrefix("").Subrouter()
+	readRouter.Use(middleware.AuthMiddleware, middleware.Require("contacts:read"))
+	readRouter.HandleFunc("/contacts", contactHandler.ListContacts).Methods("GET")
+	readRouter.HandleFunc("/contacts/summary", contactHandler.GetContactSummary).Methods("GET")
+	readRouter.HandleFunc("/contacts/duplicates", contactHandler.FindDuplicates).Methods("GET")
+	readRouter.HandleFunc("/contacts/export", contactHandler.ExportContacts).Methods("GET")
+	readRouter.HandleFunc("/contacts/{id:[0-9]+}", contactHandler.GetContact).Methods("GET")
+	readRouter.HandleFunc("/contacts/{id:[0-9]+}/status-history", contactHandler.GetContactStatusHistory).Methods("GET")
+	readRouter.HandleFunc("/contacts/{id:[0-9]+}/status-history/{entryId:[0-9]+}/revisions", contactHandler.GetStatusHistoryRevisions).Methods("GET")
+	readRouter.HandleFunc("/contacts/{id:[0-9]+}/milestones", contactHandler.GetContactMilestones).Methods("GET")
+	readRouter.HandleFunc("/contacts/{id:[0-9]+}/interactions", contactHandler.ListInteractions).Methods("GET")
+	readRouter.HandleFunc("/contacts/{id:[0-9]+}/interactions/{interactionId:[0-9]+}", contactHandler.GetInteraction).Methods("GET")
+	readRouter.HandleFunc("/contacts/{id:[0-9]+}/interactions/{interactionId:[0-9]+}/revisions", contactHandler.GetInteractionRevisions).Methods("GET")
+	readRouter.HandleFunc("/contacts/{id:[0-9]+}/timeline", contactHandler.GetContactTimeline).Methods("GET")
+	readRouter.HandleFunc("/contact-fields", fieldHandler.GetAllCustomFields).Methods("GET")
+	readRouter.HandleFunc("/contact-fields/{id:[0-9]+}", fieldHandler.GetCustomField).Methods("GET")
+	readRouter.HandleFunc("/tags", tagHandler.GetAllTags).Methods("GET")
+	readRouter.HandleFunc("/reports/pipeline", contactHandler.GetPipelineReport).Methods("GET")
+	readRouter.HandleFunc("/tasks", contactHandler.ListTasks).Methods("GET")
+	readRouter.HandleFunc("/tasks/mine", contactHandler.ListMyTasks).Methods("GET")
+	readRouter.HandleFunc("/tasks/{id:[0-9]+}", contactHandler.GetTask).Methods("GET")
+	
+	writeRouter := r.PathPrefix("").Subrouter()
+	writeRouter.Use(middleware.AuthMiddleware, middleware.Require("contacts:write"))
+	writeRouter.HandleFunc("/contacts", contactHandler.CreateContact).Methods("POST")
+	writeRouter.HandleFunc("/contacts/import", contactHandler.ImportContacts).Methods("POST")
+	writeRouter.HandleFunc("/contacts/{id:[0-9]+}", contactHandler.UpdateContact).Methods("PUT")
+	writeRouter.HandleFunc("/contacts/{id:[0-9]+}/status", contactHandler.UpdateContactStatus).Methods("PUT")
+	writeRouter.HandleFunc("/contacts/{id:[0-9]+}/interactions", contactHandler.CreateInteraction).Methods("POST")
+	writeRouter.HandleFunc("/contacts/{id:[0-9]+}/interactions/{interactionId:[0-9]+}", contactHandler.UpdateInteraction).Methods("PUT")
+	writeRouter.HandleFunc("/contacts/{id:[0-9]+}/interactions/{interactionId:[0-9]+}/void", contactHandler.VoidInteraction).Methods("POST")
+	writeRouter.HandleFunc("/contacts/{id:[0-9]+}/tags/{tagId:[0-9]+}", contactHandler.TagContact).Methods("PUT")
+	writeRouter.HandleFunc("/contacts/{id:[0-9]+}/tags/{tagId:[0-9]+}", contactHandler.UntagContact).Methods("DELETE")
+	writeRouter.HandleFunc("/tags", tagHandler.CreateTag).Methods("POST")
+	writeRouter.HandleFunc("/tags/{id:[0-9]+}/contacts", contactHandler.BulkTagContacts).Methods("POST")
+	writeRouter.HandleFunc("/tags/{id:[0-9]+}/contacts", contactHandler.BulkUntagContacts).Methods("DELETE")
+	
+	deleteRouter := r.PathPrefix("").Subrouter()
+	deleteRouter.Use(middleware.AuthMiddleware, middleware.Require("contacts:delete"))
+	deleteRouter.HandleFunc("/contacts/{id:[0-9]+}", contactHandler.DeleteContact).Methods("DELETE")
+	deleteRouter.HandleFunc("/contacts/{id:[0-9]+}/merge", contactHandler.MergeContact).Methods("POST")
+	
+	// Status history corrections
+	historyRouter := r.PathPrefix("").Subrouter()
+	historyRouter.Use(middleware.AuthMiddleware, middleware.Require("history:write"))
+	historyRouter.HandleFunc("/contacts/{id:[0-9]+}/status-history/{entryId:[0-9]+}", contactHandler.CorrectStatusHistoryEntry).Methods("PUT")
+	historyRouter.HandleFunc("/contacts/{id:[0-9]+}/status-history/{entryId:[0-9]+}/void", contactHandler.VoidStatusHistoryEntry).Methods("POST")
+	
+	// Follow-up tasks
+	taskRouter := r.PathPrefix("").Subrouter()
+	taskRouter.Use(middleware.AuthMiddleware, middleware.Require("tasks:write"))
+	taskRouter.HandleFunc("/tasks", contactHandler.CreateTask).Methods("POST")
+	taskRouter.HandleFunc("/tasks/{id:[0-9]+}", contactHandler.UpdateTask).Methods("PUT")
+	taskRouter.HandleFunc("/tasks/{id:[0-9]+}", contactHandler.DeleteTask).Methods("DELETE")
+	taskRouter.HandleFunc("/tasks/{id:[0-9]+}/complete", contactHandler.CompleteTask).Methods("POST")
+	taskRouter.HandleFunc("/tasks/{id:[0-9]+}/reopen", contactHandler.ReopenTask).Methods("POST")
+	
+	// Status management
+	statusRouter := r.PathPrefix("").Subrouter()
+	statusRouter.Use(middleware.AuthMiddleware, middleware.Require("statuses:write"))
+	statusRouter.HandleFunc("/statuses", statusHandler.CreateStatus).Methods("POST")
+	statusRouter.HandleFunc("/statuses/{id:[0-9]+}", statusHandler.UpdateStatus).Methods("PUT")
+	statusRouter.HandleFunc("/statuses/{id:[0-9]+}", statusHandler.DeleteStatus).Methods("DELETE")
+	statusRouter.HandleFunc("/status-transitions", transitionHandler.CreateTransition).Methods("POST")
+	statusRouter.HandleFunc("/status-transitions/{id:[0-9]+}", transitionHandler.UpdateTransition).Methods("PUT")
+	statusRouter.HandleFunc("/status-transitions/{id:[0-9]+}", transitionHandler.DeleteTransition).Methods("DELETE")
+	
+	// Tag management
+	tagRouter := r.PathPrefix("").Subrouter()
+	tagRouter.Use(middleware.AuthMiddleware, middleware.Require("tags:write"))
+	tagRouter.HandleFunc("/tags/{id:[0-9]+}", tagHandler.RenameTag).Methods("PUT")
+	tagRouter.HandleFunc("/tags/{id:[0-9]+}/merge", tagHandler.MergeTag).Methods("POST")
+	tagRouter.HandleFunc("/tags/{id:[0-9]+}", tagHandler.DeleteTag).Methods("DELETE")
 	
-	// Protected endpoints (require authentication)
-	apiRouter := r.PathPrefix("").Subrouter()
-	apiRouter.Use(middleware.AuthMiddleware)
-	
-	// Contacts endpoints
-	apiRouter.HandleFunc("/contacts", contactHandler.ListContacts).Methods("GET")
-	apiRouter.HandleFunc("/contacts", contactHandler.CreateContact).Methods("POST")
-	apiRouter.HandleFunc("/contacts/{id:[0-9]+}", contactHandler.GetContact).Methods("GET")
-	apiRouter.HandleFunc("/contacts/{id:[0-9]+}", contactHandler.UpdateContact).Methods("PUT")
-	apiRouter.HandleFunc("/contacts/{id:[0-9]+}", contactHandler.DeleteContact).Methods("DELETE")
-	apiRouter.HandleFunc("/contacts/{id:[0-9]+}/status", contactHandler.UpdateContactStatus).Methods("PUT")
-	apiRouter.HandleFunc("/contacts/{id:[0-9]+}/status-history", contactHandler.GetContactStatusHistory).Methods("GET")
-	
-	// Admin-only endpoints
-	adminRouter := r.PathPrefix("").Subrouter()
-	adminRouter.Use(middleware.AuthMiddleware, middleware.AdminRequired)
-	adminRouter.HandleFunc("/statuses", statusHandler.CreateStatus).Methods("POST")
-	adminRouter.HandleFunc("/statuses/{id:[0-9]+}", statusHandler.UpdateStatus).Methods("PUT")
-	adminRouter.HandleFunc("/statuses/{id:[0-9]+}", statusHandler.DeleteStatus).Methods("DELETE")
+	// Custom field management
+	fieldRouter := r.PathPrefix("").Subrouter()
+	fieldRouter.Use(middleware.AuthMiddleware, middleware.Require("fields:write"))
+	fieldRouter.HandleFunc("/contact-fields", fieldHandler.CreateCustomField).Methods("POST")
+	fieldRouter.HandleFunc("/contact-fields/{id:[0-9]+}", fieldHandler.UpdateCustomField).Methods("PUT")
+	fieldRouter.HandleFunc("/contact-fields/{id:[0-9]+}", fieldHandler.DeleteCustomField).Methods("DELETE")
 	
 	// Start server
 	log.Printf("Contact service starting on port %s", cfg.ServerPort)
@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/cardoza1991/church-management-system/services/reservation-service/api/middleware"
+	"github.com/cardoza1991/church-management-system/pkg/auth"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/pkg/pagination"
 	"github.com/cardoza1991/church-management-system/services/reservation-service/internal/models"
 )
 
@@ -19,28 +21,18 @@ type ReservationHandler struct {
 
 // GetAllReservations returns a list of all reservations
 func (h *ReservationHandler) GetAllReservations(w http.ResponseWriter, r *http.Request) {
-	// Get pagination parameters
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-	
-	limit := 20 // Default limit
-	if limitStr != "" {
-		parsedLimit, err := strconv.Atoi(limitStr)
-		if err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-		}
+	// Get pagination parameters: a cursor from a previous page, or limit and offset
+	page, err := pagination.ParseRequest(r, 20, 100)
+	if err == nil {
+		err = page.CheckSort(models.ReservationSort)
 	}
-	
-	offset := 0 // Default offset
-	if offsetStr != "" {
-		parsedOffset, err := strconv.Atoi(offsetStr)
-		if err == nil && parsedOffset >= 0 {
-			offset = parsedOffset
-		}
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
 	}
 	
 	// Fetch reservations from repository
-	reservations, err := h.ReservationRepo.GetAll(limit, offset)
+	reservations, cursors, err := h.ReservationRepo.GetAll(page)
 	if err != nil {
 		http.Error(w, "Failed to fetch reservations: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -49,8 +41,10 @@ func (h *ReservationHandler) GetAllReservations(w http.ResponseWriter, r *http.R
 	// Return response
 	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
 		"reservations": reservations,
-		"limit":        limit,
-		"offset":       offset,
+		"limit":        page.Limit,
+		"offset":       page.Offset,
+		"next_cursor":  cursors.Next,
+		"prev_cursor":  cursors.Prev,
 	})
 }
 
@@ -166,7 +160,7 @@ type ReservationRequest struct {
 // CreateReservation handles creating a new reservation
 func (h *ReservationHandler) CreateReservation(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
-	claims, ok := r.Context().Value("user").(*middleware.Claims)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -292,7 +286,7 @@ func (h *ReservationHandler) CreateReservation(w http.ResponseWriter, r *http.Re
 // UpdateReservation handles updating an existing reservation
 func (h *ReservationHandler) UpdateReservation(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
-	claims, ok := r.Context().Value("user").(*middleware.Claims)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -313,8 +307,8 @@ func (h *ReservationHandler) UpdateReservation(w http.ResponseWriter, r *http.Re
 		return
 	}
 	
-	// Only the user who created the reservation or an admin can update it
-	if existingReservation.UserID != claims.UserID && claims.Role != "admin" {
+	// Only the user who created the reservation or a manager can update it
+	if existingReservation.UserID != claims.UserID && !auth.Can(claims.Role, auth.ReservationsManage) {
 		http.Error(w, "You can only update your own reservations", http.StatusForbidden)
 		return
 	}
@@ -446,7 +440,7 @@ func (h *ReservationHandler) UpdateReservation(w http.ResponseWriter, r *http.Re
 // DeleteReservation handles deleting a reservation
 func (h *ReservationHandler) DeleteReservation(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
-	claims, ok := r.Context().Value("user").(*middleware.Claims)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -467,8 +461,8 @@ func (h *ReservationHandler) DeleteReservation(w http.ResponseWriter, r *http.Re
 		return
 	}
 	
-	// Only the user who created the reservation or an admin can delete it
-	if existingReservation.UserID != claims.UserID && claims.Role != "admin" {
+	// Only the user who created the reservation or a manager can delete it
+	if existingReservation.UserID != claims.UserID && !auth.Can(claims.Role, auth.ReservationsManage) {
 		http.Error(w, "You can only delete your own reservations", http.StatusForbidden)
 		return
 	}
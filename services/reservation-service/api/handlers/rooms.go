@@ -7,7 +7,7 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/cardoza1991/church-management-system/services/reservation-service/api/middleware"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
 	"github.com/cardoza1991/church-management-system/services/reservation-service/internal/models"
 )
 
@@ -65,13 +65,6 @@ type RoomRequest struct {
 
 // CreateRoom handles creating a new room
 func (h *RoomHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
-	// Only admins can create rooms
-	claims, ok := r.Context().Value("user").(*middleware.Claims)
-	if !ok || claims.Role != "admin" {
-		http.Error(w, "Admin access required", http.StatusForbidden)
-		return
-	}
-	
 	// Parse request
 	var req RoomRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -130,13 +123,6 @@ func (h *RoomHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
 
 // UpdateRoom handles updating an existing room
 func (h *RoomHandler) UpdateRoom(w http.ResponseWriter, r *http.Request) {
-	// Only admins can update rooms
-	claims, ok := r.Context().Value("user").(*middleware.Claims)
-	if !ok || claims.Role != "admin" {
-		http.Error(w, "Admin access required", http.StatusForbidden)
-		return
-	}
-	
 	// Get ID from URL
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -212,13 +198,6 @@ func (h *RoomHandler) UpdateRoom(w http.ResponseWriter, r *http.Request) {
 
 // DeleteRoom handles deleting a room
 func (h *RoomHandler) DeleteRoom(w http.ResponseWriter, r *http.Request) {
-	// Only admins can delete rooms
-	claims, ok := r.Context().Value("user").(*middleware.Claims)
-	if !ok || claims.Role != "admin" {
-		http.Error(w, "Admin access required", http.StatusForbidden)
-		return
-	}
-	
 	// Get ID from URL
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -2,11 +2,15 @@ module github.com/cardoza1991/church-management-system/services/reservation-serv
 
 go 1.22.2
 
-require github.com/go-sql-driver/mysql v1.9.0 // direct
+require (
+	github.com/cardoza1991/church-management-system/pkg v0.0.0
+	github.com/gorilla/mux v1.8.1
+	github.com/rs/cors v1.11.1
+)
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
-	github.com/gorilla/mux v1.8.1 // direct
+	github.com/go-sql-driver/mysql v1.9.0 // indirect
 )
 
-require github.com/rs/cors v1.11.1 // direct
+replace github.com/cardoza1991/church-management-system/pkg => ../../pkg
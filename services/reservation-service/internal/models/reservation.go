@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"errors"
 	"time"
+
+	"github.com/cardoza1991/church-management-system/pkg/pagination"
 )
 
 // Reservation represents a room booking
@@ -41,21 +43,32 @@ func NewReservationRepository(db *sql.DB) *ReservationRepository {
 	return &ReservationRepository{DB: db}
 }
 
-// GetAll retrieves all reservations
-func (r *ReservationRepository) GetAll(limit, offset int) ([]*Reservation, error) {
+// ReservationSort names the order GetAll lists reservations in; cursors
+// from other listings are rejected
+const ReservationSort = "start_time:desc"
+
+// reservationKeyset orders reservations newest first, with the ID breaking ties
+var reservationKeyset = pagination.Keyset{Key: "r.start_time", ID: "r.id", Descending: true}
+
+// GetAll retrieves a page of reservations, newest first, and cursors for the
+// neighbouring pages
+func (r *ReservationRepository) GetAll(page pagination.Request) ([]*Reservation, pagination.Cursors, error) {
+	cursorWhere, args := reservationKeyset.Where(page)
+	limit, offset := page.QueryLimit()
 	query := `
 		SELECT r.id, r.room_id, m.name, r.user_id, r.contact_id, r.title, r.description, 
 			   r.start_time, r.end_time, r.recurring_type, r.recurring_end_date, 
 			   r.created_at, r.updated_at
 		FROM reservations r
 		JOIN rooms m ON r.room_id = m.id
-		ORDER BY r.start_time DESC
+		WHERE ` + cursorWhere + `
+		ORDER BY ` + reservationKeyset.OrderBy(page) + `
 		LIMIT ? OFFSET ?
 	`
 	
-	rows, err := r.DB.Query(query, limit, offset)
+	rows, err := r.DB.Query(query, append(args, limit, offset)...)
 	if err != nil {
-		return nil, err
+		return nil, pagination.Cursors{}, err
 	}
 	defer rows.Close()
 	
@@ -81,7 +94,7 @@ func (r *ReservationRepository) GetAll(limit, offset int) ([]*Reservation, error
 			&reservation.UpdatedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, pagination.Cursors{}, err
 		}
 		
 		if contactID.Valid {
@@ -95,7 +108,10 @@ func (r *ReservationRepository) GetAll(limit, offset int) ([]*Reservation, error
 		reservations = append(reservations, reservation)
 	}
 	
-	return reservations, nil
+	reservations, cursors := pagination.Page(reservations, page, func(reservation *Reservation) pagination.Cursor {
+		return pagination.Cursor{Sort: ReservationSort, Key: pagination.FormatTime(reservation.StartTime), ID: reservation.ID}
+	})
+	return reservations, cursors, nil
 }
 
 // GetByID retrieves a reservation by ID
@@ -3,13 +3,16 @@ package main
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 	"github.com/cardoza1991/church-management-system/services/reservation-service/api/handlers"
-	"github.com/cardoza1991/church-management-system/services/reservation-service/api/middleware"
+	"github.com/cardoza1991/church-management-system/pkg/auth"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
 	"github.com/cardoza1991/church-management-system/services/reservation-service/config"
 	"github.com/cardoza1991/church-management-system/services/reservation-service/internal/db"
+	shareddb "github.com/cardoza1991/church-management-system/pkg/db"
 	"github.com/cardoza1991/church-management-system/services/reservation-service/internal/models"
 )
 
@@ -17,8 +20,18 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 	
+	// Load token signing and verification keys
+	keyring, err := auth.LoadKeyring(cfg.Keys)
+	if err != nil {
+		log.Fatalf("Failed to load token keys: %v", err)
+	}
+	auth.UseKeyring(keyring)
+	
+	// Reject access tokens whose session has been revoked in user-service
+	auth.UseRevocationList(auth.NewRemoteRevocationList(cfg.AuthService+"/sessions/revoked", 30*time.Second))
+	
 	// Connect to database
-	database, err := db.Connect(cfg.DSN())
+	database, err := shareddb.Connect(cfg.DSN())
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -56,27 +69,27 @@ func main() {
 	r.HandleFunc("/rooms/{id:[0-9]+}", roomHandler.GetRoom).Methods("GET")
 	r.HandleFunc("/rooms/available", roomHandler.GetAvailableRooms).Methods("GET")
 	
-	// Protected endpoints (require authentication)
-	apiRouter := r.PathPrefix("").Subrouter()
-	apiRouter.Use(middleware.AuthMiddleware)
-	
-	// Room endpoints
-	apiRouter.HandleFunc("/rooms/{id:[0-9]+}/availability", roomHandler.CheckRoomAvailability).Methods("GET")
+	// Protected endpoints, each group gated by a permission from the role matrix
+	readRouter := r.PathPrefix("").Subrouter()
+	readRouter.Use(middleware.AuthMiddleware, middleware.Require("reservations:read"))
+	readRouter.HandleFunc("/rooms/{id:[0-9]+}/availability", roomHandler.CheckRoomAvailability).Methods("GET")
+	readRouter.HandleFunc("/reservations", reservationHandler.GetAllReservations).Methods("GET")
+	readRouter.HandleFunc("/reservations/by-date", reservationHandler.GetReservationsByDate).Methods("GET")
+	readRouter.HandleFunc("/reservations/{id:[0-9]+}", reservationHandler.GetReservation).Methods("GET")
 	
-	// Reservation endpoints
-	apiRouter.HandleFunc("/reservations", reservationHandler.GetAllReservations).Methods("GET")
-	apiRouter.HandleFunc("/reservations/by-date", reservationHandler.GetReservationsByDate).Methods("GET")
-	apiRouter.HandleFunc("/reservations", reservationHandler.CreateReservation).Methods("POST")
-	apiRouter.HandleFunc("/reservations/{id:[0-9]+}", reservationHandler.GetReservation).Methods("GET")
-	apiRouter.HandleFunc("/reservations/{id:[0-9]+}", reservationHandler.UpdateReservation).Methods("PUT")
-	apiRouter.HandleFunc("/reservations/{id:[0-9]+}", reservationHandler.DeleteReservation).Methods("DELETE")
+	// Booking; editing someone else's reservation also needs reservations:manage
+	writeRouter := r.PathPrefix("").Subrouter()
+	writeRouter.Use(middleware.AuthMiddleware, middleware.Require("reservations:write"))
+	writeRouter.HandleFunc("/reservations", reservationHandler.CreateReservation).Methods("POST")
+	writeRouter.HandleFunc("/reservations/{id:[0-9]+}", reservationHandler.UpdateReservation).Methods("PUT")
+	writeRouter.HandleFunc("/reservations/{id:[0-9]+}", reservationHandler.DeleteReservation).Methods("DELETE")
 	
-	// Admin-only endpoints
-	adminRouter := r.PathPrefix("").Subrouter()
-	adminRouter.Use(middleware.AuthMiddleware, middleware.AdminRequired)
-	adminRouter.HandleFunc("/rooms", roomHandler.CreateRoom).Methods("POST")
-	adminRouter.HandleFunc("/rooms/{id:[0-9]+}", roomHandler.UpdateRoom).Methods("PUT")
-	adminRouter.HandleFunc("/rooms/{id:[0-9]+}", roomHandler.DeleteRoom).Methods("DELETE")
+	// Room management
+	roomRouter := r.PathPrefix("").Subrouter()
+	roomRouter.Use(middleware.AuthMiddleware, middleware.Require("rooms:write"))
+	roomRouter.HandleFunc("/rooms", roomHandler.CreateRoom).Methods("POST")
+	roomRouter.HandleFunc("/rooms/{id:[0-9]+}", roomHandler.UpdateRoom).Methods("PUT")
+	roomRouter.HandleFunc("/rooms/{id:[0-9]+}", roomHandler.DeleteRoom).Methods("DELETE")
 	
 	// Setup CORS
 	c := cors.New(cors.Options{
@@ -8,7 +8,8 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/cardoza1991/church-management-system/services/study-service/api/middleware"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/pkg/pagination"
 	"github.com/cardoza1991/church-management-system/services/study-service/internal/models"
 )
 
@@ -18,7 +19,8 @@ type StudyHandler struct {
 	LessonRepo *models.LessonRepository
 }
 
-// GetStudiesByContact returns all studies for a specific contact
+// GetStudiesByContact returns the studies for a specific contact, all at once
+// or a page at a time
 func (h *StudyHandler) GetStudiesByContact(w http.ResponseWriter, r *http.Request) {
 	// Get contact ID from URL
 	vars := mux.Vars(r)
@@ -28,8 +30,18 @@ func (h *StudyHandler) GetStudiesByContact(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	
+	// Get pagination parameters; without a limit or cursor every study is returned
+	page, err := pagination.ParseRequest(r, 0, 100)
+	if err == nil {
+		err = page.CheckSort(models.StudySort)
+	}
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	
 	// Fetch studies from repository
-	studies, err := h.StudyRepo.GetByContactID(contactID)
+	studies, cursors, err := h.StudyRepo.ListByContactID(contactID, page)
 	if err != nil {
 		http.Error(w, "Failed to fetch studies: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -37,8 +49,10 @@ func (h *StudyHandler) GetStudiesByContact(w http.ResponseWriter, r *http.Reques
 	
 	// Return response
 	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
-		"contact_id": contactID,
-		"studies":    studies,
+		"contact_id":  contactID,
+		"studies":     studies,
+		"next_cursor": cursors.Next,
+		"prev_cursor": cursors.Prev,
 	})
 }
 
@@ -76,7 +90,7 @@ type StudyRequest struct {
 // CreateStudy handles creating a new study
 func (h *StudyHandler) CreateStudy(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
-	claims, ok := r.Context().Value("user").(*middleware.Claims)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -3,32 +3,8 @@ package db
 import (
 	"database/sql"
 	"log"
-	"time"
-
-	_ "github.com/go-sql-driver/mysql"
 )
 
-// Connect establishes a connection to the database
-func Connect(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		return nil, err
-	}
-
-	// Configure connection pool
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
-
-	// Test connection
-	if err := db.Ping(); err != nil {
-		return nil, err
-	}
-
-	log.Println("Connected to database successfully")
-	return db, nil
-}
-
 // EnsureTablesExist creates the necessary tables if they don't exist
 func EnsureTablesExist(db *sql.DB) error {
 	// Create predefined lessons table if it doesn't exist
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"errors"
 	"time"
+
+	"github.com/cardoza1991/church-management-system/pkg/pagination"
 )
 
 // Study represents a completed Bible study session with a contact
@@ -33,19 +35,37 @@ func NewStudyRepository(db *sql.DB) *StudyRepository {
 
 // GetByContactID retrieves all studies for a specific contact
 func (r *StudyRepository) GetByContactID(contactID int) ([]*Study, error) {
+	studies, _, err := r.ListByContactID(contactID, pagination.Request{})
+	return studies, err
+}
+
+// StudySort names the order ListByContactID lists studies in; cursors from
+// other listings are rejected
+const StudySort = "date_completed:desc"
+
+// studyKeyset orders studies most recent first, with the ID breaking ties
+var studyKeyset = pagination.Keyset{Key: "s.date_completed", ID: "s.id", Descending: true}
+
+// ListByContactID retrieves a page of a contact's studies, most recent first,
+// and cursors for the neighbouring pages
+func (r *StudyRepository) ListByContactID(contactID int, page pagination.Request) ([]*Study, pagination.Cursors, error) {
+	cursorWhere, cursorArgs := studyKeyset.Where(page)
+	limit, offset := page.QueryLimit()
 	query := `
 		SELECT s.id, s.contact_id, s.lesson_id, l.title, s.date_completed, 
 			   s.location, s.duration_minutes, s.notes, s.taught_by_user_id, 
 			   s.created_at, s.updated_at
 		FROM studies s
 		JOIN lessons l ON s.lesson_id = l.id
-		WHERE s.contact_id = ?
-		ORDER BY s.date_completed DESC
+		WHERE s.contact_id = ? AND ` + cursorWhere + `
+		ORDER BY ` + studyKeyset.OrderBy(page) + `
+		LIMIT ? OFFSET ?
 	`
 	
-	rows, err := r.DB.Query(query, contactID)
+	args := append([]interface{}{contactID}, cursorArgs...)
+	rows, err := r.DB.Query(query, append(args, limit, offset)...)
 	if err != nil {
-		return nil, err
+		return nil, pagination.Cursors{}, err
 	}
 	defer rows.Close()
 	
@@ -66,12 +86,15 @@ func (r *StudyRepository) GetByContactID(contactID int) ([]*Study, error) {
 			&study.UpdatedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, pagination.Cursors{}, err
 		}
 		studies = append(studies, study)
 	}
 	
-	return studies, nil
+	studies, cursors := pagination.Page(studies, page, func(study *Study) pagination.Cursor {
+		return pagination.Cursor{Sort: StudySort, Key: pagination.FormatTime(study.DateCompleted), ID: study.ID}
+	})
+	return studies, cursors, nil
 }
 
 // GetByID retrieves a study by ID
@@ -3,12 +3,15 @@ package main
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/cardoza1991/church-management-system/services/study-service/api/handlers"
-	"github.com/cardoza1991/church-management-system/services/study-service/api/middleware"
+	"github.com/cardoza1991/church-management-system/pkg/auth"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
 	"github.com/cardoza1991/church-management-system/services/study-service/config"
 	"github.com/cardoza1991/church-management-system/services/study-service/internal/db"
+	shareddb "github.com/cardoza1991/church-management-system/pkg/db"
 	"github.com/cardoza1991/church-management-system/services/study-service/internal/models"
 )
 
@@ -16,8 +19,18 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 	
+	// Load token signing and verification keys
+	keyring, err := auth.LoadKeyring(cfg.Keys)
+	if err != nil {
+		log.Fatalf("Failed to load token keys: %v", err)
+	}
+	auth.UseKeyring(keyring)
+	
+	// Reject access tokens whose session has been revoked in user-service
+	auth.UseRevocationList(auth.NewRemoteRevocationList(cfg.AuthService+"/sessions/revoked", 30*time.Second))
+	
 	// Connect to database
-	database, err := db.Connect(cfg.DSN())
+	database, err := shareddb.Connect(cfg.DSN())
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -54,25 +67,29 @@ func main() {
 	r.HandleFunc("/lessons", lessonHandler.GetAllLessons).Methods("GET")
 	r.HandleFunc("/lessons/{id:[0-9]+}", lessonHandler.GetLesson).Methods("GET")
 	
-	// Protected endpoints (require authentication)
-	apiRouter := r.PathPrefix("").Subrouter()
-	apiRouter.Use(middleware.AuthMiddleware)
+	// Protected endpoints, each group gated by a permission from the role matrix
+	readRouter := r.PathPrefix("").Subrouter()
+	readRouter.Use(middleware.AuthMiddleware, middleware.Require("studies:read"))
+	readRouter.HandleFunc("/contacts/{contactId:[0-9]+}/studies", studyHandler.GetStudiesByContact).Methods("GET")
+	readRouter.HandleFunc("/contacts/{contactId:[0-9]+}/study-stats", studyHandler.GetContactStudyStats).Methods("GET")
+	readRouter.HandleFunc("/contacts/{contactId:[0-9]+}/completed-lessons", studyHandler.GetCompletedLessons).Methods("GET")
+	readRouter.HandleFunc("/studies/{id:[0-9]+}", studyHandler.GetStudy).Methods("GET")
+	
+	writeRouter := r.PathPrefix("").Subrouter()
+	writeRouter.Use(middleware.AuthMiddleware, middleware.Require("studies:write"))
+	writeRouter.HandleFunc("/studies", studyHandler.CreateStudy).Methods("POST")
+	writeRouter.HandleFunc("/studies/{id:[0-9]+}", studyHandler.UpdateStudy).Methods("PUT")
 	
-	// Studies endpoints
-	apiRouter.HandleFunc("/contacts/{contactId:[0-9]+}/studies", studyHandler.GetStudiesByContact).Methods("GET")
-	apiRouter.HandleFunc("/contacts/{contactId:[0-9]+}/study-stats", studyHandler.GetContactStudyStats).Methods("GET")
-	apiRouter.HandleFunc("/contacts/{contactId:[0-9]+}/completed-lessons", studyHandler.GetCompletedLessons).Methods("GET")
-	apiRouter.HandleFunc("/studies", studyHandler.CreateStudy).Methods("POST")
-	apiRouter.HandleFunc("/studies/{id:[0-9]+}", studyHandler.GetStudy).Methods("GET")
-	apiRouter.HandleFunc("/studies/{id:[0-9]+}", studyHandler.UpdateStudy).Methods("PUT")
-	apiRouter.HandleFunc("/studies/{id:[0-9]+}", studyHandler.DeleteStudy).Methods("DELETE")
+	deleteRouter := r.PathPrefix("").Subrouter()
+	deleteRouter.Use(middleware.AuthMiddleware, middleware.Require("studies:delete"))
+	deleteRouter.HandleFunc("/studies/{id:[0-9]+}", studyHandler.DeleteStudy).Methods("DELETE")
 	
-	// Admin-only endpoints
-	adminRouter := r.PathPrefix("").Subrouter()
-	adminRouter.Use(middleware.AuthMiddleware, middleware.AdminRequired)
-	adminRouter.HandleFunc("/lessons", lessonHandler.CreateLesson).Methods("POST")
-	adminRouter.HandleFunc("/lessons/{id:[0-9]+}", lessonHandler.UpdateLesson).Methods("PUT")
-	adminRouter.HandleFunc("/lessons/{id:[0-9]+}", lessonHandler.DeleteLesson).Methods("DELETE")
+	// Lesson management
+	lessonRouter := r.PathPrefix("").Subrouter()
+	lessonRouter.Use(middleware.AuthMiddleware, middleware.Require("lessons:write"))
+	lessonRouter.HandleFunc("/lessons", lessonHandler.CreateLesson).Methods("POST")
+	lessonRouter.HandleFunc("/lessons/{id:[0-9]+}", lessonHandler.UpdateLesson).Methods("PUT")
+	lessonRouter.HandleFunc("/lessons/{id:[0-9]+}", lessonHandler.DeleteLesson).Methods("DELETE")
 	
 	// Start server
 	log.Printf("Study service starting on port %s", cfg.ServerPort)
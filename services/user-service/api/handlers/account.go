@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/services/user-service/internal/auth"
+	"github.com/cardoza1991/church-management-system/services/user-service/internal/mail"
+	"github.com/cardoza1991/church-management-system/services/user-service/internal/models"
+	"github.com/cardoza1991/church-management-system/services/user-service/internal/ratelimit"
+)
+
+// AccountHandler handles password resets and email verification
+type AccountHandler struct {
+	UserRepo    *models.UserRepository
+	SessionRepo *models.SessionRepository
+	TokenRepo   *models.AccountTokenRepository
+	Mailer      mail.Mailer
+	AppURL      string // frontend address the emailed links point at
+	
+	// ResetLimiter and ResetIPLimiter count password reset requests per
+	// address and per client IP
+	ResetLimiter   ratelimit.Limiter
+	ResetIPLimiter ratelimit.Limiter
+}
+
+// ForgotPasswordRequest represents a request for a password reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest represents a request to set a new password with a reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// VerifyEmailRequest represents a request to confirm an email address
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// ForgotPassword emails a password reset link. It responds the same way, and
+// as quickly, whether or not the address belongs to an account, so it cannot
+// be used to discover which addresses are registered. Requests are limited
+// per address and per client IP.
+func (h *AccountHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	// Parse request
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "Email required", http.StatusBadRequest)
+		return
+	}
+	
+	// Refuse callers that have asked too often
+	ip := clientIP(r)
+	emailKey := EmailKey(req.Email)
+	if locked(w, h.ResetIPLimiter, IPKey(ip), "Too many reset requests from this address") ||
+		locked(w, h.ResetLimiter, emailKey, "Too many reset requests for this email") {
+		return
+	}
+	
+	// Count the request against the address and the client IP
+	if _, err := h.ResetLimiter.Fail(emailKey); err != nil {
+		log.Printf("Failed to record password reset request: %v", err)
+	}
+	if _, err := h.ResetIPLimiter.Fail(IPKey(ip)); err != nil {
+		log.Printf("Failed to record password reset request: %v", err)
+	}
+	
+	// Send the link in the background so the response time does not depend on
+	// whether the account exists
+	go h.sendPasswordReset(req.Email)
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusAccepted, map[string]string{
+		"message": "If that address belongs to an account, a reset link has been sent",
+	})
+}
+
+// sendPasswordReset mails a reset link to the account with email, if there
+// is one and it may log in
+func (h *AccountHandler) sendPasswordReset(email string) {
+	user, err := h.UserRepo.GetByEmail(email)
+	if err != nil || user.Status == models.StatusDeactivated {
+		return
+	}
+	
+	link, err := h.issueToken(user, models.TokenPasswordReset, auth.PasswordResetTTL, "/reset-password")
+	if err == nil {
+		err = h.Mailer.Send(mail.Message{
+			To:      user.Email,
+			Subject: "Reset your password",
+			Body: fmt.Sprintf("Hello %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s\n\n"+
+				"If you did not ask to reset your password, you can ignore this email.\n",
+				user.FullName, auth.PasswordResetTTL, link),
+		})
+	}
+	if err != nil {
+		log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+	}
+}
+
+// ResetPassword sets a new password using a reset token and ends the user's sessions
+func (h *AccountHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	// Parse request
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		http.Error(w, "Token and password required", http.StatusBadRequest)
+		return
+	}
+	
+	// Check the token without using it up, so a rejected password can be retried
+	tokenHash := auth.HashToken(req.Token)
+	token, err := h.TokenRepo.Get(tokenHash, models.TokenPasswordReset)
+	if err != nil {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+	user, err := h.UserRepo.GetByID(token.UserID)
+	if err != nil {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+	
+	// Check the password against the policy
+	if !checkNewPassword(w, h.UserRepo, user, req.Password) {
+		return
+	}
+	
+	// Redeem the token
+	if _, err := h.TokenRepo.Consume(tokenHash, models.TokenPasswordReset); err != nil {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+	
+	// Set the new password
+	if err := h.UserRepo.SetPassword(user.ID, req.Password); err != nil {
+		http.Error(w, "Failed to reset password: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Whoever knew the old password is logged out
+	if err := h.SessionRepo.RevokeAllForUser(user.ID); err != nil {
+		http.Error(w, "Failed to revoke sessions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Password reset successfully",
+	})
+}
+
+// VerifyEmail confirms a user's email address using a verification token
+func (h *AccountHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	// Parse request
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Token required", http.StatusBadRequest)
+		return
+	}
+	
+	// Redeem the token
+	token, err := h.TokenRepo.Consume(auth.HashToken(req.Token), models.TokenEmailVerification)
+	if err != nil {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+	
+	// The token only verifies the address it was sent to
+	if _, err := h.UserRepo.MarkEmailVerified(token.UserID, token.Email); err != nil {
+		http.Error(w, "Failed to verify email: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	user, err := h.UserRepo.GetByID(token.UserID)
+	if err != nil || user.Email != token.Email {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Email verified successfully",
+		"user":    user,
+	})
+}
+
+// ResendVerification emails a new verification link to the current user
+func (h *AccountHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Find user in database
+	user, err := h.UserRepo.GetByID(claims.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.EmailVerified {
+		http.Error(w, "Email already verified", http.StatusConflict)
+		return
+	}
+	
+	// Send the link
+	if err := h.SendVerification(user); err != nil {
+		http.Error(w, "Failed to send verification email: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusAccepted, map[string]string{
+		"message": "Verification email sent",
+	})
+}
+
+// SendVerification emails the user a link confirming their current address
+func (h *AccountHandler) SendVerification(user *models.User) error {
+	link, err := h.issueToken(user, models.TokenEmailVerification, auth.EmailVerificationTTL, "/verify-email")
+	if err != nil {
+		return err
+	}
+	
+	return h.Mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hello %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n",
+			user.FullName, auth.EmailVerificationTTL, link),
+	})
+}
+
+// issueToken stores a new single-use token for the user and returns the
+// frontend link that redeems it
+func (h *AccountHandler) issueToken(user *models.User, purpose string, ttl time.Duration, path string) (string, error) {
+	token, hash, err := auth.NewAccountToken()
+	if err != nil {
+		return "", err
+	}
+	
+	err = h.TokenRepo.Create(&models.AccountToken{
+		TokenHash: hash,
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	
+	return h.AppURL + path + "?token=" + url.QueryEscape(token), nil
+}
@@ -1,16 +1,40 @@
 package handlers
 
 import (
+	"crypto/hmac"
 	"encoding/json"
+	"log"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	sharedauth "github.com/cardoza1991/church-management-system/pkg/auth"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
 	"github.com/cardoza1991/church-management-system/services/user-service/internal/auth"
 	"github.com/cardoza1991/church-management-system/services/user-service/internal/models"
+	"github.com/cardoza1991/church-management-system/services/user-service/internal/ratelimit"
 )
 
 // AuthHandler handles authentication requests
 type AuthHandler struct {
-	UserRepo *models.UserRepository
+	UserRepo    *models.UserRepository
+	SessionRepo *models.SessionRepository
+	
+	// RequireApproval creates self-registered accounts as pending
+	RequireApproval bool
+	
+	// Accounts sends the email verification link to new users
+	Accounts *AccountHandler
+	
+	// TokenRepo holds login challenges awaiting a second factor
+	TokenRepo *models.AccountTokenRepository
+	
+	// Failed logins are throttled per account and per client IP
+	AccountLimiter ratelimit.Limiter
+	IPLimiter      ratelimit.Limiter
+	AuditRepo      *models.AuditRepository
 }
 
 // RegisterRequest represents a registration request
@@ -20,7 +44,6 @@ type RegisterRequest struct {
 	Email    string `json:"email"`
 	FullName string `json:"full_name"`
 	Phone    string `json:"phone,omitempty"`
-	Role     string `json:"role"`
 }
 
 // LoginRequest represents a login request
@@ -29,10 +52,65 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// RefreshRequest represents a request to exchange a refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 // AuthResponse represents the response after authentication
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  *models.User `json:"user"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refresh_token"`
+	ExpiresIn    int          `json:"expires_in"`
+	User         *models.User `json:"user"`
+	
+	// RecoveryCodes is only set when a login completes 2FA enrollment
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// startSession creates a session for the user and issues its first token pair
+func (h *AuthHandler) startSession(r *http.Request, user *models.User) (*AuthResponse, error) {
+	sessionID, err := auth.NewSessionID()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, refreshHash, err := auth.NewRefreshToken(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	
+	session := &models.Session{
+		ID:               sessionID,
+		UserID:           user.ID,
+		RefreshTokenHash: refreshHash,
+		UserAgent:        r.UserAgent(),
+		IPAddress:        clientIP(r),
+		ExpiresAt:        time.Now().Add(auth.RefreshTokenTTL),
+	}
+	if err := h.SessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+	
+	token, err := auth.GenerateToken(user.ID, user.Username, user.Role, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	
+	return &AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(auth.AccessTokenTTL.Seconds()),
+		User:         user,
+	}, nil
+}
+
+// clientIP returns the caller's address without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
 
 // Register handles user registration
@@ -50,19 +128,24 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// Default role if not provided
-	if req.Role == "" {
-		req.Role = "member"
-	}
-	
-	// Create user
+	// Public registration always creates members; elevated roles are granted
+	// afterwards by an admin or overseer
 	user := &models.User{
 		Username: req.Username,
 		Email:    req.Email,
-		Role:     req.Role,
+		Role:     models.RoleMember,
+		Status:   models.StatusActive,
 		FullName: req.FullName,
 		Phone:    req.Phone,
 	}
+	if h.RequireApproval {
+		user.Status = models.StatusPending
+	}
+	
+	// Check the password against the policy
+	if !checkNewPassword(w, h.UserRepo, user, req.Password) {
+		return
+	}
 	
 	// Save to database
 	if err := h.UserRepo.Create(user, req.Password); err != nil {
@@ -70,8 +153,28 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// Generate token
-	token, err := auth.GenerateToken(user.ID, user.Username, user.Role)
+	// Ask the user to confirm their address; registration succeeds regardless
+	if err := h.Accounts.SendVerification(user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+	
+	// Pending accounts cannot log in until approved
+	if user.Status == models.StatusPending {
+		middleware.RespondJSON(w, http.StatusAccepted, map[string]interface{}{
+			"message": "Registration received and awaiting approval",
+			"user":    user,
+		})
+		return
+	}
+	
+	// Roles that require 2FA enroll before getting tokens
+	if auth.TwoFactorRequired(user.Role) {
+		h.startChallenge(w, user)
+		return
+	}
+	
+	// Start a session and generate tokens
+	resp, err := h.startSession(r, user)
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
@@ -79,10 +182,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(AuthResponse{
-		Token: token,
-		User:  user,
-	})
+	json.NewEncoder(w).Encode(resp)
 }
 
 // Login handles user login
@@ -100,21 +200,174 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// Find user
+	// Refuse callers that have failed too often
+	ip := clientIP(r)
+	if locked(w, h.IPLimiter, IPKey(ip), "Too many failed logins from this address") ||
+		locked(w, h.AccountLimiter, AccountKey(req.Username), "Account temporarily locked after too many failed logins") {
+		return
+	}
+	
+	// Find user and check password
 	user, err := h.UserRepo.GetByUsername(req.Username)
-	if err != nil {
+	if err != nil || !user.CheckPassword(req.Password) {
+		loginFailed(h.AccountLimiter, h.IPLimiter, h.AuditRepo, req.Username, user, ip)
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 	
-	// Check password
-	if !user.CheckPassword(req.Password) {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	// The password was right, so earlier failures no longer count against the account
+	if err := h.AccountLimiter.Reset(AccountKey(req.Username)); err != nil {
+		log.Printf("Failed to reset login attempts for user %d: %v", user.ID, err)
+	}
+	
+	// Rehash the password if the configured bcrypt cost has gone up
+	upgradePasswordHash(h.UserRepo, user, req.Password)
+	
+	// Check the account is allowed to log in
+	switch user.Status {
+	case models.StatusPending:
+		http.Error(w, "Account is awaiting approval", http.StatusForbidden)
+		return
+	case models.StatusDeactivated:
+		http.Error(w, "Account is deactivated", http.StatusForbidden)
+		return
+	}
+	
+	// Ask for the second factor, or for enrollment if the role requires it
+	if user.TwoFactor || auth.TwoFactorRequired(user.Role) {
+		h.startChallenge(w, user)
+		return
+	}
+	
+	// Start a session and generate tokens
+	resp, err := h.startSession(r, user)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// AccountKey is the limiter key for failed logins to an account
+func AccountKey(username string) string {
+	return "account:" + strings.ToLower(username)
+}
+
+// IPKey is the limiter key for failed logins from a client address
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// EmailKey is the limiter key for password reset requests to an address
+func EmailKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// locked responds with 429 and reports true if the limiter has locked key
+func locked(w http.ResponseWriter, limiter ratelimit.Limiter, key, message string) bool {
+	retryAfter, err := limiter.Check(key)
+	if err != nil {
+		http.Error(w, "Failed to check attempts", http.StatusInternalServerError)
+		return true
+	}
+	if retryAfter <= 0 {
+		return false
+	}
+	
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Round(time.Second).Seconds())))
+	http.Error(w, message, http.StatusTooManyRequests)
+	return true
+}
+
+// loginFailed counts a failed login against the account and the client IP,
+// auditing any lockout it causes. user is nil when the username is unknown.
+func loginFailed(accountLimiter, ipLimiter ratelimit.Limiter, auditRepo *models.AuditRepository,
+	username string, user *models.User, ip string) {
+	userID := 0
+	if user != nil {
+		userID = user.ID
+	}
+	
+	locked, err := accountLimiter.Fail(AccountKey(username))
+	if err != nil {
+		log.Printf("Failed to record login attempt: %v", err)
+	} else if locked {
+		auditRepo.Record(&models.AuditEvent{
+			EventType: models.AuditAccountLocked,
+			UserID:    userID,
+			IPAddress: ip,
+			Details:   "username " + username,
+		})
+	}
+	
+	locked, err = ipLimiter.Fail(IPKey(ip))
+	if err != nil {
+		log.Printf("Failed to record login attempt: %v", err)
+	} else if locked {
+		auditRepo.Record(&models.AuditEvent{
+			EventType: models.AuditIPLocked,
+			IPAddress: ip,
+		})
+	}
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	// Parse request
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Refresh token required", http.StatusBadRequest)
+		return
+	}
+	
+	// Find the session the token belongs to
+	sessionID, err := auth.ParseRefreshToken(req.RefreshToken)
+	if err != nil {
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	session, err := h.SessionRepo.GetByID(sessionID)
+	if err != nil || !session.Active() {
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	
+	// A refresh token that was already rotated out is being replayed: assume
+	// it was stolen and end the session for everyone holding it
+	oldHash := auth.HashRefreshToken(req.RefreshToken)
+	if !hmac.Equal([]byte(oldHash), []byte(session.RefreshTokenHash)) {
+		h.SessionRepo.Revoke(session.ID)
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	
+	// Rotate the refresh token
+	refreshToken, refreshHash, err := auth.NewRefreshToken(session.ID)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	rotated, err := h.SessionRepo.Rotate(session.ID, oldHash, refreshHash, time.Now().Add(auth.RefreshTokenTTL))
+	if err != nil {
+		http.Error(w, "Failed to refresh session", http.StatusInternalServerError)
+		return
+	}
+	if !rotated {
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
 		return
 	}
 	
-	// Generate token
-	token, err := auth.GenerateToken(user.ID, user.Username, user.Role)
+	// Issue an access token with the user's current role
+	user, err := h.UserRepo.GetByID(session.UserID)
+	if err != nil || user.Status != models.StatusActive {
+		h.SessionRepo.Revoke(session.ID)
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	token, err := auth.GenerateToken(user.ID, user.Username, user.Role, session.ID)
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
@@ -123,7 +376,48 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(AuthResponse{
-		Token: token,
-		User:  user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(auth.AccessTokenTTL.Seconds()),
+		User:         user,
 	})
 }
+
+// Logout revokes the caller's current session
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	if claims.SessionID != "" {
+		if err := h.SessionRepo.Revoke(claims.SessionID); err != nil {
+			http.Error(w, "Failed to log out", http.StatusInternalServerError)
+			return
+		}
+	}
+	
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokedSessions lists sessions revoked within one access token lifetime so
+// other services can reject access tokens that have not yet expired
+func (h *AuthHandler) RevokedSessions(w http.ResponseWriter, r *http.Request) {
+	ids, err := h.SessionRepo.RevokedSince(time.Now().Add(-auth.AccessTokenTTL - time.Minute))
+	if err != nil {
+		http.Error(w, "Failed to list revoked sessions", http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sharedauth.RevokedSessions{SessionIDs: ids})
+}
+
+// JWKS publishes the public keys other services verify tokens with
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(sharedauth.DefaultKeyring().JWKS())
+}
@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	sharedauth "github.com/cardoza1991/church-management-system/pkg/auth"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/services/user-service/internal/models"
+)
+
+// GroupHandler handles ministry group requests
+type GroupHandler struct {
+	GroupRepo *models.GroupRepository
+	UserRepo  *models.UserRepository
+}
+
+// GroupRequest represents a new group
+type GroupRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ParentID    int    `json:"parent_id"`
+	LeaderID    int    `json:"leader_id"`
+}
+
+// UpdateGroupRequest represents changes to a group. Omitted fields are left
+// unchanged; a parent_id or leader_id of 0 clears it.
+type UpdateGroupRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	ParentID    *int    `json:"parent_id,omitempty"`
+	LeaderID    *int    `json:"leader_id,omitempty"`
+}
+
+// ListGroups returns every group; clients build the tree from parent_id
+func (h *GroupHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.GroupRepo.List()
+	if err != nil {
+		http.Error(w, "Failed to fetch groups: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if groups == nil {
+		groups = []*models.Group{}
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, groups)
+}
+
+// GetGroup returns a group by ID
+func (h *GroupHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Fetch group from repository
+	group, err := h.GroupRepo.GetByID(id)
+	if err != nil {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, group)
+}
+
+// CreateGroup adds a new group
+func (h *GroupHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	// Parse request
+	var req GroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	group := &models.Group{
+		Name:        req.Name,
+		Description: req.Description,
+		ParentID:    req.ParentID,
+		LeaderID:    req.LeaderID,
+	}
+	if !h.validateGroup(w, group) {
+		return
+	}
+	
+	// Save to database
+	if err := h.GroupRepo.Create(group); err != nil {
+		http.Error(w, "Failed to create group: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusCreated, group)
+}
+
+// UpdateGroup renames a group, moves it in the tree or changes its leader
+func (h *GroupHandler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Parse request
+	var req UpdateGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Fetch existing group
+	group, err := h.GroupRepo.GetByID(id)
+	if err != nil {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	
+	// Apply changes
+	if req.Name != nil {
+		group.Name = *req.Name
+	}
+	if req.Description != nil {
+		group.Description = *req.Description
+	}
+	if req.LeaderID != nil {
+		group.LeaderID = *req.LeaderID
+	}
+	if req.ParentID != nil {
+		group.ParentID = *req.ParentID
+	}
+	if !h.validateGroup(w, group) {
+		return
+	}
+	
+	// A group cannot be moved below itself
+	if req.ParentID != nil && group.ParentID != 0 {
+		groups, err := h.GroupRepo.List()
+		if err != nil {
+			http.Error(w, "Failed to check group tree: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if models.MovesBelowItself(groups, group.ID, group.ParentID) {
+			http.Error(w, "A group cannot be moved below itself", http.StatusBadRequest)
+			return
+		}
+	}
+	
+	// Save to database
+	if err := h.GroupRepo.Update(group); err != nil {
+		http.Error(w, "Failed to update group: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, group)
+}
+
+// DeleteGroup removes a group that has no sub-groups
+func (h *GroupHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Delete from database
+	if err := h.GroupRepo.Delete(id); err != nil {
+		if errors.Is(err, models.ErrGroupHasChildren) {
+			http.Error(w, "Move or delete the group's sub-groups first", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	
+	// Return response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListGroupMembers returns the members of a group to its leaders and to
+// users who manage groups or users
+func (h *GroupHandler) ListGroupMembers(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Check access
+	if !h.canView(w, r, id) {
+		return
+	}
+	
+	// Fetch members from repository
+	members, err := h.GroupRepo.Members(id)
+	if err != nil {
+		http.Error(w, "Failed to fetch members: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if members == nil {
+		members = []*models.User{}
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, members)
+}
+
+// AddGroupMember puts a user in a group
+func (h *GroupHandler) AddGroupMember(w http.ResponseWriter, r *http.Request) {
+	groupID, userID, ok := h.membershipIDs(w, r)
+	if !ok {
+		return
+	}
+	
+	if _, err := h.UserRepo.GetByID(userID); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	
+	if err := h.GroupRepo.AddMember(groupID, userID); err != nil {
+		http.Error(w, "Failed to add member: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Member added",
+	})
+}
+
+// RemoveGroupMember takes a user out of a group
+func (h *GroupHandler) RemoveGroupMember(w http.ResponseWriter, r *http.Request) {
+	groupID, userID, ok := h.membershipIDs(w, r)
+	if !ok {
+		return
+	}
+	
+	if err := h.GroupRepo.RemoveMember(groupID, userID); err != nil {
+		http.Error(w, "Membership not found", http.StatusNotFound)
+		return
+	}
+	
+	// Return response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDownline returns the groups the current user leads, the groups below
+// them and everyone in those groups. Other services call it with the caller's
+// token to scope what a leader may see.
+func (h *GroupHandler) GetDownline(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	groupIDs, err := h.GroupRepo.LedSubtree(claims.UserID)
+	if err != nil {
+		http.Error(w, "Failed to fetch groups: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	h.respondDownline(w, groupIDs, claims.UserID)
+}
+
+// GetGroupDownline returns a group, the groups below it and everyone in them
+func (h *GroupHandler) GetGroupDownline(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Check access
+	if !h.canView(w, r, id) {
+		return
+	}
+	
+	groupIDs, err := h.GroupRepo.Subtree(id)
+	if err != nil {
+		http.Error(w, "Failed to fetch groups: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	h.respondDownline(w, groupIDs, 0)
+}
+
+// respondDownline writes the users in groupIDs, leaving out excludeID
+func (h *GroupHandler) respondDownline(w http.ResponseWriter, groupIDs []int, excludeID int) {
+	users, err := h.GroupRepo.UsersInGroups(groupIDs, excludeID)
+	if err != nil {
+		http.Error(w, "Failed to fetch users: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	userIDs := []int{}
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+	if groupIDs == nil {
+		groupIDs = []int{}
+	}
+	if users == nil {
+		users = []*models.User{}
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"group_ids": groupIDs,
+		"user_ids":  userIDs,
+		"users":     users,
+	})
+}
+
+// canView reports whether the current user may see who is in a group.
+// Anyone who may not gets a 404, as if the group did not exist.
+func (h *GroupHandler) canView(w http.ResponseWriter, r *http.Request, groupID int) bool {
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	
+	if _, err := h.GroupRepo.GetByID(groupID); err != nil {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return false
+	}
+	
+	visible, err := groupVisible(claims.Role, groupID, func() ([]int, error) {
+		return h.GroupRepo.LedSubtree(claims.UserID)
+	})
+	if err != nil {
+		http.Error(w, "Failed to fetch groups: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if !visible {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// groupVisible decides who may see who is in a group: those who manage
+// groups or users, and leaders of the group or a group above it. led returns
+// the groups the user leads and those below them; it is only called for
+// roles that need it.
+func groupVisible(role string, groupID int, led func() ([]int, error)) (bool, error) {
+	if sharedauth.Can(role, sharedauth.GroupsWrite) || sharedauth.Can(role, sharedauth.UsersRead) {
+		return true, nil
+	}
+	
+	ids, err := led()
+	if err != nil {
+		return false, err
+	}
+	return containsID(ids, groupID), nil
+}
+
+// membershipIDs reads the group and user IDs of a membership route and checks
+// the group exists
+func (h *GroupHandler) membershipIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	groupID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	userID, err := strconv.Atoi(vars["userId"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	
+	if _, err := h.GroupRepo.GetByID(groupID); err != nil {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return 0, 0, false
+	}
+	return groupID, userID, true
+}
+
+// validateGroup checks a group's name and that its parent and leader exist
+func (h *GroupHandler) validateGroup(w http.ResponseWriter, group *models.Group) bool {
+	if group.Name == "" {
+		http.Error(w, "Group name is required", http.StatusBadRequest)
+		return false
+	}
+	if group.ParentID != 0 {
+		if _, err := h.GroupRepo.GetByID(group.ParentID); err != nil {
+			http.Error(w, "Parent group not found", http.StatusBadRequest)
+			return false
+		}
+	}
+	if group.LeaderID != 0 {
+		if _, err := h.UserRepo.GetByID(group.LeaderID); err != nil {
+			http.Error(w, "Leader not found", http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
+
+func containsID(ids []int, id int) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	sharedauth "github.com/cardoza1991/church-management-system/pkg/auth"
+)
+
+func TestGroupVisible(t *testing.T) {
+	led := []int{4, 7, 9} // groups the user leads and those below them
+
+	tests := []struct {
+		role    string
+		groupID int
+		want    bool
+		asksLed bool
+	}{
+		{sharedauth.RoleAdmin, 1, true, false},
+		{sharedauth.RoleOverseer, 1, true, false},
+		{sharedauth.RoleGroupLeader, 7, true, true},
+		{sharedauth.RoleGroupLeader, 1, false, true},
+		{sharedauth.RoleMember, 9, true, true}, // members can lead groups too
+		{sharedauth.RoleMember, 1, false, true},
+	}
+
+	for _, tt := range tests {
+		asked := false
+		got, err := groupVisible(tt.role, tt.groupID, func() ([]int, error) {
+			asked = true
+			return led, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("groupVisible(%s, %d) = %v, want %v", tt.role, tt.groupID, got, tt.want)
+		}
+		if asked != tt.asksLed {
+			t.Errorf("groupVisible(%s, %d) looked up led groups: %v, want %v", tt.role, tt.groupID, asked, tt.asksLed)
+		}
+	}
+}
+
+func TestGroupVisibleError(t *testing.T) {
+	lookupErr := errors.New("connection refused")
+	_, err := groupVisible(sharedauth.RoleGroupLeader, 1, func() ([]int, error) { return nil, lookupErr })
+	if !errors.Is(err, lookupErr) {
+		t.Errorf("err = %v, want %v", err, lookupErr)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/services/user-service/internal/auth"
+	"github.com/cardoza1991/church-management-system/services/user-service/internal/models"
+)
+
+// checkNewPassword reports whether user may set password. If not, it responds
+// with 422 and the list of policy violations. Users that have not been saved
+// yet have no password history to check.
+func checkNewPassword(w http.ResponseWriter, repo *models.UserRepository, user *models.User, password string) bool {
+	violations := auth.CheckPasswordPolicy(password, user.Username, user.Email)
+
+	if user.ID != 0 && auth.PasswordHistorySize > 0 {
+		history, err := repo.PasswordHistory(user.ID, auth.PasswordHistorySize)
+		if err != nil {
+			http.Error(w, "Failed to check password history: "+err.Error(), http.StatusInternalServerError)
+			return false
+		}
+		// Users from before the history was kept still have their current password
+		history = append(history, user.PasswordHash)
+		if auth.PasswordMatchesAny(password, history) {
+			violations = append(violations, auth.PasswordViolation{
+				Code:    auth.PasswordReused,
+				Message: "Password was used recently",
+			})
+		}
+	}
+	
+	if len(violations) == 0 {
+		return true
+	}
+	middleware.RespondJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+		"error":      "Password does not meet the password policy",
+		"violations": violations,
+	})
+	return false
+}
+
+// upgradePasswordHash rehashes a just-verified password if its hash was made
+// at a lower cost than configured. Failures only delay the upgrade.
+func upgradePasswordHash(repo *models.UserRepository, user *models.User, password string) {
+	if !auth.NeedsRehash(user.PasswordHash) {
+		return
+	}
+	hash, err := auth.HashPassword(password)
+	if err == nil {
+		err = repo.UpgradePasswordHash(user.ID, user.PasswordHash, hash)
+	}
+	if err != nil {
+		log.Printf("Failed to upgrade password hash for user %d: %v", user.ID, err)
+		return
+	}
+	user.PasswordHash = hash
+}
@@ -0,0 +1,502 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/services/user-service/internal/auth"
+	"github.com/cardoza1991/church-management-system/services/user-service/internal/models"
+)
+
+// recoveryCodeCount is how many recovery codes a user gets at a time
+const recoveryCodeCount = 10
+
+// TwoFactorChallenge is returned by Login instead of tokens when a second
+// factor is needed
+type TwoFactorChallenge struct {
+	TwoFactorRequired  bool   `json:"two_factor_required"`
+	EnrollmentRequired bool   `json:"enrollment_required"` // the role requires 2FA but the user has not set it up
+	Challenge          string `json:"challenge"`
+	ExpiresIn          int    `json:"expires_in"`
+}
+
+// TwoFactorLoginRequest completes a login with a TOTP code or a recovery code
+type TwoFactorLoginRequest struct {
+	Challenge    string `json:"challenge"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// TwoFactorRequest carries a second factor for managing 2FA while logged in
+type TwoFactorRequest struct {
+	Password     string `json:"password,omitempty"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// TOTPEnrollment is the secret an authenticator app needs
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// startChallenge responds with a login challenge for the user's second factor
+func (h *AuthHandler) startChallenge(w http.ResponseWriter, user *models.User) {
+	token, hash, err := auth.NewAccountToken()
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	
+	err = h.TokenRepo.Create(&models.AccountToken{
+		TokenHash: hash,
+		UserID:    user.ID,
+		Purpose:   models.TokenLoginChallenge,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(auth.LoginChallengeTTL),
+	})
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	
+	middleware.RespondJSON(w, http.StatusOK, TwoFactorChallenge{
+		TwoFactorRequired:  true,
+		EnrollmentRequired: !user.TwoFactor,
+		Challenge:          token,
+		ExpiresIn:          int(auth.LoginChallengeTTL.Seconds()),
+	})
+}
+
+// challengeUser returns the active user a login challenge belongs to
+func (h *AuthHandler) challengeUser(challenge string) (*models.User, error) {
+	token, err := h.TokenRepo.Get(auth.HashToken(challenge), models.TokenLoginChallenge)
+	if err != nil {
+		return nil, err
+	}
+	user, err := h.UserRepo.GetByID(token.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != models.StatusActive {
+		return nil, errors.New("account cannot log in")
+	}
+	return user, nil
+}
+
+// LoginTwoFactor completes a login with the second factor. Users whose role
+// requires 2FA but who have not enrolled confirm their new authenticator here,
+// and receive their recovery codes with the tokens.
+func (h *AuthHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// Parse request
+	var req TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Challenge == "" {
+		http.Error(w, "Challenge required", http.StatusBadRequest)
+		return
+	}
+	
+	// Find the user who passed the password step
+	user, err := h.challengeUser(req.Challenge)
+	if err != nil {
+		http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+	
+	// Wrong codes count towards the account lockout like wrong passwords
+	ip := clientIP(r)
+	if locked(w, h.AccountLimiter, AccountKey(user.Username), "Account temporarily locked after too many failed logins") {
+		return
+	}
+	
+	totp, err := h.UserRepo.GetTOTP(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to load two-factor settings", http.StatusInternalServerError)
+		return
+	}
+	
+	// Check the code, finishing enrollment if this is the user's first one
+	var recoveryCodes []string
+	var ok bool
+	if totp.Enabled {
+		ok, err = h.verifySecondFactor(user.ID, totp, req.Code, req.RecoveryCode)
+	} else {
+		if totp.Secret == "" {
+			http.Error(w, "Two-factor enrollment required: call /login/2fa/enroll first", http.StatusBadRequest)
+			return
+		}
+		recoveryCodes, ok, err = h.confirmEnrollment(user.ID, totp, req.Code)
+	}
+	if err != nil {
+		http.Error(w, "Failed to verify code: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		loginFailed(h.AccountLimiter, h.IPLimiter, h.AuditRepo, user.Username, user, ip)
+		http.Error(w, "Invalid two-factor code", http.StatusUnauthorized)
+		return
+	}
+	
+	// The challenge is good for one login only
+	if _, err := h.TokenRepo.Consume(auth.HashToken(req.Challenge), models.TokenLoginChallenge); err != nil {
+		http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+	if err := h.AccountLimiter.Reset(AccountKey(user.Username)); err != nil {
+		log.Printf("Failed to reset login attempts for user %d: %v", user.ID, err)
+	}
+	
+	// Start a session and generate tokens
+	resp, err := h.startSession(r, user)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	resp.User.TwoFactor = true
+	resp.RecoveryCodes = recoveryCodes
+	
+	// Return response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// LoginEnrollTwoFactor starts TOTP enrollment during a login challenge, for
+// users whose role requires 2FA
+func (h *AuthHandler) LoginEnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// Parse request
+	var req TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Challenge == "" {
+		http.Error(w, "Challenge required", http.StatusBadRequest)
+		return
+	}
+	
+	// Find the user who passed the password step
+	user, err := h.challengeUser(req.Challenge)
+	if err != nil {
+		http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+	
+	h.startEnrollment(w, user)
+}
+
+// GetTwoFactor reports the current user's two-factor status
+func (h *AuthHandler) GetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	totp, err := h.UserRepo.GetTOTP(claims.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	remaining, err := h.UserRepo.CountRecoveryCodes(claims.UserID)
+	if err != nil {
+		http.Error(w, "Failed to count recovery codes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"enabled":                  totp.Enabled,
+		"required":                 auth.TwoFactorRequired(claims.Role),
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// EnrollTwoFactor generates a TOTP secret for the current user. It takes
+// effect once confirmed with ConfirmTwoFactor.
+func (h *AuthHandler) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	user, err := h.UserRepo.GetByID(claims.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	
+	h.startEnrollment(w, user)
+}
+
+// startEnrollment stores a fresh secret and responds with it
+func (h *AuthHandler) startEnrollment(w http.ResponseWriter, user *models.User) {
+	if user.TwoFactor {
+		http.Error(w, "Two-factor authentication already enabled", http.StatusConflict)
+		return
+	}
+	
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		http.Error(w, "Failed to generate secret", http.StatusInternalServerError)
+		return
+	}
+	if err := h.UserRepo.StartTOTPEnrollment(user.ID, secret); err != nil {
+		http.Error(w, "Failed to start enrollment: "+err.Error(), http.StatusConflict)
+		return
+	}
+	
+	middleware.RespondJSON(w, http.StatusOK, TOTPEnrollment{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(user.Username, secret),
+	})
+}
+
+// ConfirmTwoFactor enables 2FA once the user proves their authenticator works,
+// and returns their recovery codes. The codes are not shown again.
+func (h *AuthHandler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Parse request
+	var req TwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Code required", http.StatusBadRequest)
+		return
+	}
+	
+	totp, err := h.UserRepo.GetTOTP(claims.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if totp.Enabled {
+		http.Error(w, "Two-factor authentication already enabled", http.StatusConflict)
+		return
+	}
+	if totp.Secret == "" {
+		http.Error(w, "Start enrollment first", http.StatusBadRequest)
+		return
+	}
+	
+	// Check the code and enable
+	codes, ok, err := h.confirmEnrollment(claims.UserID, totp, req.Code)
+	if err != nil {
+		http.Error(w, "Failed to enable two-factor authentication: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Invalid two-factor code", http.StatusBadRequest)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor turns 2FA off for the current user, unless their role requires it
+func (h *AuthHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if auth.TwoFactorRequired(claims.Role) {
+		http.Error(w, "Two-factor authentication is required for your role", http.StatusForbidden)
+		return
+	}
+	
+	// Parse request
+	var req TwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Require both the password and a second factor. Wrong ones count towards
+	// the account lockout like failed logins.
+	user, err := h.UserRepo.GetByID(claims.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if locked(w, h.AccountLimiter, AccountKey(user.Username), "Account temporarily locked after too many failed logins") {
+		return
+	}
+	if !user.CheckPassword(req.Password) {
+		loginFailed(h.AccountLimiter, h.IPLimiter, h.AuditRepo, user.Username, user, clientIP(r))
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	if !h.checkSecondFactor(w, r, user, req) {
+		return
+	}
+	
+	// Disable
+	if err := h.UserRepo.DisableTOTP(user.ID); err != nil {
+		http.Error(w, "Failed to disable two-factor authentication: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Parse request
+	var req TwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Require a second factor
+	user, err := h.UserRepo.GetByID(claims.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !h.checkSecondFactor(w, r, user, req) {
+		return
+	}
+	
+	// Replace the codes
+	codes, hashes, err := auth.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		http.Error(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+		return
+	}
+	if err := h.UserRepo.ReplaceRecoveryCodes(user.ID, hashes); err != nil {
+		http.Error(w, "Failed to store recovery codes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
+
+// ResetTwoFactor lets an admin turn off 2FA for a user who lost their
+// authenticator and recovery codes. Users whose role requires 2FA enroll
+// again at their next login.
+func (h *AuthHandler) ResetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// Get admin from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.UserRepo.GetByID(id); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	
+	// Disable
+	if err := h.UserRepo.DisableTOTP(id); err != nil {
+		http.Error(w, "Failed to reset two-factor authentication: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.AuditRepo.Record(&models.AuditEvent{
+		EventType: models.AuditTwoFactorReset,
+		UserID:    id,
+		ActorID:   claims.UserID,
+		IPAddress: clientIP(r),
+	})
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Two-factor authentication reset",
+	})
+}
+
+// checkSecondFactor verifies the code in req against the user's enabled 2FA,
+// responding with an error and returning false if it does not match. Wrong
+// codes count towards the account lockout like failed logins.
+func (h *AuthHandler) checkSecondFactor(w http.ResponseWriter, r *http.Request, user *models.User, req TwoFactorRequest) bool {
+	if locked(w, h.AccountLimiter, AccountKey(user.Username), "Account temporarily locked after too many failed logins") {
+		return false
+	}
+	totp, err := h.UserRepo.GetTOTP(user.ID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return false
+	}
+	if !totp.Enabled {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusConflict)
+		return false
+	}
+	
+	ok, err := h.verifySecondFactor(user.ID, totp, req.Code, req.RecoveryCode)
+	if err != nil {
+		http.Error(w, "Failed to verify code: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if !ok {
+		loginFailed(h.AccountLimiter, h.IPLimiter, h.AuditRepo, user.Username, user, clientIP(r))
+		http.Error(w, "Invalid two-factor code", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// verifySecondFactor checks a TOTP code, or a recovery code if one is given,
+// using it up so it cannot be replayed
+func (h *AuthHandler) verifySecondFactor(userID int, totp *models.TOTP, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		return h.UserRepo.UseRecoveryCode(userID, auth.HashRecoveryCode(recoveryCode))
+	}
+	
+	step, ok := auth.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok || step <= totp.LastStep {
+		return false, nil
+	}
+	return h.UserRepo.UseTOTPStep(userID, step)
+}
+
+// confirmEnrollment enables 2FA if code matches the pending secret, returning
+// the new recovery codes
+func (h *AuthHandler) confirmEnrollment(userID int, totp *models.TOTP, code string) ([]string, bool, error) {
+	step, ok := auth.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return nil, false, nil
+	}
+	
+	codes, hashes, err := auth.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := h.UserRepo.EnableTOTP(userID, step, hashes); err != nil {
+		return nil, false, err
+	}
+	return codes, true, nil
+}
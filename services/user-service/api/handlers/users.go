@@ -2,20 +2,78 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
+	sharedauth "github.com/cardoza1991/church-management-system/pkg/auth"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
 	"github.com/cardoza1991/church-management-system/services/user-service/internal/models"
+	"github.com/cardoza1991/church-management-system/services/user-service/internal/ratelimit"
 )
 
 // UserHandler handles user-related requests
 type UserHandler struct {
-	UserRepo *models.UserRepository
+	UserRepo    *models.UserRepository
+	SessionRepo *models.SessionRepository
+	Accounts    *AccountHandler // re-verifies changed email addresses
+	
+	AccountLimiter ratelimit.Limiter
+	IPLimiter      ratelimit.Limiter
+	AuditRepo      *models.AuditRepository
 }
 
-// GetUser returns a user by ID
+// ListUsers returns a page of users, optionally filtered by search text and status
+func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	// Get pagination parameters
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+	
+	limit := 20 // Default limit
+	if limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+	
+	offset := 0 // Default offset
+	if offsetStr != "" {
+		parsedOffset, err := strconv.Atoi(offsetStr)
+		if err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+	
+	// Fetch users from repository
+	search := r.URL.Query().Get("search")
+	status := r.URL.Query().Get("status")
+	users, total, err := h.UserRepo.List(search, status, limit, offset)
+	if err != nil {
+		http.Error(w, "Failed to fetch users: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"users":  users,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetUser returns a user by ID. Users without users:read only see themselves.
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
 	// Get ID from URL
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -24,13 +82,17 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// Example response for demonstration
-	user := &models.User{
-		ID:       id,
-		Username: "user" + vars["id"],
-		Email:    "user" + vars["id"] + "@example.com",
-		Role:     "member",
-		FullName: "User " + vars["id"],
+	// Check access
+	if id != claims.UserID && !sharedauth.Can(claims.Role, sharedauth.UsersRead) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	
+	// Fetch user from repository
+	user, err := h.UserRepo.GetByID(id)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
 	}
 	
 	// Return response
@@ -38,10 +100,278 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
+// UpdateUserRequest represents changes to a user's profile.
+// Omitted fields are left unchanged.
+type UpdateUserRequest struct {
+	Email    *string `json:"email,omitempty"`
+	FullName *string `json:"full_name,omitempty"`
+	Phone    *string `json:"phone,omitempty"`
+}
+
+// UpdateUser modifies a user's profile
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	
+	h.updateProfile(w, r, id)
+}
+
+// UpdateSelf modifies the current user's profile
+func (h *UserHandler) UpdateSelf(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	h.updateProfile(w, r, claims.UserID)
+}
+
+// updateProfile applies an UpdateUserRequest to the user with the given ID
+func (h *UserHandler) updateProfile(w http.ResponseWriter, r *http.Request, id int) {
+	// Parse request
+	var req UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	
+	// Fetch existing user
+	user, err := h.UserRepo.GetByID(id)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	
+	// Apply changes
+	emailChanged := req.Email != nil && *req.Email != user.Email
+	if req.Email != nil {
+		user.Email = *req.Email
+	}
+	if req.FullName != nil {
+		user.FullName = *req.FullName
+	}
+	if req.Phone != nil {
+		user.Phone = *req.Phone
+	}
+	if user.Email == "" || user.FullName == "" {
+		http.Error(w, "Email and full name cannot be empty", http.StatusBadRequest)
+		return
+	}
+	
+	// Save to database
+	if err := h.UserRepo.Update(user); err != nil {
+		if errors.Is(err, models.ErrEmailTaken) {
+			http.Error(w, "Email already in use", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to update user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// A new address has to be confirmed again
+	if emailChanged {
+		if err := h.Accounts.SendVerification(user); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, user)
+}
+
+// ChangePasswordRequest represents a request to change the current user's password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ChangePassword sets a new password for the current user after checking the
+// current one, and logs out their other sessions
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Parse request
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		http.Error(w, "Current and new password required", http.StatusBadRequest)
+		return
+	}
+	
+	// Check the current password. Wrong ones count towards the account lockout
+	// like failed logins.
+	user, err := h.UserRepo.GetByID(claims.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if locked(w, h.AccountLimiter, AccountKey(user.Username), "Account temporarily locked after too many failed logins") {
+		return
+	}
+	if !user.CheckPassword(req.CurrentPassword) {
+		loginFailed(h.AccountLimiter, h.IPLimiter, h.AuditRepo, user.Username, user, clientIP(r))
+		http.Error(w, "Current password is incorrect", http.StatusUnauthorized)
+		return
+	}
+	
+	// Check the new password against the policy
+	if !checkNewPassword(w, h.UserRepo, user, req.NewPassword) {
+		return
+	}
+	
+	// Set the new password
+	if err := h.UserRepo.SetPassword(user.ID, req.NewPassword); err != nil {
+		http.Error(w, "Failed to change password: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Keep this session but log out everywhere else
+	if err := h.SessionRepo.RevokeOthersForUser(user.ID, claims.SessionID); err != nil {
+		http.Error(w, "Failed to revoke sessions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Password changed successfully",
+	})
+}
+
+// DeactivateUser blocks a user from logging in and ends their sessions,
+// keeping their account and history
+func (h *UserHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, models.StatusDeactivated)
+}
+
+// ReactivateUser lets a deactivated user log in again
+func (h *UserHandler) ReactivateUser(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, models.StatusActive)
+}
+
+// setStatus moves a user between the active and deactivated states
+func (h *UserHandler) setStatus(w http.ResponseWriter, r *http.Request, status string) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	if id == claims.UserID {
+		http.Error(w, "Cannot change the status of your own account", http.StatusForbidden)
+		return
+	}
+	
+	// Fetch existing user
+	user, err := h.UserRepo.GetByID(id)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	
+	// Pending accounts are approved by granting a role, not reactivated
+	from := models.StatusActive
+	if status == models.StatusActive {
+		from = models.StatusDeactivated
+	}
+	if user.Status != from {
+		http.Error(w, "User is "+user.Status, http.StatusConflict)
+		return
+	}
+	
+	// Update status
+	if err := h.UserRepo.SetStatus(id, status); err != nil {
+		http.Error(w, "Failed to update user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	user.Status = status
+	
+	// A deactivated user's existing tokens stop working too
+	if status == models.StatusDeactivated {
+		if err := h.SessionRepo.RevokeAllForUser(id); err != nil {
+			http.Error(w, "Failed to revoke sessions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, user)
+}
+
+// DeleteUser permanently removes a user
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	if id == claims.UserID {
+		http.Error(w, "Cannot delete your own account", http.StatusForbidden)
+		return
+	}
+	
+	// End the user's sessions. They are deleted with the user, so their
+	// revocations are kept for the other services to keep rejecting them
+	if err := h.SessionRepo.RevokeAllForUser(id); err != nil {
+		http.Error(w, "Failed to revoke sessions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.SessionRepo.KeepRevocations(id); err != nil {
+		http.Error(w, "Failed to revoke sessions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Delete from database
+	if err := h.UserRepo.Delete(id); err != nil {
+		if err.Error() == "user not found" {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "User deleted successfully",
+	})
+}
+
 // GetSelf returns the current user
 func (h *UserHandler) GetSelf(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (set by AuthMiddleware)
-	claims, ok := r.Context().Value("user").(*auth.Claims)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -58,3 +388,213 @@ func (h *UserHandler) GetSelf(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
 }
+
+
+// ListSessions returns a user's login sessions
+func (h *UserHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Fetch sessions
+	sessions, err := h.SessionRepo.ListByUser(id)
+	if err != nil {
+		http.Error(w, "Failed to fetch sessions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":  id,
+		"sessions": sessions,
+	})
+}
+
+// RevokeSession ends one of a user's sessions
+func (h *UserHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	// Get IDs from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Check that the session belongs to the user
+	session, err := h.SessionRepo.GetByID(vars["sessionId"])
+	if err != nil || session.UserID != id {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	
+	// Revoke it
+	if err := h.SessionRepo.Revoke(session.ID); err != nil {
+		http.Error(w, "Failed to revoke session: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Session revoked successfully",
+	})
+}
+
+// RevokeAllSessions ends every session a user has, e.g. when a volunteer leaves
+func (h *UserHandler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Revoke all sessions
+	if err := h.SessionRepo.RevokeAllForUser(id); err != nil {
+		http.Error(w, "Failed to revoke sessions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "All sessions revoked successfully",
+	})
+}
+
+// GrantRoleRequest represents a request to change a user's role
+type GrantRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// GrantRole changes a user's role, recording who granted it and when
+func (h *UserHandler) GrantRole(w http.ResponseWriter, r *http.Request) {
+	// Get granting user from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Parse request
+	var req GrantRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !models.ValidRole(req.Role) {
+		http.Error(w, "Invalid role", http.StatusBadRequest)
+		return
+	}
+	
+	// Nobody changes their own role
+	if id == claims.UserID {
+		http.Error(w, "Cannot change your own role", http.StatusForbidden)
+		return
+	}
+	
+	// Check the granter outranks both the current and the new role
+	user, err := h.UserRepo.GetByID(id)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !models.CanGrantRole(claims.Role, user.Role, req.Role) {
+		http.Error(w, "Insufficient permissions to grant this role", http.StatusForbidden)
+		return
+	}
+	
+	// Grant the role
+	grant, err := h.UserRepo.GrantRole(id, req.Role, claims.UserID)
+	if err != nil {
+		http.Error(w, "Failed to grant role: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Tokens carry the role, so end the user's sessions and make them log in
+	// again with the new one
+	if err := h.SessionRepo.RevokeAllForUser(id); err != nil {
+		http.Error(w, "Failed to revoke sessions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, grant)
+}
+
+// ListRoleGrants returns the history of a user's role changes
+func (h *UserHandler) ListRoleGrants(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Fetch grants
+	grants, err := h.UserRepo.ListRoleGrants(id)
+	if err != nil {
+		http.Error(w, "Failed to fetch role grants: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id": id,
+		"grants":  grants,
+	})
+}
+
+// UnlockUser lifts a lockout caused by failed logins
+func (h *UserHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	// Get admin from context (set by AuthMiddleware)
+	claims, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	
+	// Get ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	
+	// Fetch user
+	user, err := h.UserRepo.GetByID(id)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	
+	// Clear the account's failed logins
+	if err := h.AccountLimiter.Reset(AccountKey(user.Username)); err != nil {
+		http.Error(w, "Failed to unlock user: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.AuditRepo.Record(&models.AuditEvent{
+		EventType: models.AuditAccountUnlocked,
+		UserID:    user.ID,
+		ActorID:   claims.UserID,
+		IPAddress: clientIP(r),
+	})
+	
+	// Return response
+	middleware.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "User unlocked successfully",
+	})
+}
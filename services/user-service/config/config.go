@@ -1,42 +1,101 @@
 package config
 
 import (
-	"fmt"
-	"os"
+	"time"
+
+	sharedauth "github.com/cardoza1991/church-management-system/pkg/auth"
+	sharedconfig "github.com/cardoza1991/church-management-system/pkg/config"
 )
 
 // Config holds all configuration for the service
 type Config struct {
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
+	sharedconfig.DB
 	ServerPort string
+	Keys       sharedauth.KeyConfig // signing key plus keys still valid from before the last rotation
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// RegistrationMode is "open" to activate self-registered accounts immediately
+	// or "approval" to hold them until an admin or overseer grants a role
+	RegistrationMode string
+
+	// AppURL is the frontend address used in links sent by email
+	AppURL               string
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+
+	// Mail is sent through SMTP when SMTPHost is set, otherwise written to
+	// MailLogFile (or the log when that is empty too)
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+	MailLogFile  string
+
+	// LoginLimiter is "memory" for a single replica or "db" to share failed
+	// login counts across replicas
+	LoginLimiter         string
+	LoginMaxFailures     int // per account
+	LoginIPMaxFailures   int // per client IP
+	LoginFailureWindow   time.Duration
+	LoginLockoutDuration time.Duration
+
+	// Password reset requests allowed per address and per client IP within
+	// PasswordResetWindow
+	PasswordResetMaxRequests   int
+	PasswordResetIPMaxRequests int
+	PasswordResetWindow        time.Duration
+
+	// TwoFactorRequiredRoles must set up TOTP before they can log in
+	TwoFactorRequiredRoles []string
+	TOTPIssuer             string
+
+	// Password policy; BcryptCost increases are applied to existing hashes at login
+	PasswordMinLength   int
+	PasswordHistorySize int // previous passwords that may not be reused
+	BcryptCost          int
 }
 
 // Load returns a new Config struct populated with values from environment variables
 func Load() *Config {
 	return &Config{
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "3306"),
-		DBUser:     getEnv("DB_USER", "root"),
-		DBPassword: getEnv("DB_PASSWORD", ""),
-		DBName:     getEnv("DB_NAME", "church_mgmt"),
-		ServerPort: getEnv("PORT", "8080"),
-	}
-}
+		DB:         sharedconfig.LoadDB(),
+		ServerPort: sharedconfig.GetEnv("PORT", "8080"),
+		Keys:       sharedconfig.LoadKeys(""),
 
-// DSN returns a formatted database connection string
-func (c *Config) DSN() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", 
-		c.DBUser, c.DBPassword, c.DBHost, c.DBPort, c.DBName)
-}
+		AccessTokenTTL:  sharedconfig.GetEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: sharedconfig.GetEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		RegistrationMode: sharedconfig.GetEnv("REGISTRATION_MODE", "open"),
+
+		AppURL:               sharedconfig.GetEnv("APP_URL", "http://localhost:3000"),
+		PasswordResetTTL:     sharedconfig.GetEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		EmailVerificationTTL: sharedconfig.GetEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+
+		SMTPHost:     sharedconfig.GetEnv("SMTP_HOST", ""),
+		SMTPPort:     sharedconfig.GetEnv("SMTP_PORT", "587"),
+		SMTPUsername: sharedconfig.GetEnv("SMTP_USERNAME", ""),
+		SMTPPassword: sharedconfig.GetEnv("SMTP_PASSWORD", ""),
+		MailFrom:     sharedconfig.GetEnv("MAIL_FROM", "no-reply@localhost"),
+		MailLogFile:  sharedconfig.GetEnv("MAIL_LOG_FILE", ""),
+
+		LoginLimiter:         sharedconfig.GetEnv("LOGIN_LIMITER", "memory"),
+		LoginMaxFailures:     sharedconfig.GetEnvInt("LOGIN_MAX_FAILURES", 5),
+		LoginIPMaxFailures:   sharedconfig.GetEnvInt("LOGIN_IP_MAX_FAILURES", 20),
+		LoginFailureWindow:   sharedconfig.GetEnvDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		LoginLockoutDuration: sharedconfig.GetEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+
+		PasswordResetMaxRequests:   sharedconfig.GetEnvInt("PASSWORD_RESET_MAX_REQUESTS", 3),
+		PasswordResetIPMaxRequests: sharedconfig.GetEnvInt("PASSWORD_RESET_IP_MAX_REQUESTS", 10),
+		PasswordResetWindow:        sharedconfig.GetEnvDuration("PASSWORD_RESET_WINDOW", time.Hour),
+
+		TwoFactorRequiredRoles: sharedconfig.GetEnvList("TWO_FACTOR_REQUIRED_ROLES"),
+		TOTPIssuer:             sharedconfig.GetEnv("TOTP_ISSUER", "Church Management System"),
 
-// Helper function to get environment variable with a default value
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+		PasswordMinLength:   sharedconfig.GetEnvInt("PASSWORD_MIN_LENGTH", 10),
+		PasswordHistorySize: sharedconfig.GetEnvInt("PASSWORD_HISTORY_SIZE", 5),
+		BcryptCost:          sharedconfig.GetEnvInt("BCRYPT_COST", 10),
 	}
-	return defaultValue
 }
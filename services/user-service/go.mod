@@ -5,12 +5,13 @@ go 1.23.0
 toolchain go1.23.7
 
 require (
+	github.com/cardoza1991/church-management-system/pkg v0.0.0
 	github.com/go-sql-driver/mysql v1.9.0
 	github.com/gorilla/mux v1.8.1
+	github.com/rs/cors v1.11.1
 	golang.org/x/crypto v0.36.0
 )
 
-require (
-	filippo.io/edwards25519 v1.1.0 // indirect
-	github.com/rs/cors v1.11.1 // direct
-)
+require filippo.io/edwards25519 v1.1.0 // indirect
+
+replace github.com/cardoza1991/church-management-system/pkg => ../../pkg
@@ -1,97 +1,94 @@
 package auth
 
 import (
-	"errors"
-	"time"
-	
-	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
-	"encoding/base64"
-	"encoding/json"
+	"encoding/hex"
+	"errors"
 	"strings"
-)
+	"time"
 
-// Claims represents the JWT claims
-type Claims struct {
-	UserID   int    `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
-	ExpiresAt int64 `json:"exp"`
-}
+	sharedauth "github.com/cardoza1991/church-management-system/pkg/auth"
+)
 
-// For a real app, use a proper JWT library and store the secret securely
-var hmacSecret = []byte("your-secret-key")
+// Token lifetimes, overridable from configuration
+var (
+	AccessTokenTTL       = 15 * time.Minute
+	RefreshTokenTTL      = 30 * 24 * time.Hour
+	PasswordResetTTL     = time.Hour
+	EmailVerificationTTL = 48 * time.Hour
+)
 
-// GenerateToken creates a new JWT token for a user
-func GenerateToken(userID int, username, role string) (string, error) {
-	// Set expiration time - 24 hours from now
-	expirationTime := time.Now().Add(24 * time.Hour).Unix()
+// GenerateToken creates a new short-lived JWT access token for a user's session
+func GenerateToken(userID int, username, role, sessionID string) (string, error) {
+	// Set expiration time
+	expirationTime := time.Now().Add(AccessTokenTTL).Unix()
 	
 	// Create claims
-	claims := Claims{
+	claims := sharedauth.Claims{
 		UserID:    userID,
 		Username:  username,
 		Role:      role,
+		SessionID: sessionID,
+		Issuer:    sharedauth.Issuer,
 		ExpiresAt: expirationTime,
 	}
 	
 	// Create token
-	payload, err := json.Marshal(claims)
+	return sharedauth.SignToken(claims)
+}
+
+// NewSessionID returns a random session identifier
+func NewSessionID() (string, error) {
+	return randomHex(16)
+}
+
+// NewRefreshToken returns a refresh token for the session and the hash to store.
+// The token has the form "<session id>.<secret>"; only the hash of the whole
+// token is kept server-side.
+func NewRefreshToken(sessionID string) (token, hash string, err error) {
+	secret, err := randomHex(32)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
-	
-	// Base64 encode the header and payload
-	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
-	payload64 := base64.RawURLEncoding.EncodeToString(payload)
-	
-	// Create signature
-	h := hmac.New(sha256.New, hmacSecret)
-	h.Write([]byte(header + "." + payload64))
-	signature := base64.RawURLEncoding.EncodeToString(h.Sum(nil))
-	
-	// Combine to create token
-	token := header + "." + payload64 + "." + signature
-	
-	return token, nil
+	token = sessionID + "." + secret
+	return token, HashRefreshToken(token), nil
 }
 
-// VerifyToken validates a JWT token
-func VerifyToken(tokenString string) (*Claims, error) {
-	// Split the token
-	parts := strings.Split(tokenString, ".")
-	if len(parts) != 3 {
-		return nil, errors.New("invalid token format")
+// ParseRefreshToken extracts the session ID from a refresh token
+func ParseRefreshToken(token string) (string, error) {
+	sessionID, secret, ok := strings.Cut(token, ".")
+	if !ok || sessionID == "" || secret == "" {
+		return "", errors.New("invalid refresh token")
 	}
-	
-	// Get the header and payload
-	header, payload, signature := parts[0], parts[1], parts[2]
-	
-	// Verify signature
-	h := hmac.New(sha256.New, hmacSecret)
-	h.Write([]byte(header + "." + payload))
-	expectedSignature := base64.RawURLEncoding.EncodeToString(h.Sum(nil))
-	
-	if signature != expectedSignature {
-		return nil, errors.New("invalid token signature")
-	}
-	
-	// Decode payload
-	decodedPayload, err := base64.RawURLEncoding.DecodeString(payload)
+	return sessionID, nil
+}
+
+// HashRefreshToken returns the hex SHA-256 hash stored for a refresh token
+func HashRefreshToken(token string) string {
+	return HashToken(token)
+}
+
+// NewAccountToken returns a random single-use token for emailed links, such as
+// password resets and email verification, and the hash to store
+func NewAccountToken() (token, hash string, err error) {
+	token, err = randomHex(32)
 	if err != nil {
-		return nil, errors.New("failed to decode payload")
+		return "", "", err
 	}
-	
-	// Parse claims
-	var claims Claims
-	if err := json.Unmarshal(decodedPayload, &claims); err != nil {
-		return nil, errors.New("failed to parse claims")
-	}
-	
-	// Check if token is expired
-	if claims.ExpiresAt < time.Now().Unix() {
-		return nil, errors.New("token is expired")
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hex SHA-256 hash of a token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
-	
-	return &claims, nil
+	return hex.EncodeToString(b), nil
 }
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password policy, overridable from configuration
+var (
+	// PasswordMinLength is the fewest characters a new password may have
+	PasswordMinLength = 10
+	// PasswordHistorySize is how many previous passwords a user may not reuse
+	PasswordHistorySize = 5
+	// BcryptCost is the work factor for new hashes; older, cheaper hashes are
+	// upgraded the next time their owner logs in
+	BcryptCost = bcrypt.DefaultCost
+)
+
+// passwordMaxBytes is the most bcrypt will hash; anything longer would be
+// silently truncated
+const passwordMaxBytes = 72
+
+// Password violation codes
+const (
+	PasswordTooShort = "too_short"
+	PasswordTooLong  = "too_long"
+	PasswordCommon   = "common"
+	PasswordPersonal = "personal"
+	PasswordReused   = "reused"
+)
+
+// PasswordViolation describes one way a password breaks the policy
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var commonPasswords = parseCommonPasswords(commonPasswordList)
+
+func parseCommonPasswords(list string) map[string]bool {
+	passwords := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords[strings.ToLower(line)] = true
+	}
+	return passwords
+}
+
+// CheckPasswordPolicy returns the ways password breaks the policy. personal
+// lists values such as the username and email that the password may not contain.
+func CheckPasswordPolicy(password string, personal ...string) []PasswordViolation {
+	var violations []PasswordViolation
+
+	if n := len([]rune(password)); n < PasswordMinLength {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordTooShort,
+			Message: fmt.Sprintf("Password must be at least %d characters", PasswordMinLength),
+		})
+	}
+	if len(password) > passwordMaxBytes {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordTooLong,
+			Message: fmt.Sprintf("Password must be at most %d bytes", passwordMaxBytes),
+		})
+	}
+
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordCommon,
+			Message: "Password is too common",
+		})
+	}
+
+	for _, value := range personal {
+		// Compare the local part of email addresses
+		if i := strings.Index(value, "@"); i >= 0 {
+			value = value[:i]
+		}
+		value = strings.ToLower(strings.TrimSpace(value))
+		if len(value) >= 3 && strings.Contains(lower, value) {
+			violations = append(violations, PasswordViolation{
+				Code:    PasswordPersonal,
+				Message: "Password must not contain your username or email",
+			})
+			break
+		}
+	}
+
+	return violations
+}
+
+// PasswordMatchesAny reports whether password matches any of the given hashes
+func PasswordMatchesAny(password string, hashes []string) bool {
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// HashPassword hashes password at the configured cost
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// NeedsRehash reports whether hash was made at a lower cost than configured
+func NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && cost < BcryptCost
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func violationCodes(violations []PasswordViolation) []string {
+	var codes []string
+	for _, v := range violations {
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
+
+func TestCheckPasswordPolicy(t *testing.T) {
+	tests := []struct {
+		password string
+		want     []string
+	}{
+		{"correct horse battery", nil},
+		{"short", []string{PasswordTooShort}},
+		{"Password123", []string{PasswordCommon}},
+		{"JesusLovesMe", []string{PasswordCommon}},
+		{"my-jsmith-secret", []string{PasswordPersonal}},
+		{"xx-example-xx", nil}, // email domains are not personal
+		{strings.Repeat("a", 73), []string{PasswordTooLong}},
+	}
+
+	for _, tt := range tests {
+		got := violationCodes(CheckPasswordPolicy(tt.password, "jsmith", "jsmith@example.com"))
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("CheckPasswordPolicy(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestPasswordMatchesAny(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("old password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := []string{"not a hash", string(hash)}
+
+	if !PasswordMatchesAny("old password", hashes) {
+		t.Error("expected a match for a previous password")
+	}
+	if PasswordMatchesAny("new password", hashes) {
+		t.Error("unexpected match for a new password")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	defer func(cost int) { BcryptCost = cost }(BcryptCost)
+	BcryptCost = bcrypt.MinCost + 1
+
+	old, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if !NeedsRehash(string(old)) {
+		t.Error("expected a cheaper hash to need rehashing")
+	}
+
+	current, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if NeedsRehash(current) {
+		t.Error("hash at the configured cost should not need rehashing")
+	}
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B test vectors for SHA-1, truncated to six digits
+func TestValidateTOTP(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		step, ok := ValidateTOTP(secret, tt.code, time.Unix(tt.unix, 0))
+		if !ok {
+			t.Errorf("code %s rejected at %d", tt.code, tt.unix)
+		}
+		if step != tt.unix/30 {
+			t.Errorf("step = %d, want %d", step, tt.unix/30)
+		}
+	}
+
+	// One step of clock drift either way is tolerated, more is not
+	if _, ok := ValidateTOTP(secret, "287082", time.Unix(59+30, 0)); !ok {
+		t.Error("code from the previous step rejected")
+	}
+	if _, ok := ValidateTOTP(secret, "287082", time.Unix(59+90, 0)); ok {
+		t.Error("code from three steps ago accepted")
+	}
+	if _, ok := ValidateTOTP(secret, "28708", time.Unix(59, 0)); ok {
+		t.Error("short code accepted")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("NewRecoveryCodes: %v", err)
+	}
+	if len(codes) != 10 || len(hashes) != 10 {
+		t.Fatalf("got %d codes and %d hashes, want 10", len(codes), len(hashes))
+	}
+
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if seen[code] {
+			t.Errorf("duplicate code %s", code)
+		}
+		seen[code] = true
+
+		// Typed without the dash, in upper case
+		typed := code[:4] + code[5:]
+		if got := HashRecoveryCode(" " + strings.ToUpper(typed) + " "); got != hashes[i] {
+			t.Errorf("hash of %q does not match stored hash", typed)
+		}
+	}
+}
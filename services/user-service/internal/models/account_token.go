@@ -0,0 +1,100 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Account token purposes
+const (
+	TokenPasswordReset     = "password_reset"
+	TokenEmailVerification = "email_verification"
+	TokenLoginChallenge    = "login_challenge" // a password-verified login awaiting its second factor
+)
+
+// AccountToken is a single-use token emailed to a user. Only its hash is stored.
+type AccountToken struct {
+	TokenHash string
+	UserID    int
+	Purpose   string
+	Email     string // address the token was sent to
+	ExpiresAt time.Time
+}
+
+// AccountTokenRepository provides access to the account token store
+type AccountTokenRepository struct {
+	DB *sql.DB
+}
+
+// NewAccountTokenRepository creates a new AccountTokenRepository
+func NewAccountTokenRepository(db *sql.DB) *AccountTokenRepository {
+	return &AccountTokenRepository{DB: db}
+}
+
+// Create stores a new token, invalidating the user's earlier unused tokens
+// for the same purpose so only the latest link works
+func (r *AccountTokenRepository) Create(token *AccountToken) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE account_tokens SET used_at = NOW()
+		WHERE user_id = ? AND purpose = ? AND used_at IS NULL`, token.UserID, token.Purpose)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO account_tokens (token_hash, user_id, purpose, email, expires_at)
+		VALUES (?, ?, ?, ?, ?)`, token.TokenHash, token.UserID, token.Purpose, token.Email, token.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Consume marks a token as used and returns it. It fails if the token is
+// unknown, already used, expired or issued for a different purpose.
+func (r *AccountTokenRepository) Consume(hash, purpose string) (*AccountToken, error) {
+	result, err := r.DB.Exec(`UPDATE account_tokens SET used_at = NOW()
+		WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > NOW()`, hash, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected != 1 {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	token := &AccountToken{TokenHash: hash}
+	err = r.DB.QueryRow(`SELECT user_id, purpose, email, expires_at FROM account_tokens WHERE token_hash = ?`, hash).
+		Scan(&token.UserID, &token.Purpose, &token.Email, &token.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// Get returns a token that is still valid without using it up
+func (r *AccountTokenRepository) Get(hash, purpose string) (*AccountToken, error) {
+	token := &AccountToken{TokenHash: hash}
+	err := r.DB.QueryRow(`SELECT user_id, purpose, email, expires_at FROM account_tokens
+		WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > NOW()`, hash, purpose).
+		Scan(&token.UserID, &token.Purpose, &token.Email, &token.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("invalid or expired token")
+		}
+		return nil, err
+	}
+
+	return token, nil
+}
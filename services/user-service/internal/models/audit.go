@@ -0,0 +1,55 @@
+package models
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Audit event types
+const (
+	AuditAccountLocked   = "account_locked"
+	AuditIPLocked        = "ip_locked"
+	AuditAccountUnlocked = "account_unlocked"
+	AuditTwoFactorReset  = "two_factor_reset"
+)
+
+// AuditEvent records a security-relevant event
+type AuditEvent struct {
+	ID        int       `json:"id"`
+	EventType string    `json:"event_type"`
+	UserID    int       `json:"user_id,omitempty"`  // the account affected, if known
+	ActorID   int       `json:"actor_id,omitempty"` // the user who caused it, if any
+	IPAddress string    `json:"ip_address,omitempty"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditRepository provides access to the audit log
+type AuditRepository struct {
+	DB *sql.DB
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{DB: db}
+}
+
+// Record writes an event to the audit log and the service log. Failing to
+// store it is logged rather than returned so it never blocks the request.
+func (r *AuditRepository) Record(event *AuditEvent) {
+	log.Printf("audit: %s user=%d actor=%d ip=%s %s",
+		event.EventType, event.UserID, event.ActorID, event.IPAddress, event.Details)
+
+	_, err := r.DB.Exec(`INSERT INTO audit_events (event_type, user_id, actor_id, ip_address, details)
+		VALUES (?, ?, ?, ?, ?)`,
+		event.EventType, nullInt(event.UserID), nullInt(event.ActorID), event.IPAddress, event.Details)
+	if err != nil {
+		log.Printf("Failed to record audit event %s: %v", event.EventType, err)
+	}
+}
+
+// nullInt stores zero IDs as NULL
+func nullInt(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
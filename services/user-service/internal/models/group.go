@@ -0,0 +1,265 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Group is a ministry group. Groups form a tree through ParentID, and the
+// leader of a group oversees everyone in it and in the groups below it.
+type Group struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	ParentID    int       `json:"parent_id,omitempty"` // zero for top-level groups
+	LeaderID    int       `json:"leader_id,omitempty"` // zero when the group has no leader
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ErrGroupHasChildren is returned when deleting a group that still has sub-groups
+var ErrGroupHasChildren = errors.New("group has sub-groups")
+
+// MovesBelowItself reports whether giving groupID the parent parentID would
+// put the group below itself, walking up from parentID through groups. The
+// walk stops at a group it has already seen, so an existing cycle ends it.
+func MovesBelowItself(groups []*Group, groupID, parentID int) bool {
+	parents := make(map[int]int, len(groups))
+	for _, group := range groups {
+		parents[group.ID] = group.ParentID
+	}
+
+	seen := map[int]bool{}
+	for id := parentID; id != 0 && !seen[id]; id = parents[id] {
+		if id == groupID {
+			return true
+		}
+		seen[id] = true
+	}
+	return false
+}
+
+// GroupRepository provides access to the group store
+type GroupRepository struct {
+	DB *sql.DB
+}
+
+// NewGroupRepository creates a new GroupRepository
+func NewGroupRepository(db *sql.DB) *GroupRepository {
+	return &GroupRepository{DB: db}
+}
+
+// groupColumns lists the columns scanGroup expects, in order
+const groupColumns = `g.id, g.name, g.description, g.parent_id, g.leader_id,
+	(SELECT COUNT(*) FROM group_members m WHERE m.group_id = g.id), g.created_at, g.updated_at`
+
+// Create adds a new group
+func (r *GroupRepository) Create(group *Group) error {
+	result, err := r.DB.Exec(`INSERT INTO ministry_groups (name, description, parent_id, leader_id)
+		VALUES (?, ?, ?, ?)`, group.Name, group.Description, nullInt(group.ParentID), nullInt(group.LeaderID))
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	created, err := r.GetByID(int(id))
+	if err != nil {
+		return err
+	}
+	*group = *created
+	return nil
+}
+
+// GetByID finds a group by ID
+func (r *GroupRepository) GetByID(id int) (*Group, error) {
+	group, err := scanGroup(r.DB.QueryRow(`SELECT `+groupColumns+` FROM ministry_groups g WHERE g.id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("group not found")
+		}
+		return nil, err
+	}
+	return group, nil
+}
+
+// List returns every group ordered by name
+func (r *GroupRepository) List() ([]*Group, error) {
+	rows, err := r.DB.Query(`SELECT ` + groupColumns + ` FROM ministry_groups g ORDER BY g.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []*Group
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// Update saves a group's name, description, parent and leader
+func (r *GroupRepository) Update(group *Group) error {
+	_, err := r.DB.Exec(`UPDATE ministry_groups SET name = ?, description = ?, parent_id = ?, leader_id = ?
+		WHERE id = ?`, group.Name, group.Description, nullInt(group.ParentID), nullInt(group.LeaderID), group.ID)
+	return err
+}
+
+// Delete removes a group and its memberships. Groups with sub-groups must
+// have them moved or deleted first.
+func (r *GroupRepository) Delete(id int) error {
+	var children int
+	if err := r.DB.QueryRow(`SELECT COUNT(*) FROM ministry_groups WHERE parent_id = ?`, id).Scan(&children); err != nil {
+		return err
+	}
+	if children > 0 {
+		return ErrGroupHasChildren
+	}
+
+	result, err := r.DB.Exec(`DELETE FROM ministry_groups WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("group not found")
+	}
+	return nil
+}
+
+// AddMember puts a user in a group. Adding an existing member does nothing.
+func (r *GroupRepository) AddMember(groupID, userID int) error {
+	_, err := r.DB.Exec(`INSERT IGNORE INTO group_members (group_id, user_id) VALUES (?, ?)`, groupID, userID)
+	return err
+}
+
+// RemoveMember takes a user out of a group
+func (r *GroupRepository) RemoveMember(groupID, userID int) error {
+	result, err := r.DB.Exec(`DELETE FROM group_members WHERE group_id = ? AND user_id = ?`, groupID, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("membership not found")
+	}
+	return nil
+}
+
+// Members returns the users in a group ordered by name
+func (r *GroupRepository) Members(groupID int) ([]*User, error) {
+	return r.queryUsers(`SELECT `+userColumns+` FROM users
+		WHERE id IN (SELECT user_id FROM group_members WHERE group_id = ?)
+		ORDER BY full_name`, groupID)
+}
+
+// Subtree returns the ID of a group and of every group below it
+func (r *GroupRepository) Subtree(groupID int) ([]int, error) {
+	return r.subtree(`SELECT id FROM ministry_groups WHERE id = ?`, groupID)
+}
+
+// LedSubtree returns the groups a user leads and every group below them
+func (r *GroupRepository) LedSubtree(userID int) ([]int, error) {
+	return r.subtree(`SELECT id FROM ministry_groups WHERE leader_id = ?`, userID)
+}
+
+// subtree walks down the tree from the groups selected by roots. UNION
+// discards rows already seen, so the walk ends even if the tree has a cycle.
+func (r *GroupRepository) subtree(roots string, args ...interface{}) ([]int, error) {
+	rows, err := r.DB.Query(`WITH RECURSIVE subtree (id) AS (
+			`+roots+`
+			UNION
+			SELECT g.id FROM ministry_groups g JOIN subtree s ON g.parent_id = s.id
+		)
+		SELECT id FROM subtree ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// UsersInGroups returns the members and leaders of the given groups, except
+// for excludeID, ordered by name
+func (r *GroupRepository) UsersInGroups(groupIDs []int, excludeID int) ([]*User, error) {
+	if len(groupIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(groupIDs)), ", ")
+	args := []interface{}{excludeID}
+	for i := 0; i < 2; i++ {
+		for _, id := range groupIDs {
+			args = append(args, id)
+		}
+	}
+
+	return r.queryUsers(`SELECT `+userColumns+` FROM users
+		WHERE id <> ? AND (
+			id IN (SELECT user_id FROM group_members WHERE group_id IN (`+placeholders+`))
+			OR id IN (SELECT leader_id FROM ministry_groups WHERE id IN (`+placeholders+`))
+		)
+		ORDER BY full_name`, args...)
+}
+
+func (r *GroupRepository) queryUsers(query string, args ...interface{}) ([]*User, error) {
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func scanGroup(row rowScanner) (*Group, error) {
+	group := &Group{}
+	var description sql.NullString
+	var parentID, leaderID sql.NullInt64
+
+	err := row.Scan(&group.ID, &group.Name, &description, &parentID, &leaderID,
+		&group.MemberCount, &group.CreatedAt, &group.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	group.Description = description.String
+	group.ParentID = int(parentID.Int64)
+	group.LeaderID = int(leaderID.Int64)
+	return group, nil
+}
@@ -0,0 +1,55 @@
+package models
+
+import "testing"
+
+func TestMovesBelowItself(t *testing.T) {
+	// 1
+	// ├── 2
+	// │   └── 3
+	// │       └── 4
+	// └── 5
+	// 6
+	groups := []*Group{
+		{ID: 1},
+		{ID: 2, ParentID: 1},
+		{ID: 3, ParentID: 2},
+		{ID: 4, ParentID: 3},
+		{ID: 5, ParentID: 1},
+		{ID: 6},
+	}
+
+	tests := []struct {
+		name              string
+		groupID, parentID int
+		want              bool
+	}{
+		{"under itself", 2, 2, true},
+		{"under its child", 2, 3, true},
+		{"under a deeper descendant", 1, 4, true},
+		{"under a sibling", 2, 5, false},
+		{"under its parent", 3, 2, false},
+		{"under another tree", 2, 6, false},
+		{"to the top level", 2, 0, false},
+		{"under an unknown group", 2, 99, false},
+	}
+
+	for _, tt := range tests {
+		if got := MovesBelowItself(groups, tt.groupID, tt.parentID); got != tt.want {
+			t.Errorf("%s: MovesBelowItself(%d, %d) = %v, want %v", tt.name, tt.groupID, tt.parentID, got, tt.want)
+		}
+	}
+}
+
+func TestMovesBelowItselfExistingCycle(t *testing.T) {
+	groups := []*Group{
+		{ID: 1, ParentID: 2},
+		{ID: 2, ParentID: 1},
+		{ID: 3},
+	}
+	if MovesBelowItself(groups, 3, 1) {
+		t.Error("moving a group under an unrelated cycle was refused")
+	}
+	if !MovesBelowItself(groups, 2, 1) {
+		t.Error("moving a group under its own cycle was allowed")
+	}
+}
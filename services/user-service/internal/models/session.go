@@ -0,0 +1,210 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Session represents a login session backed by a rotating refresh token
+type Session struct {
+	ID               string     `json:"id"`
+	UserID           int        `json:"user_id"`
+	RefreshTokenHash string     `json:"-"`
+	UserAgent        string     `json:"user_agent,omitempty"`
+	IPAddress        string     `json:"ip_address,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	LastUsedAt       time.Time  `json:"last_used_at"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the session can still be refreshed
+func (s *Session) Active() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// SessionRepository provides access to the session store
+type SessionRepository struct {
+	DB *sql.DB
+}
+
+// NewSessionRepository creates a new SessionRepository
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{DB: db}
+}
+
+// Create adds a new session to the database
+func (r *SessionRepository) Create(session *Session) error {
+	query := `INSERT INTO sessions (id, user_id, refresh_token_hash, user_agent, ip_address, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err := r.DB.Exec(query, session.ID, session.UserID, session.RefreshTokenHash,
+		session.UserAgent, session.IPAddress, session.ExpiresAt)
+	return err
+}
+
+// GetByID finds a session by ID
+func (r *SessionRepository) GetByID(id string) (*Session, error) {
+	query := `SELECT id, user_id, refresh_token_hash, user_agent, ip_address,
+		created_at, last_used_at, expires_at, revoked_at
+		FROM sessions WHERE id = ?`
+
+	session, err := scanSession(r.DB.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("session not found")
+		}
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// ListByUser returns a user's sessions, most recently used first
+func (r *SessionRepository) ListByUser(userID int) ([]*Session, error) {
+	query := `SELECT id, user_id, refresh_token_hash, user_agent, ip_address,
+		created_at, last_used_at, expires_at, revoked_at
+		FROM sessions WHERE user_id = ? ORDER BY last_used_at DESC`
+
+	rows, err := r.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// Rotate replaces a session's refresh token hash. It only succeeds if oldHash
+// is still current, so a refresh token can be redeemed at most once.
+func (r *SessionRepository) Rotate(id, oldHash, newHash string, expiresAt time.Time) (bool, error) {
+	query := `UPDATE sessions
+		SET refresh_token_hash = ?, expires_at = ?, last_used_at = NOW()
+		WHERE id = ? AND refresh_token_hash = ? AND revoked_at IS NULL AND expires_at > NOW()`
+
+	result, err := r.DB.Exec(query, newHash, expiresAt, id, oldHash)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// Revoke ends a single session
+func (r *SessionRepository) Revoke(id string) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE id = ? AND revoked_at IS NULL`
+
+	_, err := r.DB.Exec(query, id)
+	return err
+}
+
+// RevokeAllForUser ends every active session belonging to a user
+func (r *SessionRepository) RevokeAllForUser(userID int) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE user_id = ? AND revoked_at IS NULL`
+
+	_, err := r.DB.Exec(query, userID)
+	return err
+}
+
+// RevokeOthersForUser ends every active session of a user except keepID
+func (r *SessionRepository) RevokeOthersForUser(userID int, keepID string) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE user_id = ? AND id <> ? AND revoked_at IS NULL`
+
+	_, err := r.DB.Exec(query, userID, keepID)
+	return err
+}
+
+// KeepRevocations copies a user's revoked, unexpired sessions to
+// revoked_sessions, which outlives the user, so that RevokedSince still lists
+// them once the user is deleted. Copies that have expired are cleared out.
+func (r *SessionRepository) KeepRevocations(userID int) error {
+	_, err := r.DB.Exec(`DELETE FROM revoked_sessions WHERE expires_at < NOW()`)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT IGNORE INTO revoked_sessions (session_id, revoked_at, expires_at)
+	          SELECT id, revoked_at, expires_at FROM sessions
+	          WHERE user_id = ? AND revoked_at IS NOT NULL AND expires_at > NOW()`
+
+	_, err = r.DB.Exec(query, userID)
+	return err
+}
+
+// RevokedSince returns the IDs of sessions revoked after the given time,
+// including those of deleted users
+func (r *SessionRepository) RevokedSince(since time.Time) ([]string, error) {
+	query := `SELECT id FROM sessions WHERE revoked_at >= ?
+	          UNION
+	          SELECT session_id FROM revoked_sessions WHERE revoked_at >= ?`
+
+	rows, err := r.DB.Query(query, since, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// IsRevoked reports whether a session has been revoked. Unknown sessions are
+// treated as revoked. It lets the repository serve as an auth.RevocationList.
+func (r *SessionRepository) IsRevoked(id string) bool {
+	var revokedAt sql.NullTime
+	err := r.DB.QueryRow(`SELECT revoked_at FROM sessions WHERE id = ?`, id).Scan(&revokedAt)
+	if err != nil {
+		return true
+	}
+	return revokedAt.Valid
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSession(row rowScanner) (*Session, error) {
+	session := &Session{}
+	var userAgent, ipAddress sql.NullString
+	var revokedAt sql.NullTime
+
+	err := row.Scan(
+		&session.ID, &session.UserID, &session.RefreshTokenHash, &userAgent, &ipAddress,
+		&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	session.UserAgent = userAgent.String
+	session.IPAddress = ipAddress.String
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+
+	return session, nil
+}
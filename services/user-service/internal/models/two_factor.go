@@ -0,0 +1,156 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// TOTP holds a user's two-factor state
+type TOTP struct {
+	Secret   string // empty when the user has never started enrollment
+	Enabled  bool   // false while enrollment awaits confirmation
+	LastStep int64  // most recent time step accepted, to stop replays
+}
+
+// GetTOTP returns a user's two-factor state
+func (r *UserRepository) GetTOTP(userID int) (*TOTP, error) {
+	totp := &TOTP{}
+	var secret sql.NullString
+
+	err := r.DB.QueryRow(`SELECT totp_secret, totp_enabled_at IS NOT NULL, totp_last_step FROM users WHERE id = ?`, userID).
+		Scan(&secret, &totp.Enabled, &totp.LastStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	totp.Secret = secret.String
+	return totp, nil
+}
+
+// StartTOTPEnrollment stores a new secret awaiting confirmation. It fails if
+// two-factor authentication is already enabled.
+func (r *UserRepository) StartTOTPEnrollment(userID int, secret string) error {
+	result, err := r.DB.Exec(`UPDATE users SET totp_secret = ?, totp_last_step = 0
+		WHERE id = ? AND totp_enabled_at IS NULL`, secret, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected != 1 {
+		return errors.New("two-factor authentication already enabled")
+	}
+	return nil
+}
+
+// EnableTOTP confirms enrollment and stores the user's recovery codes
+func (r *UserRepository) EnableTOTP(userID int, step int64, recoveryHashes []string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE users SET totp_enabled_at = NOW(), totp_last_step = ?
+		WHERE id = ? AND totp_secret IS NOT NULL`, step, userID)
+	if err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DisableTOTP removes the user's secret and recovery codes
+func (r *UserRepository) DisableTOTP(userID int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0
+		WHERE id = ?`, userID)
+	if err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(tx, userID, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep records that a code for the given time step was accepted. It
+// reports false if that step, or a later one, was already used.
+func (r *UserRepository) UseTOTPStep(userID int, step int64) (bool, error) {
+	result, err := r.DB.Exec(`UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?`,
+		step, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// ReplaceRecoveryCodes discards a user's recovery codes and stores new ones
+func (r *UserRepository) ReplaceRecoveryCodes(userID int, hashes []string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, hashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseRecoveryCode marks a recovery code as used, reporting false if it is
+// unknown or was already used
+func (r *UserRepository) UseRecoveryCode(userID int, hash string) (bool, error) {
+	result, err := r.DB.Exec(`UPDATE recovery_codes SET used_at = NOW()
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`, userID, hash)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left
+func (r *UserRepository) CountRecoveryCodes(userID int) (int, error) {
+	var count int
+	err := r.DB.QueryRow(`SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL`, userID).
+		Scan(&count)
+	return count, err
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int, hashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if _, err := tx.Exec(`INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
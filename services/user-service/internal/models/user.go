@@ -5,20 +5,84 @@ import (
 	"errors"
 	"time"
 
+	"github.com/cardoza1991/church-management-system/pkg/auth"
+	userauth "github.com/cardoza1991/church-management-system/services/user-service/internal/auth"
+	"github.com/go-sql-driver/mysql"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Roles a user can hold, from most to least privileged
+const (
+	RoleAdmin       = auth.RoleAdmin
+	RoleOverseer    = auth.RoleOverseer
+	RoleGroupLeader = auth.RoleGroupLeader
+	RoleTeacher     = auth.RoleTeacher
+	RoleMember      = auth.RoleMember
+)
+
+// Account statuses
+const (
+	StatusActive      = "active"
+	StatusPending     = "pending" // registered but awaiting approval by an admin or overseer
+	StatusDeactivated = "deactivated"
+)
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleOverseer, RoleGroupLeader, RoleTeacher, RoleMember:
+		return true
+	}
+	return false
+}
+
+// CanGrantRole reports whether a user holding granterRole may move a user from
+// currentRole to newRole. Admins may grant any role; overseers may grant roles
+// below their own to users who are not already overseers or admins.
+func CanGrantRole(granterRole, currentRole, newRole string) bool {
+	switch granterRole {
+	case RoleAdmin:
+		return true
+	case RoleOverseer:
+		elevated := func(role string) bool { return role == RoleAdmin || role == RoleOverseer }
+		return !elevated(currentRole) && !elevated(newRole)
+	}
+	return false
+}
+
 // User represents a user in the system
 type User struct {
+	ID            int       `json:"id"`
+	Username      string    `json:"username"`
+	PasswordHash  string    `json:"-"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Role          string    `json:"role"`
+	Status        string    `json:"status"`
+	FullName      string    `json:"full_name"`
+	Phone         string    `json:"phone,omitempty"`
+	TwoFactor     bool      `json:"two_factor_enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// RoleGrant records a role change made by an admin or overseer
+type RoleGrant struct {
 	ID           int       `json:"id"`
-	Username     string    `json:"username"`
-	PasswordHash string    `json:"-"`
-	Email        string    `json:"email"`
+	UserID       int       `json:"user_id"`
 	Role         string    `json:"role"`
-	FullName     string    `json:"full_name"`
-	Phone        string    `json:"phone,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	PreviousRole string    `json:"previous_role,omitempty"`
+	GrantedBy    int       `json:"granted_by,omitempty"` // zero once the granting user has been deleted
+	GrantedAt    time.Time `json:"granted_at"`
+}
+
+// ErrEmailTaken is returned when another user already has the email address
+var ErrEmailTaken = errors.New("email already in use")
+
+// isDuplicateKey reports whether err is a MySQL unique constraint violation
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
 }
 
 // UserRepository provides access to the user store
@@ -34,16 +98,26 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 // Create adds a new user to the database
 func (r *UserRepository) Create(user *User, password string) error {
 	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := userauth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	
+	if user.Status == "" {
+		user.Status = StatusActive
+	}
+	
+	tx, err := r.DB.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 	
-	query := `INSERT INTO users (username, password_hash, email, role, full_name, phone)
-		VALUES (?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO users (username, password_hash, email, role, status, full_name, phone)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
 	
-	result, err := r.DB.Exec(query, user.Username, string(hashedPassword), 
-		user.Email, user.Role, user.FullName, user.Phone)
+	result, err := tx.Exec(query, user.Username, hashedPassword, 
+		user.Email, user.Role, user.Status, user.FullName, user.Phone)
 	if err != nil {
 		return err
 	}
@@ -53,22 +127,41 @@ func (r *UserRepository) Create(user *User, password string) error {
 		return err
 	}
 	
+	if err := recordPassword(tx, int(id), hashedPassword); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	
 	user.ID = int(id)
 	return nil
 }
 
+// userColumns lists the columns scanUser expects, in order
+const userColumns = `id, username, password_hash, email, email_verified_at IS NOT NULL, role, status, full_name, phone,
+	totp_enabled_at IS NOT NULL, created_at, updated_at`
+
 // GetByUsername finds a user by username
 func (r *UserRepository) GetByUsername(username string) (*User, error) {
-	user := &User{}
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = ?`
 	
-	query := `SELECT id, username, password_hash, email, role, full_name, phone, created_at, updated_at 
-		FROM users WHERE username = ?`
+	user, err := scanUser(r.DB.QueryRow(query, username))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
 	
-	err := r.DB.QueryRow(query, username).Scan(
-		&user.ID, &user.Username, &user.PasswordHash, &user.Email, 
-		&user.Role, &user.FullName, &user.Phone, &user.CreatedAt, &user.UpdatedAt,
-	)
+	return user, nil
+}
+
+// GetByID finds a user by ID
+func (r *UserRepository) GetByID(id int) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ?`
 	
+	user, err := scanUser(r.DB.QueryRow(query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.New("user not found")
@@ -79,6 +172,284 @@ func (r *UserRepository) GetByUsername(username string) (*User, error) {
 	return user, nil
 }
 
+// List returns a page of users ordered by name, plus the total number of
+// matches. search matches username, email or full name; status filters by
+// account status when not empty.
+func (r *UserRepository) List(search, status string, limit, offset int) ([]*User, int, error) {
+	where := ` WHERE 1 = 1`
+	args := []interface{}{}
+	if search != "" {
+		pattern := "%" + search + "%"
+		where += ` AND (username LIKE ? OR email LIKE ? OR full_name LIKE ?)`
+		args = append(args, pattern, pattern, pattern)
+	}
+	if status != "" {
+		where += ` AND status = ?`
+		args = append(args, status)
+	}
+	
+	// Count all matches
+	var total int
+	if err := r.DB.QueryRow(`SELECT COUNT(*) FROM users`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	
+	// Fetch the requested page
+	query := `SELECT ` + userColumns + ` FROM users` + where + ` ORDER BY full_name, id LIMIT ? OFFSET ?`
+	rows, err := r.DB.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	
+	users := []*User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+	
+	return users, total, rows.Err()
+}
+
+// GetByEmail finds a user by email address
+func (r *UserRepository) GetByEmail(email string) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = ?`
+	
+	user, err := scanUser(r.DB.QueryRow(query, email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	
+	return user, nil
+}
+
+// Update modifies a user's profile. Changing the email address clears its
+// verification.
+func (r *UserRepository) Update(user *User) error {
+	// MySQL applies assignments left to right, so email_verified_at is compared
+	// against the old address
+	query := `UPDATE users SET email_verified_at = IF(email = ?, email_verified_at, NULL),
+		email = ?, full_name = ?, phone = ? WHERE id = ?`
+	
+	_, err := r.DB.Exec(query, user.Email, user.Email, user.FullName, user.Phone, user.ID)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return ErrEmailTaken
+		}
+		return err
+	}
+	
+	// Re-read the verification flag
+	var verified bool
+	err = r.DB.QueryRow(`SELECT email_verified_at IS NOT NULL FROM users WHERE id = ?`, user.ID).Scan(&verified)
+	user.EmailVerified = verified
+	return err
+}
+
+// SetPassword replaces a user's password and adds it to their password history
+func (r *UserRepository) SetPassword(id int, password string) error {
+	hashedPassword, err := userauth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	
+	if _, err := tx.Exec(`UPDATE users SET password_hash = ? WHERE id = ?`, hashedPassword, id); err != nil {
+		return err
+	}
+	if err := recordPassword(tx, id, hashedPassword); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpgradePasswordHash replaces the stored hash of an unchanged password, such
+// as after rehashing it at a higher cost. It leaves the history alone.
+func (r *UserRepository) UpgradePasswordHash(id int, oldHash, newHash string) error {
+	// Only replace the hash we checked, in case the password changed meanwhile
+	_, err := r.DB.Exec(`UPDATE users SET password_hash = ? WHERE id = ? AND password_hash = ?`, newHash, id, oldHash)
+	return err
+}
+
+// PasswordHistory returns the hashes of the user's most recent passwords,
+// newest first, including the current one
+func (r *UserRepository) PasswordHistory(id int, limit int) ([]string, error) {
+	rows, err := r.DB.Query(`SELECT password_hash FROM password_history
+		WHERE user_id = ? ORDER BY id DESC LIMIT ?`, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// recordPassword adds a hash to the user's password history and prunes
+// entries older than the configured history size
+func recordPassword(tx *sql.Tx, userID int, hash string) error {
+	if _, err := tx.Exec(`INSERT INTO password_history (user_id, password_hash) VALUES (?, ?)`, userID, hash); err != nil {
+		return err
+	}
+	
+	keep := userauth.PasswordHistorySize
+	if keep < 1 {
+		keep = 1
+	}
+	// MySQL does not allow LIMIT directly inside IN, hence the derived table
+	_, err := tx.Exec(`DELETE FROM password_history WHERE user_id = ? AND id NOT IN (
+		SELECT id FROM (
+			SELECT id FROM password_history WHERE user_id = ? ORDER BY id DESC LIMIT ?
+		) AS recent
+	)`, userID, userID, keep)
+	return err
+}
+
+// MarkEmailVerified records that the user has confirmed email, provided it is
+// still their address
+func (r *UserRepository) MarkEmailVerified(id int, email string) (bool, error) {
+	result, err := r.DB.Exec(`UPDATE users SET email_verified_at = NOW()
+		WHERE id = ? AND email = ? AND email_verified_at IS NULL`, id, email)
+	if err != nil {
+		return false, err
+	}
+	
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// SetStatus activates, approves or deactivates a user
+func (r *UserRepository) SetStatus(id int, status string) error {
+	_, err := r.DB.Exec(`UPDATE users SET status = ? WHERE id = ?`, status, id)
+	return err
+}
+
+// Delete removes a user from the database along with their sessions
+func (r *UserRepository) Delete(id int) error {
+	result, err := r.DB.Exec(`DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func scanUser(row rowScanner) (*User, error) {
+	user := &User{}
+	var phone sql.NullString
+	
+	err := row.Scan(
+		&user.ID, &user.Username, &user.PasswordHash, &user.Email, &user.EmailVerified,
+		&user.Role, &user.Status, &user.FullName, &phone, &user.TwoFactor, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	
+	user.Phone = phone.String
+	return user, nil
+}
+
+// GrantRole sets a user's role and records who granted it. Granting any role
+// to a pending account also approves it.
+func (r *UserRepository) GrantRole(userID int, role string, grantedBy int) (*RoleGrant, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	
+	// Lock the user row so concurrent grants record the right previous role
+	var previousRole string
+	err = tx.QueryRow(`SELECT role FROM users WHERE id = ? FOR UPDATE`, userID).Scan(&previousRole)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	
+	query := `UPDATE users SET role = ?, status = IF(status = ?, ?, status) WHERE id = ?`
+	if _, err := tx.Exec(query, role, StatusPending, StatusActive, userID); err != nil {
+		return nil, err
+	}
+	
+	grant := &RoleGrant{
+		UserID:       userID,
+		Role:         role,
+		PreviousRole: previousRole,
+		GrantedBy:    grantedBy,
+		GrantedAt:    time.Now(),
+	}
+	result, err := tx.Exec(`INSERT INTO role_grants (user_id, role, previous_role, granted_by, granted_at)
+		VALUES (?, ?, ?, ?, ?)`, grant.UserID, grant.Role, grant.PreviousRole, grant.GrantedBy, grant.GrantedAt)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	grant.ID = int(id)
+	
+	return grant, tx.Commit()
+}
+
+// ListRoleGrants returns the roles granted to a user, most recent first
+func (r *UserRepository) ListRoleGrants(userID int) ([]*RoleGrant, error) {
+	query := `SELECT id, user_id, role, previous_role, granted_by, granted_at
+		FROM role_grants WHERE user_id = ? ORDER BY granted_at DESC, id DESC`
+	
+	rows, err := r.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	grants := []*RoleGrant{}
+	for rows.Next() {
+		grant := &RoleGrant{}
+		var previousRole sql.NullString
+		var grantedBy sql.NullInt64
+		if err := rows.Scan(&grant.ID, &grant.UserID, &grant.Role, &previousRole, &grantedBy, &grant.GrantedAt); err != nil {
+			return nil, err
+		}
+		grant.PreviousRole = previousRole.String
+		grant.GrantedBy = int(grantedBy.Int64)
+		grants = append(grants, grant)
+	}
+	
+	return grants, rows.Err()
+}
+
 // CheckPassword verifies a user's password
 func (u *User) CheckPassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password))
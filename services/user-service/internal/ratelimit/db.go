@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"time"
+)
+
+// DBLimiter keeps attempts in the login_attempts table so every replica
+// shares the same counts. Times come from the database clock.
+type DBLimiter struct {
+	DB     *sql.DB
+	policy Policy
+}
+
+// NewDBLimiter creates a database-backed Limiter
+func NewDBLimiter(db *sql.DB, policy Policy) *DBLimiter {
+	return &DBLimiter{DB: db, policy: policy}
+}
+
+// Check returns how long the key remains locked
+func (l *DBLimiter) Check(key string) (time.Duration, error) {
+	var seconds int
+	err := l.DB.QueryRow(`SELECT TIMESTAMPDIFF(SECOND, NOW(), locked_until) FROM login_attempts
+		WHERE attempt_key = ? AND locked_until > NOW()`, key).Scan(&seconds)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds+1) * time.Second, nil
+}
+
+// Fail records a failed attempt
+func (l *DBLimiter) Fail(key string) (bool, error) {
+	window := int(l.policy.Window.Seconds())
+
+	// Count the failure, starting a new window once the old one has passed.
+	// MySQL applies assignments left to right, so both see the old window_start.
+	_, err := l.DB.Exec(`INSERT INTO login_attempts (attempt_key, failures, window_start)
+		VALUES (?, 1, NOW())
+		ON DUPLICATE KEY UPDATE
+			failures = IF(window_start < NOW() - INTERVAL ? SECOND, 1, failures + 1),
+			window_start = IF(window_start < NOW() - INTERVAL ? SECOND, NOW(), window_start)`,
+		key, window, window)
+	if err != nil {
+		return false, err
+	}
+
+	// Lock the key once it reaches the limit; only one concurrent caller wins
+	result, err := l.DB.Exec(`UPDATE login_attempts
+		SET failures = 0, locked_until = NOW() + INTERVAL ? SECOND
+		WHERE attempt_key = ? AND failures >= ? AND (locked_until IS NULL OR locked_until <= NOW())`,
+		int(l.policy.Lockout.Seconds()), key, l.policy.MaxFailures)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// Reset forgets the key
+func (l *DBLimiter) Reset(key string) error {
+	_, err := l.DB.Exec(`DELETE FROM login_attempts WHERE attempt_key = ?`, key)
+	return err
+}
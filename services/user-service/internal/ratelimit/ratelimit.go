@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Policy decides when a key is locked out
+type Policy struct {
+	MaxFailures int           // failures within Window that trigger a lockout
+	Window      time.Duration // failures older than this are forgotten
+	Lockout     time.Duration // how long a locked key stays locked
+}
+
+// Limiter counts failed attempts per key, such as a username or client IP,
+// and locks keys that fail too often
+type Limiter interface {
+	// Check returns how long the key remains locked, or zero if it is not locked
+	Check(key string) (time.Duration, error)
+	// Fail records a failed attempt and reports whether it locked the key
+	Fail(key string) (bool, error)
+	// Reset forgets the key's failures and lifts any lockout
+	Reset(key string) error
+}
+
+// MemoryLimiter keeps attempts in process memory. Each replica counts
+// separately, so use DBLimiter when running more than one.
+type MemoryLimiter struct {
+	policy Policy
+	now    func() time.Time // the clock, replaced in tests
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	failures    int
+	windowStart time.Time
+	lockedUntil time.Time
+}
+
+// maxEntries bounds memory use; expired entries are pruned beyond it
+const maxEntries = 10000
+
+// NewMemoryLimiter creates an in-memory Limiter
+func NewMemoryLimiter(policy Policy) *MemoryLimiter {
+	return &MemoryLimiter{policy: policy, now: time.Now, entries: map[string]*entry{}}
+}
+
+// Check returns how long the key remains locked
+func (l *MemoryLimiter) Check(key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		return 0, nil
+	}
+	if remaining := e.lockedUntil.Sub(l.now()); remaining > 0 {
+		return remaining, nil
+	}
+	return 0, nil
+}
+
+// Fail records a failed attempt
+func (l *MemoryLimiter) Fail(key string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	e, ok := l.entries[key]
+	if !ok {
+		if len(l.entries) >= maxEntries {
+			l.prune(now)
+		}
+		e = &entry{windowStart: now}
+		l.entries[key] = e
+	}
+
+	// Start a new window once the old one has passed
+	if now.Sub(e.windowStart) > l.policy.Window {
+		e.failures = 0
+		e.windowStart = now
+	}
+
+	e.failures++
+	if e.failures >= l.policy.MaxFailures && !now.Before(e.lockedUntil) {
+		e.failures = 0
+		e.lockedUntil = now.Add(l.policy.Lockout)
+		return true, nil
+	}
+	return false, nil
+}
+
+// Reset forgets the key
+func (l *MemoryLimiter) Reset(key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key)
+	return nil
+}
+
+// prune drops entries whose window and lockout have both passed
+func (l *MemoryLimiter) prune(now time.Time) {
+	for key, e := range l.entries {
+		if now.Sub(e.windowStart) > l.policy.Window && !now.Before(e.lockedUntil) {
+			delete(l.entries, key)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{MaxFailures: 3, Window: 10 * time.Minute, Lockout: 15 * time.Minute}
+
+// testLimiter returns a MemoryLimiter on a clock that only moves when told to
+func testLimiter() (*MemoryLimiter, func(time.Duration)) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	l := NewMemoryLimiter(testPolicy)
+	l.now = func() time.Time { return now }
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	// Each step waits, then fails, checks or resets the key. Fails expect
+	// locked; checks expect remaining.
+	type step struct {
+		wait      time.Duration
+		op        string
+		locked    bool
+		remaining time.Duration
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"locks on the third failure", []step{
+			{0, "fail", false, 0},
+			{time.Minute, "fail", false, 0},
+			{time.Minute, "check", false, 0},
+			{0, "fail", true, 0},
+			{0, "check", false, 15 * time.Minute},
+		}},
+		{"forgets failures once the window passes", []step{
+			{0, "fail", false, 0},
+			{0, "fail", false, 0},
+			{11 * time.Minute, "fail", false, 0},
+			{0, "fail", false, 0},
+			{0, "fail", true, 0},
+		}},
+		{"does not re-lock while locked", []step{
+			{0, "fail", false, 0},
+			{0, "fail", false, 0},
+			{0, "fail", true, 0},
+			{time.Minute, "fail", false, 0},
+			{0, "fail", false, 0},
+			{0, "fail", false, 0},
+			{0, "check", false, 14 * time.Minute},
+		}},
+		{"lockout expires", []step{
+			{0, "fail", false, 0},
+			{0, "fail", false, 0},
+			{0, "fail", true, 0},
+			{15 * time.Minute, "check", false, 0},
+			{0, "fail", false, 0},
+		}},
+		{"reset lifts the lockout and forgets failures", []step{
+			{0, "fail", false, 0},
+			{0, "fail", false, 0},
+			{0, "fail", true, 0},
+			{0, "reset", false, 0},
+			{0, "check", false, 0},
+			{0, "fail", false, 0},
+			{0, "fail", false, 0},
+		}},
+	}
+
+	for _, tt := range tests {
+		l, wait := testLimiter()
+		for i, s := range tt.steps {
+			wait(s.wait)
+			switch s.op {
+			case "fail":
+				if locked, _ := l.Fail("jsmith"); locked != s.locked {
+					t.Errorf("%s: step %d: Fail = %v, want %v", tt.name, i, locked, s.locked)
+				}
+			case "check":
+				if remaining, _ := l.Check("jsmith"); remaining != s.remaining {
+					t.Errorf("%s: step %d: Check = %v, want %v", tt.name, i, remaining, s.remaining)
+				}
+			case "reset":
+				l.Reset("jsmith")
+			}
+		}
+	}
+}
+
+func TestMemoryLimiterPrune(t *testing.T) {
+	l, wait := testLimiter()
+	for i := 0; i < 3; i++ {
+		l.Fail("locked")
+	}
+	for i := 1; len(l.entries) < maxEntries; i++ {
+		l.Fail(fmt.Sprint(i))
+	}
+
+	// Past every window but still within the lockout: only the locked key
+	// survives the prune triggered by a new key
+	wait(11 * time.Minute)
+	l.Fail("new")
+	if len(l.entries) != 2 {
+		t.Fatalf("%d entries after pruning, want 2", len(l.entries))
+	}
+	if remaining, _ := l.Check("locked"); remaining != 4*time.Minute {
+		t.Errorf("locked key has %v left, want 4m", remaining)
+	}
+}
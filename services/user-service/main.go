@@ -7,16 +7,47 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/cardoza1991/church-management-system/services/user-service/api/handlers"
-	"github.com/cardoza1991/church-management-system/services/user-service/api/middleware"
+	"github.com/cardoza1991/church-management-system/pkg/auth"
+	"github.com/cardoza1991/church-management-system/pkg/middleware"
+	"github.com/cardoza1991/church-management-system/pkg/db"
 	"github.com/cardoza1991/church-management-system/services/user-service/config"
-	"github.com/cardoza1991/church-management-system/services/user-service/internal/db"
+	userauth "github.com/cardoza1991/church-management-system/services/user-service/internal/auth"
+	"github.com/cardoza1991/church-management-system/services/user-service/internal/mail"
 	"github.com/cardoza1991/church-management-system/services/user-service/internal/models"
+	"github.com/cardoza1991/church-management-system/services/user-service/internal/ratelimit"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
 	
+	if cfg.RegistrationMode != "open" && cfg.RegistrationMode != "approval" {
+		log.Fatalf("Invalid REGISTRATION_MODE %q: use open or approval", cfg.RegistrationMode)
+	}
+	
+	// Load token signing and verification keys
+	keyring, err := auth.LoadKeyring(cfg.Keys)
+	if err != nil {
+		log.Fatalf("Failed to load token keys: %v", err)
+	}
+	if !keyring.CanSign() {
+		log.Fatalf("No signing key: set JWT_PRIVATE_KEY_FILE, JWT_SECRET_FILE or JWT_SECRET")
+	}
+	auth.UseKeyring(keyring)
+	userauth.AccessTokenTTL = cfg.AccessTokenTTL
+	userauth.RefreshTokenTTL = cfg.RefreshTokenTTL
+	userauth.PasswordResetTTL = cfg.PasswordResetTTL
+	userauth.EmailVerificationTTL = cfg.EmailVerificationTTL
+	userauth.TwoFactorRequiredRoles = cfg.TwoFactorRequiredRoles
+	userauth.TOTPIssuer = cfg.TOTPIssuer
+	if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		log.Fatalf("Invalid BCRYPT_COST %d: use %d to %d", cfg.BcryptCost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	userauth.PasswordMinLength = cfg.PasswordMinLength
+	userauth.PasswordHistorySize = cfg.PasswordHistorySize
+	userauth.BcryptCost = cfg.BcryptCost
+	
 	// Connect to database
 	database, err := db.Connect(cfg.DSN())
 	if err != nil {
@@ -26,10 +57,88 @@ func main() {
 	
 	// Create repositories
 	userRepo := models.NewUserRepository(database)
+	sessionRepo := models.NewSessionRepository(database)
+	tokenRepo := models.NewAccountTokenRepository(database)
+	auditRepo := models.NewAuditRepository(database)
+	groupRepo := models.NewGroupRepository(database)
+	
+	// Throttle failed logins per account and per client IP
+	accountPolicy := ratelimit.Policy{
+		MaxFailures: cfg.LoginMaxFailures,
+		Window:      cfg.LoginFailureWindow,
+		Lockout:     cfg.LoginLockoutDuration,
+	}
+	ipPolicy := accountPolicy
+	ipPolicy.MaxFailures = cfg.LoginIPMaxFailures
+	newLimiter := func(policy ratelimit.Policy) ratelimit.Limiter {
+		switch cfg.LoginLimiter {
+		case "memory":
+			return ratelimit.NewMemoryLimiter(policy)
+		case "db":
+			return ratelimit.NewDBLimiter(database, policy)
+		}
+		log.Fatalf("Invalid LOGIN_LIMITER %q: use memory or db", cfg.LoginLimiter)
+		return nil
+	}
+	accountLimiter := newLimiter(accountPolicy)
+	ipLimiter := newLimiter(ipPolicy)
+	
+	// Throttle password reset requests per address and per client IP
+	resetPolicy := ratelimit.Policy{
+		MaxFailures: cfg.PasswordResetMaxRequests,
+		Window:      cfg.PasswordResetWindow,
+		Lockout:     cfg.PasswordResetWindow,
+	}
+	resetIPPolicy := resetPolicy
+	resetIPPolicy.MaxFailures = cfg.PasswordResetIPMaxRequests
+	
+	// Reject access tokens whose session has been revoked
+	auth.UseRevocationList(sessionRepo)
+	
+	// Send mail through SMTP, or just record it when no server is configured
+	var mailer mail.Mailer = &mail.LogMailer{Path: cfg.MailLogFile, From: cfg.MailFrom}
+	if cfg.SMTPHost != "" {
+		mailer = &mail.SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		}
+	}
 	
 	// Create handlers
-	authHandler := &handlers.AuthHandler{UserRepo: userRepo}
-	userHandler := &handlers.UserHandler{UserRepo: userRepo}
+	accountHandler := &handlers.AccountHandler{
+		UserRepo:       userRepo,
+		SessionRepo:    sessionRepo,
+		TokenRepo:      tokenRepo,
+		Mailer:         mailer,
+		AppURL:         cfg.AppURL,
+		ResetLimiter:   newLimiter(resetPolicy),
+		ResetIPLimiter: newLimiter(resetIPPolicy),
+	}
+	authHandler := &handlers.AuthHandler{
+		UserRepo:        userRepo,
+		SessionRepo:     sessionRepo,
+		RequireApproval: cfg.RegistrationMode == "approval",
+		Accounts:        accountHandler,
+		TokenRepo:       tokenRepo,
+		AccountLimiter:  accountLimiter,
+		IPLimiter:       ipLimiter,
+		AuditRepo:       auditRepo,
+	}
+	userHandler := &handlers.UserHandler{
+		UserRepo:       userRepo,
+		SessionRepo:    sessionRepo,
+		Accounts:       accountHandler,
+		AccountLimiter: accountLimiter,
+		IPLimiter:      ipLimiter,
+		AuditRepo:      auditRepo,
+	}
+	groupHandler := &handlers.GroupHandler{
+		GroupRepo: groupRepo,
+		UserRepo:  userRepo,
+	}
 	
 	// Create router
 	r := mux.NewRouter()
@@ -43,14 +152,77 @@ func main() {
 	// Auth endpoints
 	r.HandleFunc("/register", authHandler.Register).Methods("POST")
 	r.HandleFunc("/login", authHandler.Login).Methods("POST")
+	r.HandleFunc("/token/refresh", authHandler.Refresh).Methods("POST")
+	r.HandleFunc("/.well-known/jwks.json", authHandler.JWKS).Methods("GET")
+	r.HandleFunc("/sessions/revoked", authHandler.RevokedSessions).Methods("GET")
+	r.Handle("/logout", middleware.AuthMiddleware(http.HandlerFunc(authHandler.Logout))).Methods("POST")
+	
+	// Two-factor authentication
+	r.HandleFunc("/login/2fa", authHandler.LoginTwoFactor).Methods("POST")
+	r.HandleFunc("/login/2fa/enroll", authHandler.LoginEnrollTwoFactor).Methods("POST")
+	twoFactorRouter := r.PathPrefix("/2fa").Subrouter()
+	twoFactorRouter.Use(middleware.AuthMiddleware)
+	twoFactorRouter.HandleFunc("", authHandler.GetTwoFactor).Methods("GET")
+	twoFactorRouter.HandleFunc("/enroll", authHandler.EnrollTwoFactor).Methods("POST")
+	twoFactorRouter.HandleFunc("/confirm", authHandler.ConfirmTwoFactor).Methods("POST")
+	twoFactorRouter.HandleFunc("/disable", authHandler.DisableTwoFactor).Methods("POST")
+	twoFactorRouter.HandleFunc("/recovery-codes", authHandler.RegenerateRecoveryCodes).Methods("POST")
+	
+	// Password reset and email verification
+	r.HandleFunc("/password/forgot", accountHandler.ForgotPassword).Methods("POST")
+	r.HandleFunc("/password/reset", accountHandler.ResetPassword).Methods("POST")
+	r.HandleFunc("/email/verify", accountHandler.VerifyEmail).Methods("POST")
+	r.Handle("/email/verify/resend", middleware.AuthMiddleware(http.HandlerFunc(accountHandler.ResendVerification))).Methods("POST")
 	
 	
 	// Protected user endpoints
 	userRouter := r.PathPrefix("/users").Subrouter()
 	userRouter.Use(middleware.AuthMiddleware)
 	userRouter.HandleFunc("/me", userHandler.GetSelf).Methods("GET")
+	userRouter.HandleFunc("/me", userHandler.UpdateSelf).Methods("PUT")
+	userRouter.HandleFunc("/me/password", userHandler.ChangePassword).Methods("POST")
+	userRouter.HandleFunc("/me/downline", groupHandler.GetDownline).Methods("GET")
 	userRouter.HandleFunc("/{id}", userHandler.GetUser).Methods("GET")
 	
+	// User management, each group gated by a permission from the role matrix
+	readRouter := r.PathPrefix("/users").Subrouter()
+	readRouter.Use(middleware.AuthMiddleware, middleware.Require("users:read"))
+	readRouter.HandleFunc("", userHandler.ListUsers).Methods("GET")
+	readRouter.HandleFunc("/{id:[0-9]+}/role-grants", userHandler.ListRoleGrants).Methods("GET")
+	
+	writeRouter := r.PathPrefix("/users").Subrouter()
+	writeRouter.Use(middleware.AuthMiddleware, middleware.Require("users:write"))
+	writeRouter.HandleFunc("/{id:[0-9]+}", userHandler.UpdateUser).Methods("PUT")
+	writeRouter.HandleFunc("/{id:[0-9]+}", userHandler.DeleteUser).Methods("DELETE")
+	writeRouter.HandleFunc("/{id:[0-9]+}/deactivate", userHandler.DeactivateUser).Methods("POST")
+	writeRouter.HandleFunc("/{id:[0-9]+}/reactivate", userHandler.ReactivateUser).Methods("POST")
+	writeRouter.HandleFunc("/{id:[0-9]+}/lockout", userHandler.UnlockUser).Methods("DELETE")
+	writeRouter.HandleFunc("/{id:[0-9]+}/2fa", authHandler.ResetTwoFactor).Methods("DELETE")
+	writeRouter.HandleFunc("/{id:[0-9]+}/sessions", userHandler.ListSessions).Methods("GET")
+	writeRouter.HandleFunc("/{id:[0-9]+}/sessions", userHandler.RevokeAllSessions).Methods("DELETE")
+	writeRouter.HandleFunc("/{id:[0-9]+}/sessions/{sessionId}", userHandler.RevokeSession).Methods("DELETE")
+	
+	// Role grants; CanGrantRole further limits which roles an overseer may grant
+	roleRouter := r.PathPrefix("/users").Subrouter()
+	roleRouter.Use(middleware.AuthMiddleware, middleware.Require("roles:grant"))
+	roleRouter.HandleFunc("/{id:[0-9]+}/role", userHandler.GrantRole).Methods("PUT")
+	
+	// Ministry groups; leaders may also see who is in the groups they lead
+	groupRouter := r.PathPrefix("/groups").Subrouter()
+	groupRouter.Use(middleware.AuthMiddleware)
+	groupRouter.HandleFunc("", groupHandler.ListGroups).Methods("GET")
+	groupRouter.HandleFunc("/{id:[0-9]+}", groupHandler.GetGroup).Methods("GET")
+	groupRouter.HandleFunc("/{id:[0-9]+}/members", groupHandler.ListGroupMembers).Methods("GET")
+	groupRouter.HandleFunc("/{id:[0-9]+}/downline", groupHandler.GetGroupDownline).Methods("GET")
+	
+	groupWriteRouter := r.PathPrefix("/groups").Subrouter()
+	groupWriteRouter.Use(middleware.AuthMiddleware, middleware.Require("groups:write"))
+	groupWriteRouter.HandleFunc("", groupHandler.CreateGroup).Methods("POST")
+	groupWriteRouter.HandleFunc("/{id:[0-9]+}", groupHandler.UpdateGroup).Methods("PUT")
+	groupWriteRouter.HandleFunc("/{id:[0-9]+}", groupHandler.DeleteGroup).Methods("DELETE")
+	groupWriteRouter.HandleFunc("/{id:[0-9]+}/members/{userId:[0-9]+}", groupHandler.AddGroupMember).Methods("PUT")
+	groupWriteRouter.HandleFunc("/{id:[0-9]+}/members/{userId:[0-9]+}", groupHandler.RemoveGroupMember).Methods("DELETE")
+	
 	
 	// Start server
 	log.Printf("Server starting on port %s", cfg.ServerPort)